@@ -0,0 +1,187 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cloudfoundry-community/portcullis/broker"
+	"github.com/cloudfoundry-community/portcullis/store"
+	"github.com/gorilla/mux"
+	"github.com/starkandwayne/goutils/log"
+)
+
+//GetAccessResponse contains the information to be written to the body in
+// response to a call to the GetAccess handler, to be marshalled to JSON.
+type GetAccessResponse struct {
+	//Count should be set to the length of the Plans slice
+	Count int `json:"count"`
+	//Plans are the plans in the catalog of the mapping's service broker, and
+	// the orgs that can see them
+	Plans []broker.PlanAccess `json:"plans"`
+}
+
+//AccessChangesResponse contains the information to be written to the body in
+// response to a call to the GrantAccess, RevokeAccess, or GetAccessChanges
+// handlers, to be marshalled to JSON.
+type AccessChangesResponse struct {
+	//Count should be set to the length of the Changes slice
+	Count int `json:"count"`
+	//Changes are the changes that were made to plan access, oldest first
+	Changes []store.AccessChange `json:"changes"`
+}
+
+//GetAccess is an HTTP handler that returns which orgs can see each plan of the
+// service broker that is registered in CF for the mapping named in the URL.
+//
+//Return codes:
+// 200 - The plans were returned.
+// 404 - There is no such mapping, or it has no service broker in CF.
+// 500 - Internal error - i.e Store cannot be reached
+// 502 - CF could not be asked about the plans
+func GetAccess(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !accessMappingExists(w, name) {
+		return
+	}
+
+	plans, err := broker.ListPlanAccess(name)
+	if err != nil {
+		writeAccessError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(responsify(http.StatusOK, GetAccessResponse{
+		Count: len(plans),
+		Plans: plans,
+	}, ""))
+}
+
+//GrantAccess is an HTTP handler that lets the org with the GUID in the URL see
+// the plans of the mapping's service broker. The `service` and `plan` query
+// parameters narrow down which plans, and match every plan if they're not
+// given. Each plan that the org couldn't see before is recorded as a change.
+//
+//Return codes:
+// 200 - Access was granted. The changes are empty if the org had access already.
+// 404 - There is no such mapping, it has no service broker in CF, or no plans
+//       match.
+// 500 - Internal error - i.e Store cannot be reached
+// 502 - CF could not be asked to change access. Changes made before the
+//       failure are still recorded.
+func GrantAccess(w http.ResponseWriter, r *http.Request) {
+	changeAccess(w, r, true)
+}
+
+//RevokeAccess is an HTTP handler that stops the org with the GUID in the URL
+// from seeing the plans of the mapping's service broker, taking the same query
+// parameters as GrantAccess. Each plan that the org could see before is
+// recorded as a change. Public plans can still be seen by every org.
+//
+//Return codes:
+// 200 - Access was revoked. The changes are empty if the org had no access.
+// 404 - There is no such mapping, it has no service broker in CF, or no plans
+//       match.
+// 500 - Internal error - i.e Store cannot be reached
+// 502 - CF could not be asked to change access. Changes made before the
+//       failure are still recorded.
+func RevokeAccess(w http.ResponseWriter, r *http.Request) {
+	changeAccess(w, r, false)
+}
+
+func changeAccess(w http.ResponseWriter, r *http.Request, grant bool) {
+	name, orgGUID := mux.Vars(r)["name"], mux.Vars(r)["org_guid"]
+	if !accessMappingExists(w, name) {
+		return
+	}
+
+	serviceName, planName := r.URL.Query().Get("service"), r.URL.Query().Get("plan")
+	var plans []broker.PlanAccess
+	var err error
+	if grant {
+		plans, err = broker.GrantPlanAccess(name, orgGUID, serviceName, planName)
+	} else {
+		plans, err = broker.RevokePlanAccess(name, orgGUID, serviceName, planName)
+	}
+
+	//Whatever was changed before any failure still happened, and is recorded
+	changes := []store.AccessChange{}
+	for _, plan := range plans {
+		change := store.AccessChange{
+			MappingName:      name,
+			OrganizationGUID: orgGUID,
+			ServiceName:      plan.ServiceName,
+			PlanName:         plan.PlanName,
+			PlanGUID:         plan.PlanGUID,
+			Granted:          grant,
+			User:             auth.User(r),
+			Time:             time.Now().UTC(),
+		}
+		if addErr := store.AddAccessChange(change); addErr != nil {
+			log.Errorf("Could not record access change to plan %s by %s: %s", plan.PlanGUID, change.User, addErr)
+		}
+		changes = append(changes, change)
+	}
+
+	if err != nil {
+		writeAccessError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(responsify(http.StatusOK, AccessChangesResponse{
+		Count:   len(changes),
+		Changes: changes,
+	}, ""))
+}
+
+//GetAccessChanges is an HTTP handler that returns every recorded change to the
+// plan access of the mapping named in the URL, along with who made it.
+//
+//Return codes:
+// 200 - The changes were returned. The list is empty if there were none.
+// 500 - Internal error - i.e Store cannot be reached
+func GetAccessChanges(w http.ResponseWriter, r *http.Request) {
+	changes, err := store.ListAccessChanges(mux.Vars(r)["name"])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(responsify(http.StatusInternalServerError, nil, MetaMessageStoreError))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(responsify(http.StatusOK, AccessChangesResponse{
+		Count:   len(changes),
+		Changes: changes,
+	}, ""))
+}
+
+//accessMappingExists writes an error response and returns false if there is
+// no mapping with the given name
+func accessMappingExists(w http.ResponseWriter, name string) bool {
+	_, err := store.GetMapping(name)
+	if err == nil {
+		return true
+	}
+
+	if err == store.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(responsify(http.StatusNotFound, nil, fmt.Sprintf("No mapping in store with name: `%s`", name)))
+		return false
+	}
+
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write(responsify(http.StatusInternalServerError, nil, MetaMessageStoreError))
+	return false
+}
+
+func writeAccessError(w http.ResponseWriter, err error) {
+	returnCode := http.StatusBadGateway
+	if err == broker.ErrNotRegistered || err == broker.ErrNoSuchPlan {
+		returnCode = http.StatusNotFound
+	}
+
+	w.WriteHeader(returnCode)
+	w.Write(responsify(returnCode, nil, fmt.Sprintf("Could not manage plan access: %s", err)))
+}
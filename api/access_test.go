@@ -0,0 +1,243 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/cloudfoundry-community/portcullis/api"
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Plan access", func() {
+	var cf *stubCF
+	var mapping store.Mapping
+	var smallPlan, bigPlan, publicPlan string
+	var testRequest *http.Request
+	var testResponse *httptest.ResponseRecorder
+
+	const org = "some-org"
+
+	BeforeEach(func() {
+		Expect(Initialize(config.APIConfig{
+			Port: 5590,
+			Auth: config.AuthConfig{
+				Type: "basic",
+				Config: map[string]interface{}{
+					"username": "admin-user",
+					"password": "admin-pass",
+				},
+			},
+		})).To(Succeed())
+		Expect(store.ClearAccessChanges()).To(Succeed())
+		cf = newStubCF()
+		cf.connectBroker()
+
+		mapping = genTestMapping()
+		Expect(store.AddMapping(mapping)).To(Succeed())
+		cf.Brokers[genRandomString()] = map[string]interface{}{"name": mapping.Name}
+		smallPlan = cf.addPlan(mapping.Name, "db", "small", false)
+		bigPlan = cf.addPlan(mapping.Name, "db", "big", false)
+		publicPlan = cf.addPlan(mapping.Name, "cache", "shared", true)
+	})
+
+	AfterEach(func() {
+		cf.close()
+		store.ClearMappings()
+	})
+
+	JustBeforeEach(func() {
+		testRequest.SetBasicAuth("admin-user", "admin-pass")
+		testResponse = httptest.NewRecorder()
+		Router().ServeHTTP(testResponse, testRequest)
+	})
+
+	changesIn := func(testResponse *httptest.ResponseRecorder) []interface{} {
+		contents := readJSONResponse(testResponse)["contents"].(map[string]interface{})
+		return contents["changes"].([]interface{})
+	}
+
+	Describe("Listing plan access", func() {
+		BeforeEach(func() {
+			testRequest = httptest.NewRequest("GET", "/v1/mappings/"+mapping.Name+"/access", nil)
+		})
+
+		Context("When an org has been given access to a plan", func() {
+			BeforeEach(func() {
+				cf.Lists[visibilitiesPath][genRandomString()] = map[string]interface{}{
+					"service_plan_guid": bigPlan,
+					"organization_guid": org,
+				}
+			})
+
+			It("should return every plan, sorted by service and plan", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusOK))
+				contents := readJSONResponse(testResponse)["contents"].(map[string]interface{})
+				Expect(contents["count"]).To(BeEquivalentTo(3))
+				plans := contents["plans"].([]interface{})
+				Expect(plans[0].(map[string]interface{})["plan_guid"]).To(Equal(publicPlan))
+				Expect(plans[0].(map[string]interface{})["public"]).To(BeTrue())
+				Expect(plans[1].(map[string]interface{})["plan_guid"]).To(Equal(bigPlan))
+				Expect(plans[1].(map[string]interface{})["organizations"]).To(ConsistOf(org))
+				Expect(plans[2].(map[string]interface{})["plan_guid"]).To(Equal(smallPlan))
+				Expect(plans[2].(map[string]interface{})["organizations"]).To(BeEmpty())
+			})
+		})
+
+		Context("When the mapping doesn't exist", func() {
+			BeforeEach(func() {
+				testRequest = httptest.NewRequest("GET", "/v1/mappings/"+genRandomString()+"/access", nil)
+			})
+
+			It("should return a status code of 404", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("When the mapping has no service broker in CF", func() {
+			BeforeEach(func() {
+				other := genTestMapping()
+				Expect(store.AddMapping(other)).To(Succeed())
+				testRequest = httptest.NewRequest("GET", "/v1/mappings/"+other.Name+"/access", nil)
+			})
+
+			It("should return a status code of 404", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("Granting plan access", func() {
+		BeforeEach(func() {
+			testRequest = httptest.NewRequest("PUT", "/v1/mappings/"+mapping.Name+"/access/"+org, nil)
+		})
+
+		It("should give the org access to every plan that isn't public", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusOK))
+			Expect(changesIn(testResponse)).To(HaveLen(2))
+			Expect(cf.visibleTo(smallPlan)).To(ConsistOf(org))
+			Expect(cf.visibleTo(bigPlan)).To(ConsistOf(org))
+			Expect(cf.visibleTo(publicPlan)).To(BeEmpty())
+		})
+
+		It("should record the changes with the calling user", func() {
+			changes, err := store.ListAccessChanges(mapping.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(HaveLen(2))
+			for _, change := range changes {
+				Expect(change.OrganizationGUID).To(Equal(org))
+				Expect(change.Granted).To(BeTrue())
+				Expect(change.User).To(Equal("admin-user"))
+				Expect(change.ServiceName).To(Equal("db"))
+			}
+		})
+
+		Context("When only one plan is asked for", func() {
+			BeforeEach(func() {
+				testRequest = httptest.NewRequest("PUT", "/v1/mappings/"+mapping.Name+"/access/"+org+"?service=db&plan=small", nil)
+			})
+
+			It("should only give access to that plan", func() {
+				Expect(changesIn(testResponse)).To(HaveLen(1))
+				Expect(cf.visibleTo(smallPlan)).To(ConsistOf(org))
+				Expect(cf.visibleTo(bigPlan)).To(BeEmpty())
+			})
+		})
+
+		Context("When the org already has access", func() {
+			BeforeEach(func() {
+				cf.Lists[visibilitiesPath][genRandomString()] = map[string]interface{}{
+					"service_plan_guid": smallPlan,
+					"organization_guid": org,
+				}
+			})
+
+			It("should only change the other plans", func() {
+				changes := changesIn(testResponse)
+				Expect(changes).To(HaveLen(1))
+				Expect(changes[0].(map[string]interface{})["plan_guid"]).To(Equal(bigPlan))
+				Expect(cf.visibleTo(smallPlan)).To(HaveLen(1))
+			})
+		})
+
+		Context("When no plan matches", func() {
+			BeforeEach(func() {
+				testRequest = httptest.NewRequest("PUT", "/v1/mappings/"+mapping.Name+"/access/"+org+"?plan=huge", nil)
+			})
+
+			It("should return a status code of 404", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("When CF won't make the change", func() {
+			BeforeEach(func() {
+				cf.Fail = true
+			})
+
+			It("should return a status code of 502", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusBadGateway))
+			})
+
+			It("should not record a change", func() {
+				Expect(store.ListAccessChanges(mapping.Name)).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Revoking plan access", func() {
+		BeforeEach(func() {
+			cf.Lists[visibilitiesPath][genRandomString()] = map[string]interface{}{
+				"service_plan_guid": smallPlan,
+				"organization_guid": org,
+			}
+			cf.Lists[visibilitiesPath][genRandomString()] = map[string]interface{}{
+				"service_plan_guid": smallPlan,
+				"organization_guid": "other-org",
+			}
+			testRequest = httptest.NewRequest("DELETE", "/v1/mappings/"+mapping.Name+"/access/"+org, nil)
+		})
+
+		It("should take away the org's access, and nobody else's", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusOK))
+			Expect(cf.visibleTo(smallPlan)).To(ConsistOf("other-org"))
+		})
+
+		It("should record the change", func() {
+			changes, err := store.ListAccessChanges(mapping.Name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(HaveLen(1))
+			Expect(changes[0].PlanGUID).To(Equal(smallPlan))
+			Expect(changes[0].Granted).To(BeFalse())
+			Expect(changes[0].User).To(Equal("admin-user"))
+		})
+	})
+
+	Describe("Listing the changes", func() {
+		BeforeEach(func() {
+			Expect(store.AddAccessChange(store.AccessChange{
+				MappingName:      mapping.Name,
+				OrganizationGUID: org,
+				PlanGUID:         smallPlan,
+				Granted:          true,
+				User:             "someone",
+			})).To(Succeed())
+			Expect(store.AddAccessChange(store.AccessChange{
+				MappingName:      genRandomString(),
+				OrganizationGUID: org,
+				PlanGUID:         bigPlan,
+			})).To(Succeed())
+			testRequest = httptest.NewRequest("GET", "/v1/mappings/"+mapping.Name+"/access/changes", nil)
+		})
+
+		It("should return the changes to the mapping", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusOK))
+			changes := changesIn(testResponse)
+			Expect(changes).To(HaveLen(1))
+			Expect(changes[0].(map[string]interface{})["user"]).To(Equal("someone"))
+		})
+	})
+})
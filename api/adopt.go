@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/cloudfoundry-community/portcullis/broker"
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
+	"github.com/cloudfoundry-community/portcullis/store"
+	"github.com/gorilla/mux"
+)
+
+//adoptRequest is the body of a call to the AdoptBroker handler
+type adoptRequest struct {
+	//Username and Password are the credentials that CF gives the broker, which
+	// are used to check its catalog through Portcullis
+	Username string `json:"username"`
+	Password string `json:"password"`
+	//BindConfig becomes the bind config of the new mapping
+	BindConfig bindparser.ConfigList `json:"bind_config"`
+}
+
+//AdoptBroker is an HTTP handler that moves the CF service broker named in the
+// URL behind Portcullis. A mapping with the same name is created to point at
+// the broker's current URL, the catalog is fetched through Portcullis, and then
+// the broker in CF is updated to point at Portcullis. The JSON body must give
+// the broker's `username` and `password`, and can give a `bind_config` for the
+// mapping.
+//
+//Return codes:
+// 200 - The broker was adopted
+// 400 - The JSON body could not be parsed, or the bind config is invalid
+// 404 - CF has no service broker with the name
+// 409 - The broker already points at Portcullis
+// 502 - The catalog could not be fetched through Portcullis, or CF could not
+//       be updated. The broker still has its original URL.
+func AdoptBroker(w http.ResponseWriter, r *http.Request) {
+	var req adoptRequest
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err == nil && len(bodyBytes) > 0 {
+		err = json.Unmarshal(bodyBytes, &req)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(responsify(http.StatusBadRequest, nil, "The provided JSON body could not be parsed"))
+		return
+	}
+
+	adoption, err := broker.AdoptBroker(mux.Vars(r)["name"], broker.Registration{
+		Username: req.Username,
+		Password: req.Password,
+	}, req.BindConfig)
+	writeAdoption(w, adoption, err, "Could not adopt service broker")
+}
+
+//RollbackAdoption is an HTTP handler that points the adopted CF service broker
+// named in the URL back at its original URL, which its mapping still has. The
+// mapping is kept.
+//
+//Return codes:
+// 200 - The broker has its original URL again
+// 404 - CF has no service broker with the name
+// 409 - The broker does not point at Portcullis
+// 502 - CF could not be updated, or there's no mapping to get the URL from
+func RollbackAdoption(w http.ResponseWriter, r *http.Request) {
+	adoption, err := broker.RollbackAdoption(mux.Vars(r)["name"])
+	writeAdoption(w, adoption, err, "Could not roll back adoption of service broker")
+}
+
+//writeAdoption writes the given adoption as the response, or the error with
+// the given failure message in front if there was one
+func writeAdoption(w http.ResponseWriter, adoption broker.Adoption, err error, failure string) {
+	if err != nil {
+		returnCode := http.StatusBadGateway
+		switch {
+		case err == broker.ErrNotRegistered:
+			returnCode = http.StatusNotFound
+		case err == broker.ErrAlreadyAdopted || err == broker.ErrNotAdopted:
+			returnCode = http.StatusConflict
+		case store.IsErrInvalid(err):
+			returnCode = http.StatusBadRequest
+		}
+		w.WriteHeader(returnCode)
+		w.Write(responsify(returnCode, nil, fmt.Sprintf("%s: %s", failure, err)))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(responsify(http.StatusOK, adoption, ""))
+}
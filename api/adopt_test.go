@@ -0,0 +1,196 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/cloudfoundry-community/portcullis/api"
+	"github.com/cloudfoundry-community/portcullis/broker"
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Adopting service brokers", func() {
+	var cf *stubCF
+	//backend is the broker being adopted, and proxy is Portcullis in front of it
+	var backend, proxy *httptest.Server
+	var brokerName string
+	var body map[string]interface{}
+	var testResponse *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		Expect(Initialize(config.APIConfig{
+			Port: 5590,
+			Auth: config.AuthConfig{
+				Type: "none",
+			},
+		})).To(Succeed())
+
+		backend = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, _ := r.BasicAuth()
+			if r.URL.Path != "/v2/catalog" || username != "broker-user" || password != "broker-pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"services":[{"name":"db"}]}`))
+		}))
+		proxy = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			broker.Router().ServeHTTP(w, r)
+		}))
+
+		cf = newStubCF()
+		cf.connectBrokerAt(proxy.URL)
+		brokerName = genRandomString()
+		cf.Brokers[genRandomString()] = map[string]interface{}{
+			"name":       brokerName,
+			"broker_url": backend.URL,
+		}
+
+		bindConfig, err := genTestMapping().ToMap()
+		Expect(err).NotTo(HaveOccurred())
+		body = map[string]interface{}{
+			"username":    "broker-user",
+			"password":    "broker-pass",
+			"bind_config": bindConfig["bind_config"],
+		}
+	})
+
+	AfterEach(func() {
+		cf.close()
+		proxy.Close()
+		backend.Close()
+		store.ClearMappings()
+	})
+
+	adopt := func(name string) *httptest.ResponseRecorder {
+		j, err := json.Marshal(body)
+		Expect(err).NotTo(HaveOccurred())
+		response := httptest.NewRecorder()
+		Router().ServeHTTP(response, httptest.NewRequest("POST", "/v1/adopt/"+name, bytes.NewReader(j)))
+		return response
+	}
+
+	rollback := func(name string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		Router().ServeHTTP(response, httptest.NewRequest("POST", "/v1/adopt/"+name+"/rollback", nil))
+		return response
+	}
+
+	Describe("Adopting a broker", func() {
+		JustBeforeEach(func() {
+			testResponse = adopt(brokerName)
+		})
+
+		It("should point the broker in CF at Portcullis", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusOK))
+			Expect(cf.brokerNamed(brokerName)["broker_url"]).To(Equal(proxy.URL + "/" + brokerName))
+		})
+
+		It("should create a mapping to the original URL", func() {
+			mapping, err := store.GetMapping(brokerName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mapping.Location).To(Equal(backend.URL))
+		})
+
+		It("should return the services in the catalog", func() {
+			contents := readJSONResponse(testResponse)["contents"].(map[string]interface{})
+			Expect(contents["original_url"]).To(Equal(backend.URL))
+			Expect(contents["services"]).To(ConsistOf("db"))
+		})
+
+		Context("When the catalog can't be fetched through Portcullis", func() {
+			BeforeEach(func() {
+				body["password"] = "wrong-pass"
+			})
+
+			It("should leave the broker and store alone", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusBadGateway))
+				Expect(cf.brokerNamed(brokerName)["broker_url"]).To(Equal(backend.URL))
+				_, err := store.GetMapping(brokerName)
+				Expect(err).To(Equal(store.ErrNotFound))
+			})
+		})
+
+		Context("When CF can't be updated", func() {
+			BeforeEach(func() {
+				cf.Fail = true
+			})
+
+			It("should remove the mapping again", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusBadGateway))
+				_, err := store.GetMapping(brokerName)
+				Expect(err).To(Equal(store.ErrNotFound))
+			})
+		})
+
+		Context("When no bind config is given", func() {
+			BeforeEach(func() {
+				delete(body, "bind_config")
+			})
+
+			It("should return a status code of 400", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusBadRequest))
+				Expect(cf.brokerNamed(brokerName)["broker_url"]).To(Equal(backend.URL))
+			})
+
+			It("should say that a bind config is needed", func() {
+				meta := readJSONResponse(testResponse)["meta"].(map[string]interface{})
+				Expect(meta["message"]).To(ContainSubstring("bind config"))
+			})
+		})
+
+		Context("When CF has no such broker", func() {
+			BeforeEach(func() {
+				brokerName = genRandomString()
+			})
+
+			It("should return a status code of 404", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("When the broker has been adopted already", func() {
+			It("should return a status code of 409", func() {
+				Expect(adopt(brokerName).Code).To(Equal(http.StatusConflict))
+			})
+		})
+	})
+
+	Describe("Rolling back an adoption", func() {
+		It("should point the broker back at its original URL, and keep the mapping", func() {
+			Expect(adopt(brokerName).Code).To(Equal(http.StatusOK))
+			testResponse = rollback(brokerName)
+			Expect(testResponse.Code).To(Equal(http.StatusOK))
+			Expect(cf.brokerNamed(brokerName)["broker_url"]).To(Equal(backend.URL))
+			_, err := store.GetMapping(brokerName)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should let the broker be adopted again", func() {
+			Expect(adopt(brokerName).Code).To(Equal(http.StatusOK))
+			Expect(rollback(brokerName).Code).To(Equal(http.StatusOK))
+			delete(body, "bind_config")
+			Expect(adopt(brokerName).Code).To(Equal(http.StatusOK))
+		})
+
+		Context("When the broker hasn't been adopted", func() {
+			BeforeEach(func() {
+				testResponse = rollback(brokerName)
+			})
+
+			It("should return a status code of 409", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusConflict))
+			})
+
+			It("should say that the rollback failed", func() {
+				meta := readJSONResponse(testResponse)["meta"].(map[string]interface{})
+				Expect(meta["message"]).To(HavePrefix("Could not roll back adoption of service broker"))
+			})
+		})
+	})
+})
@@ -24,7 +24,7 @@ var (
 // to the Minor digit of the version. Other changes (bug fixes, etc) should
 // result in a change to the Patch digit of the version.
 // Major incrementation is reserved for a total rework of the API.
-const APIVersion string = "1.1.0"
+const APIVersion string = "1.2.0"
 
 //Initialize reads in the AuthConfig
 func Initialize(conf config.APIConfig) (err error) {
@@ -69,6 +69,23 @@ func Initialize(conf config.APIConfig) (err error) {
 	s.HandleFunc("/mappings", auth.Auth(CreateMapping)).Methods("POST")
 	s.HandleFunc("/mappings/{name}", auth.Auth(DeleteMapping)).Methods("DELETE")
 	s.HandleFunc("/mappings/{name}", auth.Auth(EditMapping)).Methods("PUT")
+	//Plan access
+	s.HandleFunc("/mappings/{name}/access", auth.Auth(GetAccess)).Methods("GET")
+	s.HandleFunc("/mappings/{name}/access/changes", auth.Auth(GetAccessChanges)).Methods("GET")
+	s.HandleFunc("/mappings/{name}/access/{org_guid}", auth.Auth(GrantAccess)).Methods("PUT")
+	s.HandleFunc("/mappings/{name}/access/{org_guid}", auth.Auth(RevokeAccess)).Methods("DELETE")
+	//Backfilling bindings made before Portcullis
+	s.HandleFunc("/mappings/{name}/backfill", auth.Auth(GetBackfill)).Methods("GET")
+	s.HandleFunc("/mappings/{name}/backfill", auth.Auth(Backfill)).Methods("POST")
+	//Drift
+	s.HandleFunc("/drift", auth.Auth(GetDrift)).Methods("GET")
+	s.HandleFunc("/drift/repair", auth.Auth(RepairDrift)).Methods("POST")
+	//Adopting existing service brokers
+	s.HandleFunc("/adopt/{name}", auth.Auth(AdoptBroker)).Methods("POST")
+	s.HandleFunc("/adopt/{name}/rollback", auth.Auth(RollbackAdoption)).Methods("POST")
+	//Bind failures
+	s.HandleFunc("/bind_failures", auth.Auth(GetBindFailures)).Methods("GET")
+	s.HandleFunc("/bind_failures", auth.Auth(ClearBindFailures)).Methods("DELETE")
 
 	router.NotFoundHandler = RespondNotFound{}
 	return
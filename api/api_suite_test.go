@@ -12,9 +12,12 @@ import (
 
 	"testing"
 
+	"github.com/cloudfoundry-community/portcullis/broker"
 	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
+	"github.com/cloudfoundry-community/portcullis/config"
 	"github.com/cloudfoundry-community/portcullis/store"
 	_ "github.com/cloudfoundry-community/portcullis/store/dummy"
+	"github.com/cloudfoundry-community/portcullis/testutil"
 )
 
 var apiClient http.Client
@@ -66,10 +69,12 @@ func genTestMapping() store.Mapping {
 	return store.Mapping{
 		Name:     genRandomString(),
 		Location: genRandomString(),
-		BindConfig: bindparser.Config{
-			FlavorName: "dummy",
-			Config: map[string]interface{}{
-				"confirm": true,
+		BindConfig: bindparser.ConfigList{
+			{
+				FlavorName: "dummy",
+				Config: map[string]interface{}{
+					"confirm": true,
+				},
 			},
 		},
 	}
@@ -82,3 +87,163 @@ func readJSONResponse(testResponse *httptest.ResponseRecorder) map[string]interf
 		"JSON couldn't be unmarshalled: "+testResponse.Body.String())
 	return ret
 }
+
+//stubCF is the shared stand-in for the CF API, with helpers for the api specs
+type stubCF struct {
+	*testutil.StubCF
+}
+
+const (
+	servicesPath     = testutil.ServicesPath
+	plansPath        = testutil.PlansPath
+	visibilitiesPath = testutil.VisibilitiesPath
+	instancesPath    = testutil.InstancesPath
+	bindingsPath     = testutil.ServiceBindingsPath
+	spacesPath       = testutil.SpacesPath
+	appsPath         = testutil.AppsPath
+	secGroupsPath    = testutil.SecGroupsPath
+)
+
+func newStubCF() *stubCF {
+	return &stubCF{testutil.NewStubCF()}
+}
+
+//brokerNamed returns the service broker that the stub has with the given name,
+// or nil if there isn't one
+func (cf *stubCF) brokerNamed(name string) map[string]interface{} {
+	cf.Lock()
+	defer cf.Unlock()
+	for _, entity := range cf.Brokers {
+		if entity["name"] == name {
+			return entity
+		}
+	}
+	return nil
+}
+
+//addPlan puts a plan of a service into the catalog of the service broker with
+// the given name, adding the service if the broker doesn't have it yet. Returns
+// the GUID of the plan.
+func (cf *stubCF) addPlan(brokerName, serviceName, planName string, public bool) string {
+	cf.Lock()
+	defer cf.Unlock()
+
+	var brokerGUID string
+	for guid, entity := range cf.Brokers {
+		if entity["name"] == brokerName {
+			brokerGUID = guid
+		}
+	}
+	Expect(brokerGUID).NotTo(BeEmpty(), "No service broker named "+brokerName)
+
+	var serviceGUID string
+	for guid, entity := range cf.Lists[servicesPath] {
+		if entity["label"] == serviceName && entity["service_broker_guid"] == brokerGUID {
+			serviceGUID = guid
+		}
+	}
+	if serviceGUID == "" {
+		serviceGUID = genRandomString()
+		cf.Lists[servicesPath][serviceGUID] = map[string]interface{}{
+			"label":               serviceName,
+			"service_broker_guid": brokerGUID,
+		}
+	}
+
+	planGUID := genRandomString()
+	cf.Lists[plansPath][planGUID] = map[string]interface{}{
+		"name":                planName,
+		"public":              public,
+		"service_guid":        serviceGUID,
+		"service_broker_guid": brokerGUID,
+	}
+	return planGUID
+}
+
+//visibleTo returns the GUIDs of the orgs that have been given access to the plan
+// with the given GUID
+func (cf *stubCF) visibleTo(planGUID string) []string {
+	cf.Lock()
+	defer cf.Unlock()
+	ret := []string{}
+	for _, entity := range cf.Lists[visibilitiesPath] {
+		if entity["service_plan_guid"] == planGUID {
+			ret = append(ret, entity["organization_guid"].(string))
+		}
+	}
+	return ret
+}
+
+//addBinding puts a service instance of the plan with the given GUID into a space
+// of an org, with a binding to an app in the same space that has the given
+// credentials. Returns the GUIDs of the instance and binding.
+func (cf *stubCF) addBinding(planGUID, spaceGUID, orgGUID string, creds map[string]interface{}) (string, string) {
+	cf.Lock()
+	instanceGUID := genRandomString()
+	cf.Lists[instancesPath][instanceGUID] = map[string]interface{}{
+		"service_plan_guid": planGUID,
+		"space_guid":        spaceGUID,
+	}
+	cf.Unlock()
+	return instanceGUID, cf.addAppBinding(instanceGUID, spaceGUID, orgGUID, creds)
+}
+
+//addAppBinding binds the service instance with the given GUID to a new app in a
+// space of an org, which needn't be the instance's space if the instance is
+// shared. Returns the GUID of the binding.
+func (cf *stubCF) addAppBinding(instanceGUID, spaceGUID, orgGUID string, creds map[string]interface{}) string {
+	cf.Lock()
+	defer cf.Unlock()
+
+	cf.Lists[spacesPath][spaceGUID] = map[string]interface{}{"organization_guid": orgGUID}
+	appGUID := genRandomString()
+	cf.Lists[appsPath][appGUID] = map[string]interface{}{"space_guid": spaceGUID}
+	bindingGUID := genRandomString()
+	cf.Lists[bindingsPath][bindingGUID] = map[string]interface{}{
+		"app_guid":              appGUID,
+		"service_instance_guid": instanceGUID,
+		"credentials":           creds,
+	}
+	return bindingGUID
+}
+
+//secGroups returns the security group entities that the stub has
+func (cf *stubCF) secGroups() []map[string]interface{} {
+	cf.Lock()
+	defer cf.Unlock()
+	ret := []map[string]interface{}{}
+	for _, entity := range cf.Lists[secGroupsPath] {
+		ret = append(ret, entity)
+	}
+	return ret
+}
+
+//connectBroker points the broker package at the stub
+func (cf *stubCF) connectBroker() {
+	cf.connectBrokerAt("https://portcullis.example.com/")
+}
+
+//connectBrokerAt points the broker package at the stub, with the given URL as
+// the one that CF reaches Portcullis at
+func (cf *stubCF) connectBrokerAt(publicURL string) {
+	Expect(broker.Initialize(config.BrokerConfig{
+		Port:         5591,
+		CFAPIAddress: cf.Server.URL,
+		CFAdmin:      "admin",
+		CFPassword:   "admin",
+		PublicURL:    publicURL,
+	})).To(Succeed())
+}
+
+//close shuts the stub down and leaves the broker package without a connection
+// to CF, as it is in the rest of the specs
+func (cf *stubCF) close() {
+	cf.Close()
+	Expect(broker.Initialize(config.BrokerConfig{
+		Port:         5591,
+		CFAPIAddress: cf.Server.URL,
+		CFAdmin:      "admin",
+		CFPassword:   "admin",
+	})).NotTo(Succeed())
+	Expect(broker.CFClient()).To(BeNil())
+}
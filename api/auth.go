@@ -11,10 +11,17 @@ import (
 // and then calls the provided HandlerFunc if authorization was deemed successful
 // Auth should set the Content-Type header to "application/json" before forwarding
 // to the mapped function
+//User must return the name of the user that made the given request, which has
+// already been authorized, or AnonymousUser if users aren't told apart.
 type Authorizer interface {
 	Auth(http.HandlerFunc) http.HandlerFunc
+	User(*http.Request) string
 }
 
+//AnonymousUser is the name given to the user of requests that aren't
+// authenticated, so that records of who did something always name someone
+const AnonymousUser = "anonymous"
+
 //NopAuth provides an Auth function that does nothing before calling the
 // provided HandlerFunc
 type NopAuth struct {
@@ -29,6 +36,11 @@ func (n *NopAuth) Auth(h http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+//User returns AnonymousUser, because anyone can make requests
+func (n *NopAuth) User(*http.Request) string {
+	return AnonymousUser
+}
+
 //BasicAuth provides an Auth function that checks to see if the Authorization
 // header provides a set of credentials matching those that were provided at
 // configuration time.
@@ -71,6 +83,12 @@ func (b *BasicAuth) Auth(h http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+//User returns the username from the basic auth header
+func (b *BasicAuth) User(request *http.Request) string {
+	username, _, _ := request.BasicAuth()
+	return username
+}
+
 //The easy part of basic auth
 func (b *BasicAuth) isAuthorized(username, password string) bool {
 	return username == b.Username && password == b.Password
@@ -118,6 +118,10 @@ var _ = Describe("Auth", func() {
 				testRequest.SetBasicAuth("foo", "bar")
 			})
 			testAuthSuccess()
+
+			It("should name the user as anonymous", func() {
+				Expect(SelectedAuth().User(testRequest)).To(Equal(AnonymousUser))
+			})
 		})
 	})
 
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cloudfoundry-community/portcullis/broker"
+	"github.com/cloudfoundry-community/portcullis/store"
+	"github.com/gorilla/mux"
+)
+
+//BackfillResponse contains the information to be written to the body in
+// response to a call to the GetBackfill or Backfill handlers, to be marshalled
+// to JSON.
+type BackfillResponse struct {
+	//Count should be set to the length of the Bindings slice
+	Count int `json:"count"`
+	//Bindings has an entry for every CF service binding to an instance of one of
+	// the mapping's services
+	Bindings []broker.Backfill `json:"bindings"`
+	//DryRun is true if nothing was changed
+	DryRun bool `json:"dry_run"`
+}
+
+//GetBackfill is an HTTP handler that reports what a backfill of the mapping
+// named in the URL would do, without changing anything.
+//
+//Return codes:
+// 200 - The report was made. The bindings list is empty if there are none.
+// 404 - There is no such mapping, or it has no service broker in CF.
+// 500 - Internal error - i.e CF or the store cannot be reached
+func GetBackfill(w http.ResponseWriter, r *http.Request) {
+	backfillHelper(w, r, true)
+}
+
+//Backfill is an HTTP handler that opens egress for the CF service bindings of
+// the mapping named in the URL that were made before its broker was behind
+// Portcullis, creating and recording their security groups.
+//
+//Return codes:
+// 200 - The backfill was done. Each binding entry says how it went.
+// 404 - There is no such mapping, or it has no service broker in CF.
+// 500 - Internal error - i.e CF or the store cannot be reached
+func Backfill(w http.ResponseWriter, r *http.Request) {
+	backfillHelper(w, r, false)
+}
+
+func backfillHelper(w http.ResponseWriter, r *http.Request, dryRun bool) {
+	name := mux.Vars(r)["name"]
+	backfills, err := broker.BackfillBindings(name, dryRun)
+	if err != nil {
+		returnCode := http.StatusInternalServerError
+		message := fmt.Sprintf("Could not backfill bindings: %s", err)
+		switch err {
+		case store.ErrNotFound:
+			returnCode = http.StatusNotFound
+			message = fmt.Sprintf("No mapping in store with name: `%s`", name)
+		case broker.ErrNotRegistered:
+			returnCode = http.StatusNotFound
+		}
+		w.WriteHeader(returnCode)
+		w.Write(responsify(returnCode, nil, message))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(responsify(http.StatusOK, BackfillResponse{
+		Count:    len(backfills),
+		Bindings: backfills,
+		DryRun:   dryRun,
+	}, ""))
+}
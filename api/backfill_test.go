@@ -0,0 +1,164 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/cloudfoundry-community/portcullis/api"
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Backfilling bindings", func() {
+	var cf *stubCF
+	var mapping store.Mapping
+	var instanceGUID, bindingGUID, badBindingGUID string
+	var method, mappingName string
+	var testResponse *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		Expect(Initialize(config.APIConfig{
+			Port: 5590,
+			Auth: config.AuthConfig{
+				Type: "none",
+			},
+		})).To(Succeed())
+		cf = newStubCF()
+		cf.connectBroker()
+
+		mapping = genTestMapping()
+		mappingName = mapping.Name
+		Expect(store.AddMapping(mapping)).To(Succeed())
+		cf.Brokers[genRandomString()] = map[string]interface{}{"name": mapping.Name}
+		plan := cf.addPlan(mapping.Name, "db", "small", true)
+		instanceGUID, bindingGUID = cf.addBinding(plan, "some-space", "some-org", map[string]interface{}{
+			"host": "10.0.0.5",
+			"port": 6379,
+		})
+		_, badBindingGUID = cf.addBinding(plan, "some-space", "some-org", map[string]interface{}{
+			"uri": "redis://10.0.0.6:6379",
+		})
+	})
+
+	AfterEach(func() {
+		cf.close()
+		store.ClearMappings()
+		store.ClearBindingInfo()
+		store.ClearSecGroupInfo()
+	})
+
+	JustBeforeEach(func() {
+		testResponse = httptest.NewRecorder()
+		Router().ServeHTTP(testResponse, httptest.NewRequest(method, "/v1/mappings/"+mappingName+"/backfill", nil))
+	})
+
+	//statuses returns the status of each binding in the response, by GUID
+	statuses := func() map[string]interface{} {
+		contents := readJSONResponse(testResponse)["contents"].(map[string]interface{})
+		ret := map[string]interface{}{}
+		for _, binding := range contents["bindings"].([]interface{}) {
+			ret[binding.(map[string]interface{})["binding_guid"].(string)] = binding.(map[string]interface{})["status"]
+		}
+		return ret
+	}
+
+	Describe("A dry run", func() {
+		BeforeEach(func() {
+			method = "GET"
+		})
+
+		It("should report what would be done for each binding", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusOK))
+			Expect(statuses()).To(Equal(map[string]interface{}{
+				bindingGUID:    "planned",
+				badBindingGUID: "failed",
+			}))
+		})
+
+		It("should not change anything", func() {
+			Expect(cf.secGroups()).To(BeEmpty())
+			Expect(store.ListBindingInfo()).To(BeEmpty())
+		})
+	})
+
+	Describe("A backfill", func() {
+		BeforeEach(func() {
+			method = "POST"
+		})
+
+		It("should create and record the security group", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusOK))
+			Expect(statuses()[bindingGUID]).To(Equal("created"))
+			Expect(cf.secGroups()).To(HaveLen(1))
+			Expect(cf.secGroups()[0]["space_guids"]).To(ConsistOf("some-space"))
+
+			binding, err := store.GetBindingInfo(bindingGUID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(binding.ServiceInstanceGUID).To(Equal(instanceGUID))
+			Expect(binding.OrganizationGUID).To(Equal("some-org"))
+			Expect(binding.Rules).To(HaveLen(1))
+			Expect(binding.Rules[0].Destination).To(Equal("10.0.0.5"))
+		})
+
+		It("should say why a binding failed", func() {
+			contents := readJSONResponse(testResponse)["contents"].(map[string]interface{})
+			for _, binding := range contents["bindings"].([]interface{}) {
+				if binding.(map[string]interface{})["binding_guid"] == badBindingGUID {
+					Expect(binding.(map[string]interface{})["error"]).NotTo(BeEmpty())
+				}
+			}
+		})
+
+		Context("When the instance is shared with an app in another space", func() {
+			var sharedBindingGUID string
+
+			BeforeEach(func() {
+				sharedBindingGUID = cf.addAppBinding(instanceGUID, "other-space", "other-org", map[string]interface{}{
+					"host": "10.0.0.5",
+					"port": 6379,
+				})
+			})
+
+			It("should put the binding's security group in the app's space", func() {
+				Expect(statuses()[sharedBindingGUID]).To(Equal("created"))
+
+				binding, err := store.GetBindingInfo(sharedBindingGUID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(binding.SpaceGUID).To(Equal("other-space"))
+				Expect(binding.OrganizationGUID).To(Equal("other-org"))
+
+				var spaces []interface{}
+				for _, secGroup := range cf.secGroups() {
+					spaces = append(spaces, secGroup["space_guids"].([]interface{})...)
+				}
+				Expect(spaces).To(ConsistOf("some-space", "other-space"))
+			})
+		})
+
+		Context("When the bindings have been backfilled already", func() {
+			BeforeEach(func() {
+				testResponse = httptest.NewRecorder()
+				Router().ServeHTTP(testResponse, httptest.NewRequest("POST", "/v1/mappings/"+mappingName+"/backfill", nil))
+				Expect(testResponse.Code).To(Equal(http.StatusOK))
+			})
+
+			It("should leave them alone", func() {
+				Expect(statuses()[bindingGUID]).To(Equal("recorded"))
+				Expect(cf.secGroups()).To(HaveLen(1))
+			})
+		})
+
+		Context("When the mapping doesn't exist", func() {
+			BeforeEach(func() {
+				mappingName = genRandomString()
+			})
+
+			It("should return a status code of 404", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+})
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cloudfoundry-community/portcullis/broker"
+)
+
+//DriftResponse contains the information to be written to the body in response
+// to a call to the GetDrift or RepairDrift handlers, to be marshalled to JSON.
+type DriftResponse struct {
+	//Count should be set to the length of the Drift slice
+	Count int `json:"count"`
+	//Drift has an entry for every security group that doesn't match what the
+	// bindings in the store expect
+	Drift []broker.Drift `json:"drift"`
+	//Repair is true if Portcullis attempted to repair the drift
+	Repair bool `json:"repair"`
+}
+
+//GetDrift is an HTTP handler that compares the security groups in CF with what
+// the store expects them to be, and returns the differences.
+//
+//Return codes:
+// 200 - The check was done. The drift list is empty if nothing has drifted.
+// 500 - Internal error - i.e CF or the store cannot be reached
+func GetDrift(w http.ResponseWriter, r *http.Request) {
+	driftHelper(w, false)
+}
+
+//RepairDrift is an HTTP handler that does the same check as GetDrift, and then
+// re-applies the expected rules and spaces to every group that has drifted.
+//
+//Return codes:
+// 200 - The check was done. Each drift entry says whether it was repaired.
+// 500 - Internal error - i.e CF or the store cannot be reached
+func RepairDrift(w http.ResponseWriter, r *http.Request) {
+	driftHelper(w, true)
+}
+
+func driftHelper(w http.ResponseWriter, repair bool) {
+	drifts, err := broker.CheckDrift(repair)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(responsify(http.StatusInternalServerError, nil, fmt.Sprintf("Could not check for drift: %s", err)))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(responsify(http.StatusOK, DriftResponse{
+		Count:  len(drifts),
+		Drift:  drifts,
+		Repair: repair,
+	}, ""))
+}
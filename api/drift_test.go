@@ -0,0 +1,238 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/cloudfoundry-community/portcullis/api"
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Drift", func() {
+	var testResponse *httptest.ResponseRecorder
+	var method, path string
+
+	BeforeEach(func() {
+		Expect(Initialize(config.APIConfig{
+			Port: 5590,
+			Auth: config.AuthConfig{
+				Type: "none",
+			},
+		})).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		testRequest := httptest.NewRequest(method, path, nil)
+		testResponse = httptest.NewRecorder()
+		Router().ServeHTTP(testResponse, testRequest)
+	})
+
+	assertNotConnected := func() {
+		It("should return a status code of 500", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusInternalServerError))
+		})
+
+		It("should have a meta status of Error", func() {
+			meta := readJSONResponse(testResponse)["meta"].(map[string]interface{})
+			Expect(meta["status"]).To(Equal(MetaStatusError))
+			Expect(meta["message"]).To(ContainSubstring("Cloud Foundry"))
+		})
+	}
+
+	Context("Checking for drift without a connection to CF", func() {
+		BeforeEach(func() {
+			method, path = "GET", "/v1/drift"
+		})
+		assertNotConnected()
+	})
+
+	Context("Repairing drift without a connection to CF", func() {
+		BeforeEach(func() {
+			method, path = "POST", "/v1/drift/repair"
+		})
+		assertNotConnected()
+	})
+
+	Context("With a connection to CF", func() {
+		var cf *stubCF
+		var secGroupGUID string
+
+		BeforeEach(func() {
+			cf = newStubCF()
+			cf.connectBroker()
+
+			//Backfilling is the quickest way to get a security group made and recorded
+			mapping := genTestMapping()
+			Expect(store.AddMapping(mapping)).To(Succeed())
+			cf.Brokers[genRandomString()] = map[string]interface{}{"name": mapping.Name}
+			plan := cf.addPlan(mapping.Name, "db", "small", true)
+			cf.addBinding(plan, "some-space", "some-org", map[string]interface{}{
+				"host": "10.0.0.5",
+				"port": 6379,
+			})
+			backfillResponse := httptest.NewRecorder()
+			Router().ServeHTTP(backfillResponse, httptest.NewRequest("POST", "/v1/mappings/"+mapping.Name+"/backfill", nil))
+			Expect(backfillResponse.Code).To(Equal(http.StatusOK))
+
+			infos, err := store.ListSecGroupInfo()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(infos).To(HaveLen(1))
+			secGroupGUID = infos[0].SecGroupGUID
+		})
+
+		AfterEach(func() {
+			cf.close()
+			store.ClearMappings()
+			store.ClearBindingInfo()
+			store.ClearSecGroupInfo()
+		})
+
+		//changeSecGroup edits the security group in the stub behind Portcullis' back
+		changeSecGroup := func(change func(entity map[string]interface{})) {
+			cf.Lock()
+			defer cf.Unlock()
+			change(cf.Lists[secGroupsPath][secGroupGUID])
+		}
+
+		drifts := func() []interface{} {
+			contents := readJSONResponse(testResponse)["contents"].(map[string]interface{})
+			return contents["drift"].([]interface{})
+		}
+
+		//checkAgain asserts that a fresh check finds nothing drifted
+		checkAgain := func() {
+			response := httptest.NewRecorder()
+			Router().ServeHTTP(response, httptest.NewRequest("GET", "/v1/drift", nil))
+			Expect(response.Code).To(Equal(http.StatusOK))
+			contents := readJSONResponse(response)["contents"].(map[string]interface{})
+			Expect(contents["count"]).To(BeEquivalentTo(0))
+		}
+
+		Context("When nothing has drifted", func() {
+			BeforeEach(func() {
+				method, path = "GET", "/v1/drift"
+			})
+
+			It("should report no drift", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusOK))
+				Expect(drifts()).To(BeEmpty())
+			})
+		})
+
+		Context("When the group is missing from CF", func() {
+			BeforeEach(func() {
+				cf.Lock()
+				delete(cf.Lists[secGroupsPath], secGroupGUID)
+				cf.Unlock()
+			})
+
+			Context("and drift is checked", func() {
+				BeforeEach(func() {
+					method, path = "GET", "/v1/drift"
+				})
+
+				It("should report the group as missing, and leave it that way", func() {
+					Expect(testResponse.Code).To(Equal(http.StatusOK))
+					Expect(drifts()).To(HaveLen(1))
+					drift := drifts()[0].(map[string]interface{})
+					Expect(drift["missing"]).To(BeTrue())
+					Expect(drift["repaired"]).To(BeFalse())
+					Expect(cf.secGroups()).To(BeEmpty())
+				})
+			})
+
+			Context("and drift is repaired", func() {
+				BeforeEach(func() {
+					method, path = "POST", "/v1/drift/repair"
+				})
+
+				It("should re-create the group", func() {
+					Expect(testResponse.Code).To(Equal(http.StatusOK))
+					Expect(drifts()[0].(map[string]interface{})["repaired"]).To(BeTrue())
+					Expect(cf.secGroups()).To(HaveLen(1))
+					Expect(cf.secGroups()[0]["space_guids"]).To(ConsistOf("some-space"))
+					checkAgain()
+				})
+			})
+		})
+
+		Context("When the group has an extra rule and is missing one", func() {
+			BeforeEach(func() {
+				changeSecGroup(func(entity map[string]interface{}) {
+					entity["rules"] = []interface{}{
+						map[string]interface{}{"protocol": "tcp", "destination": "10.9.9.9", "ports": "22"},
+					}
+				})
+			})
+
+			Context("and drift is checked", func() {
+				BeforeEach(func() {
+					method, path = "GET", "/v1/drift"
+				})
+
+				It("should report both rules", func() {
+					Expect(testResponse.Code).To(Equal(http.StatusOK))
+					Expect(drifts()).To(HaveLen(1))
+					drift := drifts()[0].(map[string]interface{})
+					Expect(drift["missing_rules"]).To(HaveLen(1))
+					Expect(drift["missing_rules"].([]interface{})[0].(map[string]interface{})["destination"]).To(Equal("10.0.0.5"))
+					Expect(drift["extra_rules"]).To(HaveLen(1))
+					Expect(drift["extra_rules"].([]interface{})[0].(map[string]interface{})["destination"]).To(Equal("10.9.9.9"))
+				})
+			})
+
+			Context("and drift is repaired", func() {
+				BeforeEach(func() {
+					method, path = "POST", "/v1/drift/repair"
+				})
+
+				It("should put the expected rules back", func() {
+					Expect(testResponse.Code).To(Equal(http.StatusOK))
+					Expect(drifts()[0].(map[string]interface{})["repaired"]).To(BeTrue())
+					rules := cf.secGroups()[0]["rules"].([]interface{})
+					Expect(rules).To(HaveLen(1))
+					Expect(rules[0].(map[string]interface{})["destination"]).To(Equal("10.0.0.5"))
+					checkAgain()
+				})
+			})
+		})
+
+		Context("When the group is bound to the wrong space", func() {
+			BeforeEach(func() {
+				changeSecGroup(func(entity map[string]interface{}) {
+					entity["space_guids"] = []interface{}{"other-space"}
+				})
+			})
+
+			Context("and drift is repaired", func() {
+				BeforeEach(func() {
+					method, path = "POST", "/v1/drift/repair"
+				})
+
+				It("should report and fix both spaces", func() {
+					Expect(testResponse.Code).To(Equal(http.StatusOK))
+					drift := drifts()[0].(map[string]interface{})
+					Expect(drift["missing_spaces"]).To(ConsistOf("some-space"))
+					Expect(drift["extra_spaces"]).To(ConsistOf("other-space"))
+					Expect(drift["repaired"]).To(BeTrue())
+					Expect(cf.secGroups()[0]["space_guids"]).To(ConsistOf("some-space"))
+					checkAgain()
+				})
+			})
+		})
+	})
+
+	Context("Repairing drift with a GET", func() {
+		BeforeEach(func() {
+			method, path = "GET", "/v1/drift/repair"
+		})
+
+		It("should not be routed", func() {
+			Expect(testResponse.Code).NotTo(Equal(http.StatusOK))
+		})
+	})
+})
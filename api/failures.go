@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cloudfoundry-community/portcullis/store"
+)
+
+//GetBindFailuresResponse contains the information to be written to the body in
+// response to a call to the GetBindFailures handler, to be marshalled to JSON.
+type GetBindFailuresResponse struct {
+	//Count should be set to the length of the Failures slice
+	Count int `json:"count"`
+	//Failures are the binds that Portcullis could not open egress for, oldest
+	// first
+	Failures []store.BindFailure `json:"failures"`
+}
+
+//GetBindFailures is an HTTP handler that returns the binds that the backend
+// broker accepted, but that Portcullis could not open egress for.
+//
+//Return codes:
+// 200 - The failures were returned. The list is empty if there were none.
+// 500 - Internal error - i.e Store cannot be reached
+func GetBindFailures(w http.ResponseWriter, r *http.Request) {
+	failures, err := store.ListBindFailures()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(responsify(http.StatusInternalServerError, nil, MetaMessageStoreError))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(responsify(http.StatusOK, GetBindFailuresResponse{
+		Count:    len(failures),
+		Failures: failures,
+	}, ""))
+}
+
+//ClearBindFailures is an HTTP handler that forgets all of the recorded bind
+// failures, such as once an operator has dealt with them.
+//
+//Return codes:
+// 200 - The failures were cleared.
+// 500 - Internal error - i.e Store cannot be reached
+func ClearBindFailures(w http.ResponseWriter, r *http.Request) {
+	if err := store.ClearBindFailures(); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(responsify(http.StatusInternalServerError, nil, MetaMessageStoreError))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(responsify(http.StatusOK, nil, ""))
+}
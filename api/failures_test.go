@@ -0,0 +1,94 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/cloudfoundry-community/portcullis/api"
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bind failures", func() {
+	var testResponse *httptest.ResponseRecorder
+	var method string
+
+	BeforeEach(func() {
+		Expect(Initialize(config.APIConfig{
+			Port: 5590,
+			Auth: config.AuthConfig{
+				Type: "none",
+			},
+		})).To(Succeed())
+		Expect(store.ClearBindFailures()).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		testRequest := httptest.NewRequest(method, "/v1/bind_failures", nil)
+		testResponse = httptest.NewRecorder()
+		Router().ServeHTTP(testResponse, testRequest)
+	})
+
+	Describe("Getting the failures", func() {
+		BeforeEach(func() {
+			method = "GET"
+		})
+
+		Context("When there are none", func() {
+			It("should return a status code of 200", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusOK))
+			})
+
+			It("should return an empty list", func() {
+				contents := readJSONResponse(testResponse)["contents"].(map[string]interface{})
+				Expect(contents["count"]).To(BeEquivalentTo(0))
+				Expect(contents["failures"]).To(BeEmpty())
+			})
+		})
+
+		Context("When a bind has failed", func() {
+			var failure store.BindFailure
+			BeforeEach(func() {
+				failure = store.BindFailure{
+					BindingGUID:         genRandomString(),
+					ServiceInstanceGUID: genRandomString(),
+					MappingName:         genRandomString(),
+					Time:                time.Now().UTC(),
+					Error:               "app_guid was not found in CF service broker request",
+					Unbound:             true,
+				}
+				Expect(store.AddBindFailure(failure)).To(Succeed())
+			})
+
+			It("should return the failure", func() {
+				contents := readJSONResponse(testResponse)["contents"].(map[string]interface{})
+				Expect(contents["count"]).To(BeEquivalentTo(1))
+				failures := contents["failures"].([]interface{})
+				Expect(failures[0].(map[string]interface{})["binding_guid"]).To(Equal(failure.BindingGUID))
+				Expect(failures[0].(map[string]interface{})["error"]).To(Equal(failure.Error))
+				Expect(failures[0].(map[string]interface{})["unbound"]).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Clearing the failures", func() {
+		BeforeEach(func() {
+			method = "DELETE"
+			Expect(store.AddBindFailure(store.BindFailure{BindingGUID: genRandomString()})).To(Succeed())
+		})
+
+		It("should return a status code of 200", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should remove them from the store", func() {
+			failures, err := store.ListBindFailures()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(failures).To(BeEmpty())
+		})
+	})
+})
@@ -8,10 +8,22 @@ import (
 
 	"encoding/json"
 
+	"github.com/cloudfoundry-community/portcullis/broker"
 	"github.com/cloudfoundry-community/portcullis/store"
 	"github.com/gorilla/mux"
+	"github.com/starkandwayne/goutils/log"
 )
 
+//registrationField is the key in the body of a create or edit call that asks
+// for the mapping to be registered with CF as a service broker as well
+const registrationField = "cf_registration"
+
+//registrationRequest is the part of a create or edit call body that isn't the
+// mapping itself
+type registrationRequest struct {
+	Registration *broker.Registration `json:"cf_registration"`
+}
+
 //GetMappingsResponse contains the information to be written to the body in
 // response to a call to the GetMappings handler, to be marshalled to JSON.
 type GetMappingsResponse struct {
@@ -92,7 +104,9 @@ func getAllMappingsHelper() (returnCode int, message string, contents interface{
 //CreateMapping is an HTTP handler that creates a new mapping in the store from
 // the JSON provided in the POST request BODY. If required keys are missing, an
 // error will be generated and the API call will fail. Extraneous keys which are
-// present will be ignored but generate a warning.
+// present will be ignored but generate a warning. If `cf_registration` is given,
+// a CF service broker with the same name as the mapping is registered to point
+// at it.
 //
 //Return codes:
 // 201 - The mapping was successfully created
@@ -100,6 +114,8 @@ func getAllMappingsHelper() (returnCode int, message string, contents interface{
 //       violates a constraint.
 // 409 - A mapping with this name already exists in the store
 // 500 - Internal error - i.e Store cannot be reached
+// 502 - The service broker could not be registered with CF. The mapping is
+//       not created.
 func CreateMapping(w http.ResponseWriter, r *http.Request) {
 	returnCode, message, warning := createMappingHelper(r)
 	var respBody []byte
@@ -132,7 +148,7 @@ func createMappingHelper(r *http.Request) (returnCode int, message, warning stri
 	//Check if there are any extraneous fields in the JSON body
 	var additionalFields []string
 	for k := range m {
-		if !isMappingField(k) {
+		if !isMappingField(k) && k != registrationField {
 			additionalFields = append(additionalFields, k)
 		}
 	}
@@ -152,6 +168,11 @@ func createMappingHelper(r *http.Request) (returnCode int, message, warning stri
 		// fields were probably of the wrong type
 		return http.StatusBadRequest, "There was an error while parsing the JSON body (are your fields of the wrong type?)", warning
 	}
+	var reg registrationRequest
+	err = json.Unmarshal(bodyBytes, &reg)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Sprintf("There was an error while parsing `%s` (are your fields of the wrong type?)", registrationField), warning
+	}
 	err = store.AddMapping(mapping)
 	if err != nil {
 		if err == store.ErrDuplicate {
@@ -166,19 +187,36 @@ func createMappingHelper(r *http.Request) (returnCode int, message, warning stri
 			"Encountered an error while contacting the backend store",
 			warning
 	}
+	//The mapping has to exist first, because CF asks the new broker for its
+	// catalog through Portcullis
+	if reg.Registration != nil {
+		err = broker.RegisterBroker(mapping.Name, *reg.Registration)
+		if err != nil {
+			if delErr := store.DeleteMapping(mapping.Name); delErr != nil {
+				log.Errorf("Could not remove mapping `%s` after failed registration: %s", mapping.Name, delErr)
+			}
+			return http.StatusBadGateway,
+				fmt.Sprintf("Could not register the service broker with Cloud Foundry: %s", err),
+				warning
+		}
+	}
 	return http.StatusCreated, "", warning
 }
 
 //EditMapping is an HTTP handler that edits the mapping with the name provided
 // in the URL to have the information provided by the JSON in the PUT request
 // body. Keys which are not present will retain their initial values. Extraneous
-// keys which are present will be ignored but generate a warning.
+// keys which are present will be ignored but generate a warning. If
+// `cf_registration` is given, the CF service broker with the mapping's old name
+// is updated to match the mapping, or registered if it doesn't exist.
 //
 //Return codes:
 // 200 - The edit was successful
 // 400 - The mapping is missing field(s), or field(s) violate restrictions
 // 404 - No mapping with that name exists.
 // 500 - Internal error - i.e. Store cannot be reached.
+// 502 - The service broker could not be updated in CF. The mapping is not
+//       changed.
 func EditMapping(w http.ResponseWriter, r *http.Request) {
 	name, found := mux.Vars(r)["name"]
 	if !found {
@@ -206,6 +244,7 @@ func editMappingHelper(name string, r *http.Request) (returnCode int, message, w
 		}
 		return http.StatusInternalServerError, "Encountered an error while contacting the backend store", ""
 	}
+	prevMapping := origMapping
 	//Convert the mapping into a map we can edit more easily
 	var origMappingMap map[string]interface{}
 	origMappingMap, err = origMapping.ToMap()
@@ -227,6 +266,11 @@ func editMappingHelper(name string, r *http.Request) (returnCode int, message, w
 	if err != nil {
 		return http.StatusBadRequest, "The provided JSON body could not be parsed", ""
 	}
+	var reg registrationRequest
+	err = json.Unmarshal(bodyBytes, &reg)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Sprintf("There was an error while parsing `%s` (are your fields of the wrong type?)", registrationField), ""
+	}
 	var changedFields = 0
 
 	//Merge the requested fields on top of the existing mapping object
@@ -235,6 +279,8 @@ func editMappingHelper(name string, r *http.Request) (returnCode int, message, w
 		if isMappingField(k) {
 			origMappingMap[k] = v
 			changedFields++
+		} else if k == registrationField {
+			changedFields++
 		} else {
 			additionalFields = append(additionalFields, k)
 		}
@@ -276,28 +322,57 @@ func editMappingHelper(name string, r *http.Request) (returnCode int, message, w
 			"Encountered an error while contacting the backend store",
 			warning
 	}
+	if reg.Registration != nil {
+		err = broker.UpdateBrokerRegistration(name, origMapping.Name, *reg.Registration)
+		if err != nil {
+			if undoErr := store.EditMapping(origMapping.Name, prevMapping); undoErr != nil {
+				log.Errorf("Could not undo edit of mapping `%s` after failed registration: %s", name, undoErr)
+			}
+			return http.StatusBadGateway,
+				fmt.Sprintf("Could not update the service broker in Cloud Foundry: %s", err),
+				warning
+		}
+	}
 	return http.StatusOK, "", warning
 }
 
 //DeleteMapping is an HTTP handler that removes the mapping with the name
-// provided in the URL from the store.
+// provided in the URL from the store. If the `unregister` query parameter is
+// `true`, the CF service broker with the same name is deleted first.
 //
 //Return codes:
 // 200 - The removal was successful
 // 404 - The mapping is already not present in the store
 // 500 - Internal error - i.e. Store cannot be reached.
+// 502 - The service broker could not be deleted from CF, such as when it still
+//       has service instances. The mapping is not removed.
 func DeleteMapping(w http.ResponseWriter, r *http.Request) {
 	var name string
 	if varName, nameSpecified := mux.Vars(r)["name"]; nameSpecified {
 		name = varName
 	}
-	returnCode, message := deleteMappingHelper(name)
+	returnCode, message := deleteMappingHelper(name, r.URL.Query().Get("unregister") == "true")
 	w.WriteHeader(returnCode)
 	respBody := responsify(returnCode, nil, message)
 	w.Write(respBody)
 }
 
-func deleteMappingHelper(name string) (returnCode int, message string) {
+func deleteMappingHelper(name string, unregister bool) (returnCode int, message string) {
+	if unregister {
+		_, err := store.GetMapping(name)
+		if err != nil {
+			if err == store.ErrNotFound {
+				return http.StatusNotFound, "No mapping with that name exists in the backend store"
+			}
+			return http.StatusInternalServerError, "Encountered an error while contacting the backend store"
+		}
+
+		err = broker.UnregisterBroker(name)
+		if err != nil {
+			return http.StatusBadGateway, fmt.Sprintf("Could not delete the service broker from Cloud Foundry: %s", err)
+		}
+	}
+
 	err := store.DeleteMapping(name)
 	if err != nil {
 		if err == store.ErrNotFound {
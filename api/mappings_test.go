@@ -356,6 +356,125 @@ var _ = Describe("Mappings", func() {
 			})
 		})
 
+		Context("For a mapping with more than one bind config", func() {
+			var testMapping store.Mapping
+			BeforeEach(func() {
+				testMapping = genTestMapping()
+				testMapping = testMapping.WithConfig(testMapping.BindConfig[0], bindparser.Config{
+					FlavorName: "static",
+					Config: map[string]interface{}{
+						"rules": []interface{}{
+							map[string]interface{}{"destination": "10.0.0.5", "ports": "9100"},
+						},
+					},
+				})
+				assignBody(mappingToJSON(testMapping))
+			})
+
+			It("should return a code of 201", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusCreated))
+			})
+
+			Specify("The mapping should be present in the store with all of its configs", func() {
+				var m store.Mapping
+				m, err = store.GetMapping(testMapping.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(m.BindConfig).To(HaveLen(2))
+				Expect(m.BindConfig[1].FlavorName).To(Equal("static"))
+			})
+		})
+
+		Context("For a mapping whose bind_config is a single object", func() {
+			var testMapping store.Mapping
+			BeforeEach(func() {
+				testMapping = genTestMapping()
+				testMap, err := testMapping.ToMap()
+				Expect(err).NotTo(HaveOccurred())
+				testMap["bind_config"] = testMap["bind_config"].([]interface{})[0]
+				var j []byte
+				j, err = json.Marshal(testMap)
+				Expect(err).NotTo(HaveOccurred())
+				assignBody(j)
+			})
+
+			It("should return a code of 201", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusCreated))
+			})
+
+			Specify("The mapping should be present in the store", func() {
+				var m store.Mapping
+				m, err = store.GetMapping(testMapping.Name)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(m).To(Equal(testMapping))
+			})
+		})
+
+		Context("For a mapping with an empty list of bind configs", func() {
+			var testMapping store.Mapping
+			BeforeEach(func() {
+				testMapping = genTestMapping().WithConfig()
+				assignBody(mappingToJSON(testMapping))
+			})
+
+			It("should have a return code of 400", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("For a mapping with a static rule that has no ports", func() {
+			var testMapping store.Mapping
+			BeforeEach(func() {
+				testMapping = genTestMapping().WithConfig(bindparser.Config{
+					FlavorName: "static",
+					Config: map[string]interface{}{
+						"rules": []interface{}{
+							map[string]interface{}{"destination": "10.0.0.5"},
+						},
+					},
+				})
+				assignBody(mappingToJSON(testMapping))
+			})
+
+			It("should have a return code of 400", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("For a mapping with a static hostname destination", func() {
+			var testMapping store.Mapping
+			BeforeEach(func() {
+				testMapping = genTestMapping().WithConfig(bindparser.Config{
+					FlavorName: "static",
+					Config: map[string]interface{}{
+						"rules": []interface{}{
+							map[string]interface{}{"destination": "db.example.com", "ports": "5432"},
+						},
+					},
+				})
+			})
+
+			Context("When the mapping doesn't resolve hostnames", func() {
+				BeforeEach(func() {
+					assignBody(mappingToJSON(testMapping))
+				})
+
+				It("should have a return code of 400", func() {
+					Expect(testResponse.Code).To(Equal(http.StatusBadRequest))
+				})
+			})
+
+			Context("When the mapping resolves hostnames", func() {
+				BeforeEach(func() {
+					testMapping.ResolveHostnames = true
+					assignBody(mappingToJSON(testMapping))
+				})
+
+				It("should return a code of 201", func() {
+					Expect(testResponse.Code).To(Equal(http.StatusCreated))
+				})
+			})
+		})
+
 		Context("For a mapping with a name that already exists in the storage backend", func() {
 			var origMapping store.Mapping
 			BeforeEach(func() {
@@ -486,7 +605,7 @@ var _ = Describe("Mappings", func() {
 				testMapping = genTestMapping()
 				testMap, err := testMapping.ToMap()
 				Expect(err).NotTo(HaveOccurred())
-				delete(testMap["bind_config"].(map[string]interface{}), "flavor")
+				delete(testMap["bind_config"].([]interface{})[0].(map[string]interface{}), "flavor")
 				j, err := json.Marshal(testMap)
 				Expect(err).NotTo(HaveOccurred())
 				assignBody(j)
@@ -513,7 +632,7 @@ var _ = Describe("Mappings", func() {
 			BeforeEach(func() {
 				testMapping = genTestMapping().WithConfig(bindparser.Config{
 					FlavorName: "gobbledegook",
-					Config:     testMapping.BindConfig.Config,
+					Config:     genTestMapping().BindConfig[0].Config,
 				})
 				assignBody(mappingToJSON(testMapping))
 			})
@@ -541,7 +660,7 @@ var _ = Describe("Mappings", func() {
 				testMapping = genTestMapping()
 				testMap, err := testMapping.ToMap()
 				Expect(err).NotTo(HaveOccurred())
-				delete(testMap["bind_config"].(map[string]interface{}), "config")
+				delete(testMap["bind_config"].([]interface{})[0].(map[string]interface{}), "config")
 				j, err := json.Marshal(testMap)
 				Expect(err).NotTo(HaveOccurred())
 				assignBody(j)
@@ -842,7 +961,7 @@ var _ = Describe("Mappings", func() {
 						testMapping = genTestMapping()
 						testMap, err := testMapping.ToMap()
 						Expect(err).NotTo(HaveOccurred())
-						delete(testMap["bind_config"].(map[string]interface{}), "flavor")
+						delete(testMap["bind_config"].([]interface{})[0].(map[string]interface{}), "flavor")
 						j, err := json.Marshal(testMap)
 						Expect(err).NotTo(HaveOccurred())
 						assignBody(j)
@@ -869,7 +988,7 @@ var _ = Describe("Mappings", func() {
 					BeforeEach(func() {
 						testMapping = genTestMapping().WithConfig(bindparser.Config{
 							FlavorName: "gobbledegook",
-							Config:     testMapping.BindConfig.Config,
+							Config:     genTestMapping().BindConfig[0].Config,
 						})
 						assignBody(mappingToJSON(testMapping))
 					})
@@ -0,0 +1,207 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/cloudfoundry-community/portcullis/api"
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registering mappings with CF", func() {
+	var cf *stubCF
+	var mapping store.Mapping
+	var testRequest *http.Request
+	var testResponse *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		Expect(Initialize(config.APIConfig{
+			Port: 5590,
+			Auth: config.AuthConfig{
+				Type: "none",
+			},
+		})).To(Succeed())
+		cf = newStubCF()
+		cf.connectBroker()
+		mapping = genTestMapping()
+	})
+
+	AfterEach(func() {
+		cf.close()
+		store.ClearMappings()
+	})
+
+	JustBeforeEach(func() {
+		testResponse = httptest.NewRecorder()
+		Router().ServeHTTP(testResponse, testRequest)
+	})
+
+	bodyWithRegistration := func(m store.Mapping, registration map[string]interface{}) *bytes.Reader {
+		mappingMap, err := m.ToMap()
+		Expect(err).NotTo(HaveOccurred())
+		mappingMap["cf_registration"] = registration
+		j, err := json.Marshal(mappingMap)
+		Expect(err).NotTo(HaveOccurred())
+		return bytes.NewReader(j)
+	}
+
+	Describe("Creating a mapping", func() {
+		BeforeEach(func() {
+			testRequest = httptest.NewRequest("POST", "/v1/mappings", bodyWithRegistration(mapping, map[string]interface{}{
+				"username":   "broker-user",
+				"password":   "broker-pass",
+				"space_guid": "some-space",
+			}))
+		})
+
+		It("should return a status code of 201", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusCreated))
+		})
+
+		It("should not warn about the registration field", func() {
+			meta := readJSONResponse(testResponse)["meta"].(map[string]interface{})
+			Expect(meta).NotTo(HaveKey("warning"))
+		})
+
+		It("should register a broker pointing at the mapping", func() {
+			registered := cf.brokerNamed(mapping.Name)
+			Expect(registered).NotTo(BeNil())
+			Expect(registered["broker_url"]).To(Equal("https://portcullis.example.com/" + mapping.Name))
+			Expect(registered["auth_username"]).To(Equal("broker-user"))
+			Expect(registered["auth_password"]).To(Equal("broker-pass"))
+			Expect(registered["space_guid"]).To(Equal("some-space"))
+		})
+
+		Context("When CF won't register the broker", func() {
+			BeforeEach(func() {
+				cf.Fail = true
+			})
+
+			It("should return a status code of 502", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusBadGateway))
+			})
+
+			It("should say what CF said", func() {
+				meta := readJSONResponse(testResponse)["meta"].(map[string]interface{})
+				Expect(meta["message"]).To(ContainSubstring("could not be reached"))
+			})
+
+			It("should not leave the mapping in the store", func() {
+				_, err := store.GetMapping(mapping.Name)
+				Expect(err).To(Equal(store.ErrNotFound))
+			})
+		})
+	})
+
+	Describe("Editing a mapping", func() {
+		var newName string
+		BeforeEach(func() {
+			Expect(store.AddMapping(mapping)).To(Succeed())
+			newName = genRandomString()
+		})
+
+		Context("That is already registered", func() {
+			BeforeEach(func() {
+				cf.Brokers["existing-guid"] = map[string]interface{}{
+					"name":          mapping.Name,
+					"broker_url":    "https://portcullis.example.com/" + mapping.Name,
+					"auth_username": "broker-user",
+				}
+				testRequest = httptest.NewRequest("PUT", "/v1/mappings/"+mapping.Name,
+					bodyWithRegistration(mapping.WithName(newName), map[string]interface{}{}))
+			})
+
+			It("should return a status code of 200", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusOK))
+			})
+
+			It("should rename and repoint the broker, keeping its credentials", func() {
+				Expect(cf.Brokers).To(HaveLen(1))
+				Expect(cf.Brokers["existing-guid"]["name"]).To(Equal(newName))
+				Expect(cf.Brokers["existing-guid"]["broker_url"]).To(Equal("https://portcullis.example.com/" + newName))
+				Expect(cf.Brokers["existing-guid"]["auth_username"]).To(Equal("broker-user"))
+			})
+
+			Context("When CF won't update the broker", func() {
+				BeforeEach(func() {
+					cf.Fail = true
+				})
+
+				It("should return a status code of 502", func() {
+					Expect(testResponse.Code).To(Equal(http.StatusBadGateway))
+				})
+
+				It("should put the mapping back the way it was", func() {
+					_, err := store.GetMapping(mapping.Name)
+					Expect(err).NotTo(HaveOccurred())
+					_, err = store.GetMapping(newName)
+					Expect(err).To(Equal(store.ErrNotFound))
+				})
+			})
+		})
+
+		Context("That isn't registered yet", func() {
+			BeforeEach(func() {
+				testRequest = httptest.NewRequest("PUT", "/v1/mappings/"+mapping.Name,
+					bodyWithRegistration(mapping, map[string]interface{}{
+						"username": "broker-user",
+						"password": "broker-pass",
+					}))
+			})
+
+			It("should register a broker", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusOK))
+				Expect(cf.brokerNamed(mapping.Name)).NotTo(BeNil())
+			})
+		})
+	})
+
+	Describe("Deleting a mapping", func() {
+		BeforeEach(func() {
+			Expect(store.AddMapping(mapping)).To(Succeed())
+			cf.Brokers["existing-guid"] = map[string]interface{}{"name": mapping.Name}
+		})
+
+		Context("With unregister", func() {
+			BeforeEach(func() {
+				testRequest = httptest.NewRequest("DELETE", "/v1/mappings/"+mapping.Name+"?unregister=true", nil)
+			})
+
+			It("should delete both the broker and the mapping", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusOK))
+				Expect(cf.Brokers).To(BeEmpty())
+				_, err := store.GetMapping(mapping.Name)
+				Expect(err).To(Equal(store.ErrNotFound))
+			})
+
+			Context("When CF won't delete the broker", func() {
+				BeforeEach(func() {
+					cf.Fail = true
+				})
+
+				It("should keep the mapping", func() {
+					Expect(testResponse.Code).To(Equal(http.StatusBadGateway))
+					_, err := store.GetMapping(mapping.Name)
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+		})
+
+		Context("Without unregister", func() {
+			BeforeEach(func() {
+				testRequest = httptest.NewRequest("DELETE", "/v1/mappings/"+mapping.Name, nil)
+			})
+
+			It("should leave the broker alone", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusOK))
+				Expect(cf.Brokers).To(HaveLen(1))
+			})
+		})
+	})
+})
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-community/portcullis/api"
+	"github.com/cloudfoundry-community/portcullis/config"
+)
+
+//apiTimeout is how long a command waits for the running server to answer.
+// Backfills and repairs talk to CF once per binding or group, so this is long.
+const apiTimeout = 10 * time.Minute
+
+//apiClient makes requests to the API of a running Portcullis server. Commands
+// that change security groups go through the server rather than doing the work
+// themselves, so that the server's lock keeps them from racing with binds.
+type apiClient struct {
+	baseURL  string
+	username string
+	password string
+}
+
+//apiError is returned by apiClient.do when the API answers with a status code
+// that isn't a success
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e apiError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("Portcullis API returned with status code %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("Portcullis API returned with status code %d", e.StatusCode)
+}
+
+//newAPIClient makes a client for the API described by the given config. The
+// server is assumed to be on this host unless apiURL is given.
+func newAPIClient(conf config.APIConfig, apiURL string) (*apiClient, error) {
+	//Initializing the API checks its auth config the same way the server does
+	err := api.Initialize(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiURL == "" {
+		apiURL = fmt.Sprintf("http://127.0.0.1:%d", api.Port())
+	}
+
+	ret := &apiClient{baseURL: strings.TrimSuffix(apiURL, "/") + "/v1"}
+	if basic, isBasic := api.SelectedAuth().(*api.BasicAuth); isBasic {
+		ret.username, ret.password = basic.Username, basic.Password
+	}
+	return ret, nil
+}
+
+//do sends a request to the given path of the API, with the given value as its
+// JSON body if it isn't nil, and unmarshals the contents of the response into
+// contents. The error has the server's message if the request wasn't a success.
+func (c *apiClient) do(method, path string, value interface{}, contents interface{}) error {
+	var body io.Reader
+	if value != nil {
+		j, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(j)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := (&http.Client{Timeout: apiTimeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("Could not reach the Portcullis API. Is the server running? %s", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var handlerResp struct {
+		Meta     api.Metadata    `json:"meta"`
+		Contents json.RawMessage `json:"contents"`
+	}
+	err = json.Unmarshal(respBody, &handlerResp)
+	if err != nil {
+		return fmt.Errorf("Could not unmarshal response from the Portcullis API: %s", err)
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return apiError{StatusCode: resp.StatusCode, Message: handlerResp.Meta.Message}
+	}
+
+	if contents == nil || len(handlerResp.Contents) == 0 {
+		return nil
+	}
+	err = json.Unmarshal(handlerResp.Contents, contents)
+	if err != nil {
+		return fmt.Errorf("Could not unmarshal contents from the Portcullis API: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+//ErrNotRegistered is returned when a mapping has no service broker in CF
+var ErrNotRegistered = fmt.Errorf("No service broker for the mapping is registered with Cloud Foundry")
+
+//ErrNoSuchPlan is returned when no plan in a broker's catalog matches the
+// service and plan names that were asked for
+var ErrNoSuchPlan = fmt.Errorf("No plan in the service broker's catalog matches")
+
+//PlanAccess says which orgs can see a service plan of a mapping's service
+// broker in CF
+type PlanAccess struct {
+	ServiceName string `json:"service"`
+	PlanName    string `json:"plan"`
+	PlanGUID    string `json:"plan_guid"`
+	//Public plans can be seen by every org, whatever Organizations says
+	Public bool `json:"public"`
+	//Organizations are the GUIDs of the orgs that have been given access
+	Organizations []string `json:"organizations"`
+
+	//visibilities are the GUIDs of the visibilities giving access, by org GUID
+	visibilities map[string]string
+}
+
+//cfListResponse is the envelope around a page of any CF v2 list endpoint
+type cfListResponse struct {
+	NextURL   string `json:"next_url"`
+	Resources []struct {
+		Meta struct {
+			Guid string `json:"guid"`
+		} `json:"metadata"`
+		Entity json.RawMessage `json:"entity"`
+	} `json:"resources"`
+}
+
+//ListPlanAccess returns which orgs can see each plan of the service broker that
+// is registered for the mapping with the given name
+func ListPlanAccess(mappingName string) ([]PlanAccess, error) {
+	if client == nil {
+		return nil, fmt.Errorf("Not connected to Cloud Foundry")
+	}
+
+	registered, err := serviceBrokerByName(mappingName)
+	if err != nil {
+		return nil, err
+	}
+
+	if registered == nil {
+		return nil, ErrNotRegistered
+	}
+
+	query := url.QueryEscape("service_broker_guid:" + registered.Meta.Guid)
+	serviceNames := map[string]string{}
+	err = listCFResources("/v2/services?q="+query, func(guid string, entity []byte) error {
+		var service struct {
+			Label string `json:"label"`
+		}
+		err := json.Unmarshal(entity, &service)
+		serviceNames[guid] = service.Label
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []PlanAccess{}
+	err = listCFResources("/v2/service_plans?q="+query, func(guid string, entity []byte) error {
+		var plan struct {
+			Name        string `json:"name"`
+			Public      bool   `json:"public"`
+			ServiceGUID string `json:"service_guid"`
+		}
+		err := json.Unmarshal(entity, &plan)
+		ret = append(ret, PlanAccess{
+			ServiceName:   serviceNames[plan.ServiceGUID],
+			PlanName:      plan.Name,
+			PlanGUID:      guid,
+			Public:        plan.Public,
+			Organizations: []string{},
+			visibilities:  map[string]string{},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ret {
+		plan := &ret[i]
+		err = listCFResources("/v2/service_plan_visibilities?q="+url.QueryEscape("service_plan_guid:"+plan.PlanGUID),
+			func(guid string, entity []byte) error {
+				var visibility struct {
+					OrganizationGUID string `json:"organization_guid"`
+				}
+				err := json.Unmarshal(entity, &visibility)
+				plan.Organizations = append(plan.Organizations, visibility.OrganizationGUID)
+				plan.visibilities[visibility.OrganizationGUID] = guid
+				return err
+			})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(plan.Organizations)
+	}
+
+	sort.Sort(planAccessList(ret))
+	return ret, nil
+}
+
+//planAccessList sorts PlanAccess by service name, and then plan name
+type planAccessList []PlanAccess
+
+func (p planAccessList) Len() int      { return len(p) }
+func (p planAccessList) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p planAccessList) Less(i, j int) bool {
+	if p[i].ServiceName != p[j].ServiceName {
+		return p[i].ServiceName < p[j].ServiceName
+	}
+	return p[i].PlanName < p[j].PlanName
+}
+
+//GrantPlanAccess lets the org with the given GUID see the plans of the mapping's
+// service broker. An empty service or plan name matches every service or plan.
+// Returns the plans that the org couldn't see before. Public plans are left
+// alone, since every org can already see them.
+func GrantPlanAccess(mappingName, orgGUID, serviceName, planName string) ([]PlanAccess, error) {
+	plans, err := matchingPlans(mappingName, serviceName, planName)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []PlanAccess{}
+	for _, plan := range plans {
+		if _, visible := plan.visibilities[orgGUID]; visible || plan.Public {
+			continue
+		}
+
+		_, err = cfJSONRequest("POST", "/v2/service_plan_visibilities", map[string]string{
+			"service_plan_guid": plan.PlanGUID,
+			"organization_guid": orgGUID,
+		}, http.StatusCreated)
+		if err != nil {
+			return changed, fmt.Errorf("Could not grant access to plan %s of service %s: %s", plan.PlanName, plan.ServiceName, err)
+		}
+		changed = append(changed, plan)
+	}
+	return changed, nil
+}
+
+//RevokePlanAccess takes away the access that the org with the given GUID was
+// given to the plans of the mapping's service broker. An empty service or plan
+// name matches every service or plan. Returns the plans that the org could see
+// before.
+func RevokePlanAccess(mappingName, orgGUID, serviceName, planName string) ([]PlanAccess, error) {
+	plans, err := matchingPlans(mappingName, serviceName, planName)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []PlanAccess{}
+	for _, plan := range plans {
+		visibilityGUID, visible := plan.visibilities[orgGUID]
+		if !visible {
+			continue
+		}
+
+		_, err = cfJSONRequest("DELETE", "/v2/service_plan_visibilities/"+visibilityGUID, nil, http.StatusNoContent)
+		if err != nil {
+			return changed, fmt.Errorf("Could not revoke access to plan %s of service %s: %s", plan.PlanName, plan.ServiceName, err)
+		}
+		changed = append(changed, plan)
+	}
+	return changed, nil
+}
+
+//matchingPlans gets the plans of the mapping's service broker with the given
+// service and plan names, where empty names match everything
+func matchingPlans(mappingName, serviceName, planName string) ([]PlanAccess, error) {
+	plans, err := ListPlanAccess(mappingName)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []PlanAccess{}
+	for _, plan := range plans {
+		if (serviceName == "" || plan.ServiceName == serviceName) && (planName == "" || plan.PlanName == planName) {
+			ret = append(ret, plan)
+		}
+	}
+
+	if len(ret) == 0 {
+		return nil, ErrNoSuchPlan
+	}
+	return ret, nil
+}
+
+//listCFResources calls each with the GUID and entity of every resource from the
+// CF v2 list endpoint at the given path, following the pages of results
+func listCFResources(path string, each func(guid string, entity []byte) error) error {
+	for path != "" {
+		body, err := cfJSONRequest("GET", path, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+
+		var listResp cfListResponse
+		err = json.Unmarshal(body, &listResp)
+		if err != nil {
+			return fmt.Errorf("Could not unmarshal %s from CF: %s", path, err)
+		}
+
+		for _, resource := range listResp.Resources {
+			if err = each(resource.Meta.Guid, resource.Entity); err != nil {
+				return fmt.Errorf("Could not unmarshal %s from CF: %s", path, err)
+			}
+		}
+		path = listResp.NextURL
+	}
+	return nil
+}
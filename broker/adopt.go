@@ -0,0 +1,215 @@
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
+	"github.com/cloudfoundry-community/portcullis/store"
+	"github.com/starkandwayne/goutils/log"
+)
+
+//ErrAlreadyAdopted is returned when adopting a CF service broker that already
+// points at Portcullis
+var ErrAlreadyAdopted = fmt.Errorf("The service broker already points at Portcullis")
+
+//ErrNotAdopted is returned when rolling back a CF service broker that doesn't
+// point at Portcullis
+var ErrNotAdopted = fmt.Errorf("The service broker does not point at Portcullis")
+
+//catalogTimeout is how long the backend broker has to return its catalog when
+// it is checked during an adoption
+const catalogTimeout = 30 * time.Second
+
+//Adoption describes a CF service broker that was moved behind Portcullis, or
+// moved back out from behind it
+type Adoption struct {
+	BrokerName string `json:"broker_name"`
+	//OriginalURL is the URL that CF reached the broker at before it was adopted.
+	// The mapping created for the broker points here.
+	OriginalURL string `json:"original_url"`
+	//BrokerURL is the URL that CF reaches the broker at now
+	BrokerURL string `json:"broker_url"`
+	//Services are the names of the services in the catalog that was fetched
+	// through Portcullis before CF was switched over
+	Services []string `json:"services,omitempty"`
+}
+
+//AdoptBroker moves the CF service broker with the given name behind Portcullis.
+// A mapping with the same name is created that points at the broker's current
+// URL, and the catalog is fetched through Portcullis with the given credentials
+// to check that the mapping works. Only then is the broker in CF updated to
+// point at Portcullis. If the adoption fails, a mapping that it created is
+// removed again. An existing mapping with the same name is used if it already
+// points at the broker, as it will after a rollback, and then the bind config
+// isn't needed.
+func AdoptBroker(name string, creds Registration, bindConfig bindparser.ConfigList) (Adoption, error) {
+	if err := checkCanRegister(); err != nil {
+		return Adoption{}, err
+	}
+
+	existing, err := serviceBrokerByName(name)
+	if err != nil {
+		return Adoption{}, err
+	}
+
+	if existing == nil {
+		return Adoption{}, ErrNotRegistered
+	}
+
+	ret := Adoption{
+		BrokerName:  name,
+		OriginalURL: existing.Entity.BrokerURL,
+		BrokerURL:   BrokerURL(name),
+	}
+	if ret.OriginalURL == ret.BrokerURL {
+		return Adoption{}, ErrAlreadyAdopted
+	}
+
+	created, err := adoptionMapping(store.Mapping{
+		Name:       name,
+		Location:   ret.OriginalURL,
+		BindConfig: bindConfig,
+	})
+	if err != nil {
+		return Adoption{}, err
+	}
+
+	//Undoes the mapping if it's ours, so that the adoption can be tried again
+	fail := func(err error) (Adoption, error) {
+		if created {
+			if delErr := store.DeleteMapping(name); delErr != nil {
+				log.Errorf("Could not remove mapping `%s` after failed adoption: %s", name, delErr)
+			}
+		}
+		return Adoption{}, err
+	}
+
+	ret.Services, err = verifyCatalog(name, creds)
+	if err != nil {
+		return fail(fmt.Errorf("Could not fetch the catalog through Portcullis: %s", err))
+	}
+
+	log.Infof("Pointing service broker %s at Portcullis instead of %s", name, ret.OriginalURL)
+	_, err = cfJSONRequest("PUT", "/v2/service_brokers/"+existing.Meta.Guid, serviceBroker{
+		Name:         name,
+		BrokerURL:    ret.BrokerURL,
+		AuthUsername: creds.Username,
+		AuthPassword: creds.Password,
+	}, http.StatusOK)
+	if err != nil {
+		return fail(err)
+	}
+	return ret, nil
+}
+
+//RollbackAdoption points the adopted CF service broker with the given name back
+// at the location of its mapping, which is its URL from before the adoption.
+// The mapping is kept, so that the broker can be adopted again.
+func RollbackAdoption(name string) (Adoption, error) {
+	if err := checkCanRegister(); err != nil {
+		return Adoption{}, err
+	}
+
+	existing, err := serviceBrokerByName(name)
+	if err != nil {
+		return Adoption{}, err
+	}
+
+	if existing == nil {
+		return Adoption{}, ErrNotRegistered
+	}
+
+	if existing.Entity.BrokerURL != BrokerURL(name) {
+		return Adoption{}, ErrNotAdopted
+	}
+
+	mapping, err := store.GetMapping(name)
+	if err != nil {
+		return Adoption{}, fmt.Errorf("Could not get the original URL from mapping `%s`: %s", name, err)
+	}
+
+	log.Infof("Pointing service broker %s back at %s", name, mapping.Location)
+	_, err = cfJSONRequest("PUT", "/v2/service_brokers/"+existing.Meta.Guid, serviceBroker{
+		Name:      name,
+		BrokerURL: mapping.Location,
+	}, http.StatusOK)
+	if err != nil {
+		return Adoption{}, err
+	}
+
+	return Adoption{
+		BrokerName:  name,
+		OriginalURL: mapping.Location,
+		BrokerURL:   mapping.Location,
+	}, nil
+}
+
+//adoptionMapping adds the given mapping to the store, returning true if it
+// did. A mapping that is already there is fine if it has the same location.
+func adoptionMapping(mapping store.Mapping) (created bool, err error) {
+	existing, err := store.GetMapping(mapping.Name)
+	if err == nil {
+		if existing.Location != mapping.Location {
+			return false, fmt.Errorf("Mapping `%s` already exists, and points at %s instead of %s", mapping.Name, existing.Location, mapping.Location)
+		}
+		return false, nil
+	}
+
+	if err != store.ErrNotFound {
+		return false, err
+	}
+
+	if len(mapping.BindConfig) == 0 {
+		return false, store.NewErrInvalid(fmt.Sprintf("There is no mapping named `%s` yet, so a bind config must be given for it", mapping.Name))
+	}
+
+	err = store.AddMapping(mapping)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+//verifyCatalog fetches the catalog of the mapping's backend broker through the
+// public URL of Portcullis, and returns the names of its services
+func verifyCatalog(name string, creds Registration) ([]string, error) {
+	req, err := http.NewRequest("GET", BrokerURL(name)+"/v2/catalog", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(creds.Username, creds.Password)
+	req.Header.Set("X-Broker-API-Version", "2.13")
+
+	resp, err := (&http.Client{Timeout: catalogTimeout}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Catalog request returned with status code %d", resp.StatusCode)
+	}
+
+	var catalog struct {
+		Services []struct {
+			Name string `json:"name"`
+		} `json:"services"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&catalog)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal catalog: %s", err)
+	}
+
+	if len(catalog.Services) == 0 {
+		return nil, fmt.Errorf("Catalog has no services")
+	}
+
+	ret := make([]string, 0, len(catalog.Services))
+	for _, service := range catalog.Services {
+		ret = append(ret, service.Name)
+	}
+	return ret, nil
+}
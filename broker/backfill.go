@@ -0,0 +1,255 @@
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
+	"github.com/cloudfoundry-community/portcullis/store"
+	"github.com/starkandwayne/goutils/log"
+)
+
+//These are the outcomes that a Backfill can have
+const (
+	//BackfillRecorded means that the binding is already in the store
+	BackfillRecorded = "recorded"
+	//BackfillNoRules means that the flavors made no rules for the binding
+	BackfillNoRules = "no_rules"
+	//BackfillPlanned means that the binding would get a security group, but
+	// this is a dry run
+	BackfillPlanned = "planned"
+	//BackfillCreated means that the binding's rules were put into its security
+	// group, and the binding was recorded
+	BackfillCreated = "created"
+	//BackfillFailed means that something went wrong, which Error describes
+	BackfillFailed = "failed"
+)
+
+//Backfill describes what a backfill did, or would do, for a service binding in
+// CF that was made before its broker was behind Portcullis
+type Backfill struct {
+	BindingGUID         string                    `json:"binding_guid"`
+	ServiceInstanceGUID string                    `json:"service_instance_guid"`
+	AppGUID             string                    `json:"app_guid"`
+	SpaceGUID           string                    `json:"space_guid"`
+	SecGroupName        string                    `json:"secgroup_name,omitempty"`
+	Rules               []bindparser.SecGroupRule `json:"rules,omitempty"`
+	//Status is one of the Backfill constants
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+//cfServiceBinding is the entity of a CF service binding resource
+type cfServiceBinding struct {
+	AppGUID     string                 `json:"app_guid"`
+	Credentials map[string]interface{} `json:"credentials"`
+}
+
+//BackfillBindings opens egress for the CF service bindings to instances of the
+// services of the mapping with the given name that the store has no record of,
+// such as bindings made before the broker was adopted. The credentials of each
+// binding are read from CF and run through the mapping's flavors, and the
+// resulting rules are put into the binding's security group, just as for a new
+// bind. If dryRun is true, the rules are worked out but nothing is changed.
+// A binding that fails doesn't stop the rest. Bindings whose credentials CF
+// doesn't hold itself, such as those kept in CredHub, fail.
+func BackfillBindings(mappingName string, dryRun bool) ([]Backfill, error) {
+	if client == nil {
+		return nil, fmt.Errorf("Not connected to Cloud Foundry")
+	}
+
+	mapping, err := store.GetMapping(mappingName)
+	if err != nil {
+		return nil, err
+	}
+
+	flavors, err := mapping.BindConfig.CreateFlavors()
+	if err != nil {
+		return nil, fmt.Errorf("Bind config of mapping `%s` is invalid: %s", mappingName, err)
+	}
+
+	registered, err := serviceBrokerByName(mappingName)
+	if err != nil {
+		return nil, err
+	}
+
+	if registered == nil {
+		return nil, ErrNotRegistered
+	}
+
+	var planGUIDs []string
+	err = listCFResources("/v2/service_plans?q="+url.QueryEscape("service_broker_guid:"+registered.Meta.Guid),
+		func(guid string, entity []byte) error {
+			planGUIDs = append(planGUIDs, guid)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var instanceGUIDs []string
+	for _, planGUID := range planGUIDs {
+		err = listCFResources("/v2/service_instances?q="+url.QueryEscape("service_plan_guid:"+planGUID),
+			func(guid string, entity []byte) error {
+				instanceGUIDs = append(instanceGUIDs, guid)
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ret := []Backfill{}
+	//The org of each space, by space GUID, since instances share spaces
+	orgs := map[string]string{}
+	for _, instanceGUID := range instanceGUIDs {
+		err = listCFResources("/v2/service_bindings?q="+url.QueryEscape("service_instance_guid:"+instanceGUID),
+			func(guid string, entity []byte) error {
+				var binding cfServiceBinding
+				err := json.Unmarshal(entity, &binding)
+				if err != nil {
+					return err
+				}
+
+				transport := &BindTransport{
+					Flavors:          flavors,
+					MappingName:      mappingName,
+					InstanceGUID:     instanceGUID,
+					BindingGUID:      guid,
+					ResolveHostnames: mapping.ResolveHostnames,
+				}
+				ret = append(ret, backfillBinding(transport, binding, orgs, dryRun))
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+//backfillBinding opens egress for one binding of a backfill, unless it is a dry
+// run. orgs caches the org GUIDs of spaces between bindings.
+func backfillBinding(transport *BindTransport, binding cfServiceBinding, orgs map[string]string, dryRun bool) Backfill {
+	ret := Backfill{
+		BindingGUID:         transport.BindingGUID,
+		ServiceInstanceGUID: transport.InstanceGUID,
+		AppGUID:             binding.AppGUID,
+	}
+	fail := func(err error) Backfill {
+		log.Errorf("Could not backfill binding %s: %s", ret.BindingGUID, err)
+		ret.Status = BackfillFailed
+		ret.Error = err.Error()
+		return ret
+	}
+
+	_, err := store.GetBindingInfo(ret.BindingGUID)
+	if err == nil {
+		ret.Status = BackfillRecorded
+		return ret
+	}
+	if err != store.ErrNotFound {
+		return fail(err)
+	}
+
+	if binding.Credentials == nil {
+		return fail(fmt.Errorf("CF has no credentials for the binding"))
+	}
+
+	rules, sourceRules, err := transport.egressRules(binding.Credentials)
+	if err != nil {
+		return fail(err)
+	}
+
+	if len(rules) == 0 {
+		ret.Status = BackfillNoRules
+		return ret
+	}
+
+	//The group goes in the space of the app, just as for a new bind, which isn't
+	// the instance's space when the instance is shared into other spaces
+	spaceGUID, err := appSpaceGUID(ret.AppGUID)
+	if err != nil {
+		return fail(err)
+	}
+	ret.SpaceGUID = spaceGUID
+
+	ret.SecGroupName = secGroupName(ret.ServiceInstanceGUID, spaceGUID)
+	ret.Rules = rules
+	if dryRun {
+		ret.Status = BackfillPlanned
+		return ret
+	}
+
+	orgGUID, cached := orgs[spaceGUID]
+	if !cached {
+		orgGUID, err = spaceOrgGUID(spaceGUID)
+		if err != nil {
+			return fail(err)
+		}
+		orgs[spaceGUID] = orgGUID
+	}
+
+	log.Infof("Backfilling security group %s for binding %s", ret.SecGroupName, ret.BindingGUID)
+	err = addBindingSecGroup(store.BindingInfo{
+		BindingGUID:         ret.BindingGUID,
+		ServiceInstanceGUID: ret.ServiceInstanceGUID,
+		AppGUID:             ret.AppGUID,
+		SpaceGUID:           spaceGUID,
+		OrganizationGUID:    orgGUID,
+		Rules:               rules,
+		SourceRules:         sourceRules,
+	}, transport.MappingName)
+	if err != nil {
+		return fail(err)
+	}
+
+	ret.Status = BackfillCreated
+	return ret
+}
+
+//appSpaceGUID asks CF for the GUID of the space that the app with the given GUID
+// is in
+func appSpaceGUID(appGUID string) (string, error) {
+	if appGUID == "" {
+		return "", fmt.Errorf("The binding is not for an app")
+	}
+
+	body, err := cfJSONRequest("GET", "/v2/apps/"+appGUID, nil, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+
+	var app struct {
+		Entity struct {
+			SpaceGUID string `json:"space_guid"`
+		} `json:"entity"`
+	}
+	err = json.Unmarshal(body, &app)
+	if err != nil {
+		return "", fmt.Errorf("Could not unmarshal app from CF: %s", err)
+	}
+	return app.Entity.SpaceGUID, nil
+}
+
+//spaceOrgGUID asks CF for the GUID of the org that the space with the given
+// GUID is in
+func spaceOrgGUID(spaceGUID string) (string, error) {
+	body, err := cfJSONRequest("GET", "/v2/spaces/"+spaceGUID, nil, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+
+	var space struct {
+		Entity struct {
+			OrganizationGUID string `json:"organization_guid"`
+		} `json:"entity"`
+	}
+	err = json.Unmarshal(body, &space)
+	if err != nil {
+		return "", fmt.Errorf("Could not unmarshal space from CF: %s", err)
+	}
+	return space.Entity.OrganizationGUID, nil
+}
@@ -0,0 +1,289 @@
+package broker_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Binding", func() {
+	var cf *stubCF
+	var backend *stubBackend
+	var mappingName, serviceKeyPolicy string
+	var instanceGUID, bindingGUID, appGUID string
+	var body map[string]interface{}
+	var testResponse *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		cf = newStubCF()
+		backend = newStubBackend()
+		mappingName = addTestMapping(backend.server.URL)
+		serviceKeyPolicy = config.ServiceKeyPolicyPassthrough
+
+		instanceGUID, bindingGUID = genRandomString(), genRandomString()
+		appGUID = cf.addApp("app-space", "app-org")
+		body = bindBody(appGUID, "some-space", "some-org")
+	})
+
+	AfterEach(func() {
+		cf.close()
+		backend.close()
+		clearStore()
+	})
+
+	JustBeforeEach(func() {
+		cf.connectBroker(serviceKeyPolicy)
+		testResponse = brokerRequest("PUT", mappingName, bindingPath(instanceGUID, bindingGUID), body)
+	})
+
+	Context("When the backend broker makes the binding", func() {
+		It("should pass the backend broker's response through", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusCreated))
+			contents := readJSONResponse(testResponse)
+			Expect(contents["credentials"]).To(HaveKeyWithValue("host", "10.0.0.5"))
+		})
+
+		It("should make a security group for the binding's space", func() {
+			secGroups := cf.secGroups()
+			Expect(secGroups).To(HaveLen(1))
+			secGroup := secGroups["portcullis-"+instanceGUID+"-some-space"]
+			Expect(secGroup).NotTo(BeNil())
+			Expect(secGroup["space_guids"]).To(ConsistOf("some-space"))
+			Expect(destinations(secGroup)).To(ConsistOf("10.0.0.5"))
+		})
+
+		It("should record the binding and its security group", func() {
+			binding, err := store.GetBindingInfo(bindingGUID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(binding.ServiceInstanceGUID).To(Equal(instanceGUID))
+			Expect(binding.AppGUID).To(Equal(appGUID))
+			Expect(binding.OrganizationGUID).To(Equal("some-org"))
+			Expect(binding.SecGroupName).To(Equal("portcullis-" + instanceGUID + "-some-space"))
+
+			secGroup, err := store.GetSecGroupInfoByName(binding.SecGroupName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secGroup.MappingName).To(Equal(mappingName))
+			Expect(secGroup.Rules).To(HaveLen(1))
+		})
+
+		Context("When the request has no context", func() {
+			BeforeEach(func() {
+				body = bindBody(appGUID, "", "")
+			})
+
+			It("should put the security group in the app's space", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusCreated))
+				Expect(cf.secGroups()).To(HaveKey("portcullis-" + instanceGUID + "-app-space"))
+
+				binding, err := store.GetBindingInfo(bindingGUID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(binding.SpaceGUID).To(Equal("app-space"))
+				Expect(binding.OrganizationGUID).To(Equal("app-org"))
+			})
+		})
+
+		Context("When another app in the space is bound to the instance", func() {
+			var otherBindingGUID string
+
+			BeforeEach(func() {
+				otherBindingGUID = genRandomString()
+				cf.connectBroker(serviceKeyPolicy)
+				backend.credentials = map[string]interface{}{"host": "10.0.0.6", "port": 6379}
+				response := brokerRequest("PUT", mappingName, bindingPath(instanceGUID, otherBindingGUID), body)
+				Expect(response.Code).To(Equal(http.StatusCreated))
+				backend.credentials = map[string]interface{}{"host": "10.0.0.5", "port": 6379}
+			})
+
+			It("should share the security group, with the rules of both", func() {
+				secGroups := cf.secGroups()
+				Expect(secGroups).To(HaveLen(1))
+				Expect(destinations(secGroups["portcullis-"+instanceGUID+"-some-space"])).To(ConsistOf("10.0.0.5", "10.0.0.6"))
+
+				count, err := store.SecGroupRefCount("portcullis-" + instanceGUID + "-some-space")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(count).To(Equal(2))
+			})
+		})
+
+		Context("When an app in another space is bound to the instance", func() {
+			BeforeEach(func() {
+				cf.connectBroker(serviceKeyPolicy)
+				response := brokerRequest("PUT", mappingName, bindingPath(instanceGUID, genRandomString()),
+					bindBody(cf.addApp("other-space", "some-org"), "other-space", "some-org"))
+				Expect(response.Code).To(Equal(http.StatusCreated))
+			})
+
+			It("should give each space its own security group", func() {
+				secGroups := cf.secGroups()
+				Expect(secGroups).To(HaveLen(2))
+				Expect(secGroups["portcullis-"+instanceGUID+"-some-space"]["space_guids"]).To(ConsistOf("some-space"))
+				Expect(secGroups["portcullis-"+instanceGUID+"-other-space"]["space_guids"]).To(ConsistOf("other-space"))
+			})
+		})
+	})
+
+	Context("When CF retries a bind that the backend broker already made", func() {
+		BeforeEach(func() {
+			cf.connectBroker(serviceKeyPolicy)
+			Expect(brokerRequest("PUT", mappingName, bindingPath(instanceGUID, bindingGUID), body).Code).To(Equal(http.StatusCreated))
+			backend.bindStatus = http.StatusOK
+		})
+
+		It("should pass the 200 through", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should leave the one security group and binding in place", func() {
+			Expect(cf.secGroups()).To(HaveLen(1))
+			bindings, err := store.ListBindingInfo()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bindings).To(HaveLen(1))
+		})
+
+		Context("When the security group has gone missing from CF", func() {
+			BeforeEach(func() {
+				cf.clearSecGroups()
+			})
+
+			It("should make it again", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusOK))
+				Expect(cf.secGroups()).To(HaveKey("portcullis-" + instanceGUID + "-some-space"))
+			})
+		})
+	})
+
+	Context("When the backend broker doesn't make the binding", func() {
+		BeforeEach(func() {
+			backend.bindStatus = http.StatusConflict
+		})
+
+		It("should pass the backend broker's response through, and make no security group", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusConflict))
+			Expect(cf.secGroups()).To(BeEmpty())
+			Expect(store.ListBindingInfo()).To(BeEmpty())
+		})
+	})
+
+	//assertUndone checks that a bind that couldn't be finished was undone with
+	// the backend broker and recorded as a failure
+	assertUndone := func() {
+		It("should undo the binding with the backend broker", func() {
+			Expect(backend.received("DELETE " + bindingPath(instanceGUID, bindingGUID))).To(ConsistOf(
+				"DELETE " + bindingPath(instanceGUID, bindingGUID) + "?plan_id=plan-id&service_id=service-id"))
+		})
+
+		It("should record the failure", func() {
+			failures, err := store.ListBindFailures()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(failures).To(HaveLen(1))
+			Expect(failures[0].BindingGUID).To(Equal(bindingGUID))
+			Expect(failures[0].MappingName).To(Equal(mappingName))
+			Expect(failures[0].Error).NotTo(BeEmpty())
+			Expect(failures[0].Unbound).To(BeTrue())
+		})
+
+		It("should leave nothing behind", func() {
+			Expect(cf.secGroups()).To(BeEmpty())
+			Expect(store.ListBindingInfo()).To(BeEmpty())
+		})
+	}
+
+	Context("When CF can't make the security group", func() {
+		BeforeEach(func() {
+			cf.Fail = true
+		})
+
+		It("should fail the bind with an OSBAPI error", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusInternalServerError))
+			Expect(readJSONResponse(testResponse)["description"]).To(ContainSubstring("Portcullis could not open egress"))
+		})
+
+		assertUndone()
+	})
+
+	Context("When the credentials don't make sense to the bind config", func() {
+		BeforeEach(func() {
+			backend.credentials = map[string]interface{}{"uri": "redis://10.0.0.5:6379"}
+		})
+
+		It("should fail the bind", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusInternalServerError))
+		})
+
+		assertUndone()
+
+		Context("When the backend broker can't undo the binding", func() {
+			BeforeEach(func() {
+				backend.unbindStatus = http.StatusInternalServerError
+			})
+
+			It("should record that the binding is still there", func() {
+				failures, err := store.ListBindFailures()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(failures).To(HaveLen(1))
+				Expect(failures[0].Unbound).To(BeFalse())
+				Expect(failures[0].UnbindError).To(ContainSubstring("500"))
+			})
+		})
+	})
+
+	Describe("Bindings that aren't for an app", func() {
+		BeforeEach(func() {
+			body = bindBody("", "some-space", "some-org")
+		})
+
+		Context("With the passthrough policy", func() {
+			It("should pass the bind through without a security group", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusCreated))
+				Expect(cf.secGroups()).To(BeEmpty())
+				Expect(store.ListBindingInfo()).To(BeEmpty())
+			})
+		})
+
+		Context("With the space policy", func() {
+			BeforeEach(func() {
+				serviceKeyPolicy = config.ServiceKeyPolicySpace
+			})
+
+			It("should open egress for the space in the request's context", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusCreated))
+				Expect(cf.secGroups()).To(HaveKey("portcullis-" + instanceGUID + "-some-space"))
+
+				binding, err := store.GetBindingInfo(bindingGUID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(binding.AppGUID).To(BeEmpty())
+			})
+
+			Context("When the request has no context", func() {
+				BeforeEach(func() {
+					body = bindBody("", "", "")
+				})
+
+				It("should fail the bind with RequiresApp", func() {
+					Expect(testResponse.Code).To(Equal(http.StatusUnprocessableEntity))
+					Expect(readJSONResponse(testResponse)["error"]).To(Equal("RequiresApp"))
+				})
+
+				assertUndone()
+			})
+		})
+
+		Context("With the reject policy", func() {
+			BeforeEach(func() {
+				serviceKeyPolicy = config.ServiceKeyPolicyReject
+			})
+
+			It("should fail the bind with RequiresApp", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusUnprocessableEntity))
+				Expect(readJSONResponse(testResponse)["error"]).To(Equal("RequiresApp"))
+			})
+
+			assertUndone()
+		})
+	})
+})
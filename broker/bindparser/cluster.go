@@ -0,0 +1,199 @@
+package bindparser
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+//Cluster is an implementation of bindparser.MultiRuleFlavor for services which
+// hand back more than one node to connect to, such as Cassandra, Kafka, or
+// RabbitMQ clusters. It opens egress to every node in the list that the Hosts
+// expression points to.
+type Cluster struct {
+	//Hosts is an expression pointing to the list of nodes in the bind response.
+	// The list may be an array, or a string of comma-separated nodes. Array
+	// elements may be strings or hashes. Node strings are either an address or
+	// an address:port pair.
+	Hosts string `json:"hosts" yaml:"hosts"`
+	//Host is an expression, relative to each node, that points to the address of
+	// that node. Required if the nodes are hashes. e.g. `$.ip`
+	Host string `json:"host" yaml:"host"`
+	//PortPath is an expression, relative to each node, that points to the port
+	// of that node. e.g. `$.port`
+	PortPath string `json:"port_path" yaml:"port_path"`
+	//Port is the port to open for nodes which don't specify one of their own
+	Port int `json:"port" yaml:"port"`
+}
+
+//NewCluster creates a new Cluster flavor object
+func NewCluster() Flavor {
+	return &Cluster{}
+}
+
+//Verify checks that the expressions compile, and that the port is a real port
+// number if it was given
+func (c Cluster) Verify() error {
+	if c.Hosts == "" {
+		return fmt.Errorf("`hosts` must be given for the cluster flavor")
+	}
+	for _, expr := range []string{c.Hosts, c.Host, c.PortPath} {
+		if expr == "" {
+			continue
+		}
+		if _, err := compileJSONPath(expr); err != nil {
+			return err
+		}
+	}
+	if c.Port != 0 && !IsPort(c.Port) {
+		return fmt.Errorf("`port` is not a valid port number")
+	}
+	return nil
+}
+
+//Rule returns the rule for only the first node in the cluster. Use Rules to
+// get the rules for all of them.
+func (c Cluster) Rule(creds map[string]interface{}) (rule SecGroupRule, err error) {
+	rules, err := c.Rules(creds)
+	if err != nil {
+		return rule, err
+	}
+	return rules[0], nil
+}
+
+//Rules returns a cf security group rule for each node in the cluster. Nodes with
+// the same address and port only get one rule.
+func (c Cluster) Rules(creds map[string]interface{}) (rules []SecGroupRule, err error) {
+	if creds == nil {
+		return nil, fmt.Errorf("No broker credentials were given")
+	}
+
+	nodes, err := c.getNodes(map[string]interface{}{"credentials": creds})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for i, node := range nodes {
+		dest, port, err := c.parseNode(node)
+		if err != nil {
+			return nil, fmt.Errorf("Node %d of `%s`: %s", i, c.Hosts, err)
+		}
+		rule := SecGroupRule{
+			Destination: dest,
+			Ports:       strconv.Itoa(port),
+		}
+		if key := rule.Destination + ":" + rule.Ports; !seen[key] {
+			seen[key] = true
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (c Cluster) getNodes(doc interface{}) ([]interface{}, error) {
+	path, err := compileJSONPath(c.Hosts)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := path.lookup(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []interface{}
+	switch h := hosts.(type) {
+	case []interface{}:
+		nodes = h
+	case string:
+		for _, node := range strings.Split(h, ",") {
+			if node = strings.TrimSpace(node); node != "" {
+				nodes = append(nodes, node)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("`%s` in broker credentials JSON was not a list of nodes", c.Hosts)
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("`%s` in broker credentials JSON has no nodes", c.Hosts)
+	}
+	return nodes, nil
+}
+
+//parseNode gets the address and port for a single node out of the node list
+func (c Cluster) parseNode(node interface{}) (dest string, port int, err error) {
+	switch n := node.(type) {
+	case string:
+		dest = n
+		if host, portStr, splitErr := net.SplitHostPort(n); splitErr == nil {
+			dest = host
+			port, err = strconv.Atoi(portStr)
+			if err != nil {
+				return "", 0, fmt.Errorf("port `%s` is not a number", portStr)
+			}
+		}
+
+	case map[string]interface{}:
+		if c.Host == "" {
+			return "", 0, fmt.Errorf("node is a hash, but no `host` expression was configured")
+		}
+		var value interface{}
+		value, err = c.evaluate(c.Host, n)
+		if err != nil {
+			return "", 0, err
+		}
+		var isAString bool
+		if dest, isAString = value.(string); !isAString {
+			return "", 0, fmt.Errorf("`%s` was not of type string", c.Host)
+		}
+		if c.PortPath != "" {
+			port, err = c.getPort(n)
+			if err != nil {
+				return "", 0, err
+			}
+		}
+
+	default:
+		return "", 0, fmt.Errorf("node was neither a string nor a hash")
+	}
+
+	if !IsIPAddress(dest) && !IsHostname(dest) {
+		return "", 0, fmt.Errorf("`%s` is not a valid IP address or hostname", dest)
+	}
+
+	if port == 0 {
+		port = c.Port
+	}
+	if !IsPort(port) {
+		return "", 0, fmt.Errorf("no valid port was found for the node")
+	}
+	return dest, port, nil
+}
+
+func (c Cluster) evaluate(expr string, doc interface{}) (interface{}, error) {
+	path, err := compileJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return path.lookup(doc)
+}
+
+func (c Cluster) getPort(node map[string]interface{}) (int, error) {
+	port, err := c.evaluate(c.PortPath, node)
+	if err != nil {
+		return 0, err
+	}
+	switch p := port.(type) {
+	case float64:
+		return int(p), nil
+	case string:
+		portAsInt, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, fmt.Errorf("`%s` was not a number", c.PortPath)
+		}
+		return portAsInt, nil
+	}
+	return 0, fmt.Errorf("`%s` was not a number", c.PortPath)
+}
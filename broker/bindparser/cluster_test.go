@@ -0,0 +1,190 @@
+package bindparser_test
+
+import (
+	. "github.com/cloudfoundry-community/portcullis/broker/bindparser"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster", func() {
+	var testCluster Cluster
+	var err error
+	BeforeEach(func() {
+		testCluster = Cluster{
+			Hosts: "$.credentials.hosts",
+			Port:  9042,
+		}
+	})
+
+	Describe("Verify", func() {
+		JustBeforeEach(func() {
+			err = testCluster.Verify()
+		})
+
+		Context("With a valid config", func() {
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("When the hosts expression is missing", func() {
+			BeforeEach(func() {
+				testCluster.Hosts = ""
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the host expression doesn't compile", func() {
+			BeforeEach(func() {
+				testCluster.Host = "ip"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the port is out of range", func() {
+			BeforeEach(func() {
+				testCluster.Port = 65536
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Rules", func() {
+		var testRules []SecGroupRule
+		var testCreds map[string]interface{}
+		JustBeforeEach(func() {
+			testRules, err = testCluster.Rules(testCreds)
+		})
+
+		Context("When the hosts are an array of addresses", func() {
+			BeforeEach(func() {
+				testCreds = map[string]interface{}{
+					"hosts": []interface{}{"10.0.0.1", "10.0.0.2:9160", "10.0.0.3"},
+				}
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should return a rule for every node", func() {
+				Expect(testRules).To(ConsistOf(
+					SecGroupRule{Destination: "10.0.0.1", Ports: "9042"},
+					SecGroupRule{Destination: "10.0.0.2", Ports: "9160"},
+					SecGroupRule{Destination: "10.0.0.3", Ports: "9042"},
+				))
+			})
+		})
+
+		Context("When the hosts are a comma-separated string", func() {
+			BeforeEach(func() {
+				testCreds = map[string]interface{}{
+					"hosts": "10.0.0.1:9092, 10.0.0.2:9092,10.0.0.1:9092",
+				}
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should return one rule per distinct node", func() {
+				Expect(testRules).To(ConsistOf(
+					SecGroupRule{Destination: "10.0.0.1", Ports: "9092"},
+					SecGroupRule{Destination: "10.0.0.2", Ports: "9092"},
+				))
+			})
+		})
+
+		Context("When the hosts are an array of hashes", func() {
+			BeforeEach(func() {
+				testCluster.Hosts = "$.credentials.nodes"
+				testCluster.Host = "$.ip"
+				testCluster.PortPath = "$.port"
+				testCreds = map[string]interface{}{
+					"nodes": []interface{}{
+						map[string]interface{}{"ip": "10.0.0.4", "port": float64(9200)},
+						map[string]interface{}{"ip": "10.0.0.5", "port": "9201"},
+					},
+				}
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should return a rule for every node", func() {
+				Expect(testRules).To(ConsistOf(
+					SecGroupRule{Destination: "10.0.0.4", Ports: "9200"},
+					SecGroupRule{Destination: "10.0.0.5", Ports: "9201"},
+				))
+			})
+
+			Context("but no host expression is configured", func() {
+				BeforeEach(func() {
+					testCluster.Host = ""
+				})
+
+				It("should return an error", func() {
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Context("When a node has no port and there is no default", func() {
+			BeforeEach(func() {
+				testCluster.Port = 0
+				testCreds = map[string]interface{}{
+					"hosts": []interface{}{"10.0.0.1"},
+				}
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When a node is not a valid ip address", func() {
+			BeforeEach(func() {
+				testCreds = map[string]interface{}{
+					"hosts": []interface{}{"10.0.0.1", "10.0.0.256"},
+				}
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the node list is empty", func() {
+			BeforeEach(func() {
+				testCreds = map[string]interface{}{
+					"hosts": []interface{}{},
+				}
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the hosts key is missing", func() {
+			BeforeEach(func() {
+				testCreds = map[string]interface{}{}
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
@@ -1,6 +1,7 @@
 package bindparser
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 
@@ -9,12 +10,24 @@ import (
 
 //Config is a configuration object used to set up a bindparser Flavor
 type Config struct {
-	FlavorName string                 `json:"flavor",yaml:"flavor"`
-	Config     map[string]interface{} `json:"config",yaml:"config"`
+	FlavorName string                 `json:"flavor" yaml:"flavor"`
+	Config     map[string]interface{} `json:"config" yaml:"config"`
+	//Defaults are used for the parts of rules that the flavor leaves unset
+	Defaults RuleDefaults `json:"defaults" yaml:"defaults"`
 }
 
+//ConfigList is a list of bind configs, the flavors of which are all used
+// together to make a single security group. When read from JSON, a single
+// config object is also accepted, and becomes a list of one.
+type ConfigList []Config
+
 var flavorMap = map[string]flavorMaker{
-	"dummy": NewDummy,
+	"cluster":  NewCluster,
+	"dummy":    NewDummy,
+	"jsonpath": NewJSONPath,
+	"none":     NewNone,
+	"static":   NewStatic,
+	"uri":      NewURI,
 }
 
 //CreateFlavor creates the implementation of flavor as specified by the config
@@ -33,14 +46,44 @@ func (c Config) CreateFlavor() (f Flavor, e error) {
 }
 
 //VerifyFlavor is just a shorthand for CreateFlavor, and then calling Verify on
-// the produced Flavor instance
+// the produced Flavor instance. The rules of a static flavor are also checked
+// against the defaults, as a rule that leaves its protocol to them only gets
+// its protocol once they are applied.
 func (c Config) VerifyFlavor() error {
 	flavor, err := c.CreateFlavor()
 	if err != nil {
 		return err
 	}
 	err = flavor.Verify()
-	return err
+	if err != nil {
+		return err
+	}
+	err = c.Defaults.Verify()
+	if err != nil {
+		return err
+	}
+	if static, isStatic := flavor.(*Static); isStatic {
+		return c.verifyStaticPorts(static)
+	}
+	return nil
+}
+
+//verifyStaticPorts checks that every static rule whose protocol, once the
+// defaults are applied, is tcp or udp has ports to open
+func (c Config) verifyStaticPorts(static *Static) error {
+	for i, rule := range static.StaticRules {
+		protocol := rule.Protocol
+		if protocol == "" {
+			protocol = c.Defaults.Protocol
+		}
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		if rule.Ports == "" && (protocol == "tcp" || protocol == "udp") {
+			return fmt.Errorf("Rule %d: `ports` must be given for %s rules", i, protocol)
+		}
+	}
+	return nil
 }
 
 func configIntoFlavor(conf map[string]interface{}, flavor Flavor) error {
@@ -51,3 +94,74 @@ func configIntoFlavor(conf map[string]interface{}, flavor Flavor) error {
 	err = yaml.Unmarshal(j, flavor)
 	return err
 }
+
+//UnmarshalJSON reads either a list of configs, or a single config object into
+// the ConfigList
+func (c *ConfigList) UnmarshalJSON(j []byte) error {
+	if trimmed := bytes.TrimSpace(j); len(trimmed) > 0 && trimmed[0] == '{' {
+		var single Config
+		if err := json.Unmarshal(trimmed, &single); err != nil {
+			return err
+		}
+		*c = ConfigList{single}
+		return nil
+	}
+
+	var list []Config
+	if err := json.Unmarshal(j, &list); err != nil {
+		return err
+	}
+	*c = list
+	return nil
+}
+
+//CreateFlavors calls CreateFlavor on every config in the list, and returns the
+// results as a FlavorList. The rules made by each flavor are completed with the
+// defaults from the config that it came from.
+func (c ConfigList) CreateFlavors() (FlavorList, error) {
+	ret := FlavorList{}
+	for i, conf := range c {
+		flavor, err := conf.CreateFlavor()
+		if err != nil {
+			return nil, fmt.Errorf("Bind config %d: %s", i, err)
+		}
+		ret = append(ret, defaultedFlavor{Flavor: flavor, defaults: conf.Defaults})
+	}
+	return ret, nil
+}
+
+//VerifyFlavors checks that there is at least one config in the list, and calls
+// VerifyFlavor on each of them
+func (c ConfigList) VerifyFlavors() error {
+	if len(c) == 0 {
+		return fmt.Errorf("At least one bind config must be given")
+	}
+	for i, conf := range c {
+		if err := conf.VerifyFlavor(); err != nil {
+			return fmt.Errorf("Bind config %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+//VerifyNoHostnames checks that none of the static flavors in the list open a
+// hostname destination. Those can only be used by mappings that resolve
+// hostnames, and would make every bind fail otherwise.
+func (c ConfigList) VerifyNoHostnames() error {
+	for i, conf := range c {
+		flavor, err := conf.CreateFlavor()
+		if err != nil {
+			return fmt.Errorf("Bind config %d: %s", i, err)
+		}
+		static, isStatic := flavor.(*Static)
+		if !isStatic {
+			continue
+		}
+		for j, rule := range static.StaticRules {
+			if IsHostname(rule.Destination) {
+				return fmt.Errorf("Bind config %d: Rule %d: `destination` is a hostname, which needs `resolve_hostnames` to be set", i, j)
+			}
+		}
+	}
+	return nil
+}
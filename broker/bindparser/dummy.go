@@ -4,9 +4,6 @@ import (
 	"fmt"
 	"reflect"
 
-	"strconv"
-
-	"github.com/cloudfoundry-community/go-cfclient"
 	"github.com/starkandwayne/goutils/log"
 )
 
@@ -16,7 +13,10 @@ import (
 type Dummy struct {
 	//Confirm, to make sure that you REALLY want to use this debugging implementation
 	// for whatever it is you're doing
-	Confirm bool `json:"confirm",yaml:"confirm"`
+	Confirm bool `json:"confirm" yaml:"confirm"`
+	//AllowBroadDestinations lets the credentials give CIDR blocks and ranges of
+	// addresses broader than a /8, or a /32 for IPv6
+	AllowBroadDestinations bool `json:"allow_broad_destinations" yaml:"allow_broad_destinations"`
 }
 
 //NewDummy creates a new Dummy flavor object
@@ -36,8 +36,8 @@ func (d Dummy) Verify() error {
 // security group is configured to allow apps in this space to talk to redis
 // for the given credentials returned by the bind call. Locations to get the keys
 // from are hardcoded in this Flavor implementation
-func (d Dummy) Rule(creds map[string]interface{}) (rule cfclient.SecGroupRule, err error) {
-	rule.Protocol = "tcp"
+func (d Dummy) Rule(creds map[string]interface{}) (rule SecGroupRule, err error) {
+	//The protocol is left to the bind config defaults
 	rule.Log = false
 	//get the destination IP
 
@@ -48,12 +48,11 @@ func (d Dummy) Rule(creds map[string]interface{}) (rule cfclient.SecGroupRule, e
 	}
 	rule.Destination = dest
 
-	port, err := d.getPort(creds)
+	//get the ports to open
+	rule.Ports, err = d.getPorts(creds)
 	if err != nil {
 		return rule, err
 	}
-	//get the port to open
-	rule.Ports = strconv.Itoa(port)
 
 	return rule, nil
 }
@@ -67,25 +66,31 @@ func (d Dummy) getDest(creds map[string]interface{}) (string, error) {
 	if !isAString {
 		return "", fmt.Errorf("`host` key in broker credentials JSON was not of type string")
 	}
-	if !IsIPAddress(destAsString) {
-		return "", fmt.Errorf("`host` key is not a valid IP address")
+	if !IsDestination(destAsString) && !IsHostname(destAsString) {
+		return "", fmt.Errorf("`host` key is not a valid IP address, CIDR block, IP range or hostname")
+	}
+	if IsBroadDestination(destAsString) && !d.AllowBroadDestinations {
+		return "", fmt.Errorf("`host` key is broader than a /%d for IPv4 or a /%d for IPv6, which needs `allow_broad_destinations` to be set", minCredentialPrefixV4, minCredentialPrefixV6)
 	}
 	return destAsString, nil
 }
 
-func (d Dummy) getPort(creds map[string]interface{}) (int, error) {
+//getPorts reads the `port` key, which may be a single port number, or a string
+// holding a port, a range of ports, or a comma separated list of ports
+func (d Dummy) getPorts(creds map[string]interface{}) (string, error) {
 	port, found := creds["port"]
 	if !found {
-		return 0, fmt.Errorf("`port` key not found in broker credentials JSON")
+		return "", fmt.Errorf("`port` key not found in broker credentials JSON")
 	}
 	log.Debugf("Type of port: %s", reflect.TypeOf(port).String())
-	portAsFloat, isAnInt := port.(float64)
-	if !isAnInt {
-		return 0, fmt.Errorf("`port` key in broker credentials JSON was not a number")
+	switch port.(type) {
+	case float64, string:
+	default:
+		return "", fmt.Errorf("`port` key in broker credentials JSON was not a number")
 	}
-	portAsInt := int(portAsFloat)
-	if !IsPort(portAsInt) {
-		return 0, fmt.Errorf("`port` key in broker credentials JSON is not a valid port number")
+	ports, ok := portsFromValue(port)
+	if !ok {
+		return "", fmt.Errorf("`port` key in broker credentials JSON is not a valid port number, range or list")
 	}
-	return portAsInt, nil
+	return ports, nil
 }
@@ -3,7 +3,6 @@ package bindparser_test
 import (
 	"strconv"
 
-	"github.com/cloudfoundry-community/go-cfclient"
 	. "github.com/cloudfoundry-community/portcullis/broker/bindparser"
 
 	. "github.com/onsi/ginkgo"
@@ -41,7 +40,7 @@ var _ = Describe("Dummy", func() {
 		const testHost = "10.244.3.2"
 		const testPass = "6489097d-388a-45e6-9142-7c1d29349e8b"
 		const testPort = 46486
-		var testRule cfclient.SecGroupRule
+		var testRule SecGroupRule
 		var testCreds map[string]interface{}
 		JustBeforeEach(func() {
 			testRule, err = testDummy.Rule(testCreds)
@@ -58,8 +57,8 @@ var _ = Describe("Dummy", func() {
 				It("should not return an error", func() {
 					Expect(err).NotTo(HaveOccurred())
 				})
-				Specify("the rule should have tcp as its protocol", func() {
-					Expect(testRule.Protocol).To(Equal("tcp"))
+				Specify("the rule should leave the protocol to the bind config defaults", func() {
+					Expect(testRule.Protocol).To(BeEmpty())
 				})
 				Specify("the rule should have the correct host", func() {
 					Expect(testRule.Destination).To(Equal(testHost))
@@ -146,17 +145,88 @@ var _ = Describe("Dummy", func() {
 			})
 		})
 
-		Context("When port is not an int", func() {
+		Context("When port is a string that isn't a port", func() {
 			BeforeEach(func() {
 				testCreds = map[string]interface{}{
 					"host":     testHost,
 					"password": testPass,
-					"port":     "2342",
+					"port":     "twenty",
 				}
 			})
 			It("should return an error", func() {
 				Expect(err).To(HaveOccurred())
 			})
 		})
+
+		Context("When the host is a CIDR block and the port is a range", func() {
+			BeforeEach(func() {
+				testCreds = map[string]interface{}{
+					"host": "10.1.0.0/24",
+					"port": "5000-5010",
+				}
+			})
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("should pass them through to the rule", func() {
+				Expect(testRule.Destination).To(Equal("10.1.0.0/24"))
+				Expect(testRule.Ports).To(Equal("5000-5010"))
+			})
+		})
+
+		Context("When the host is a CIDR block broader than a /8", func() {
+			BeforeEach(func() {
+				testCreds = map[string]interface{}{
+					"host": "0.0.0.0/1",
+					"port": "5000-5010",
+				}
+			})
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+
+			Context("When the config allows broad destinations", func() {
+				BeforeEach(func() {
+					testDummy.AllowBroadDestinations = true
+				})
+				It("should not return an error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+				It("should pass the block through to the rule", func() {
+					Expect(testRule.Destination).To(Equal("0.0.0.0/1"))
+				})
+			})
+		})
+
+		Context("When the host is an IP range and the port is a list", func() {
+			BeforeEach(func() {
+				testCreds = map[string]interface{}{
+					"host": "10.0.0.1-10.0.0.9",
+					"port": "80, 443",
+				}
+			})
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("should pass them through to the rule", func() {
+				Expect(testRule.Destination).To(Equal("10.0.0.1-10.0.0.9"))
+				Expect(testRule.Ports).To(Equal("80,443"))
+			})
+		})
+
+		Context("When the host is an IPv6 address", func() {
+			BeforeEach(func() {
+				testCreds = map[string]interface{}{
+					"host": "2001:db8::5",
+					"port": float64(testPort),
+				}
+			})
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("should have the correct host", func() {
+				Expect(testRule.Destination).To(Equal("2001:db8::5"))
+			})
+		})
 	})
 })
@@ -3,8 +3,6 @@ package bindparser
 import (
 	"fmt"
 	"strings"
-
-	"github.com/cloudfoundry-community/go-cfclient"
 )
 
 //Flavor represents a way of parsing out the information needed to create a
@@ -18,8 +16,21 @@ type Flavor interface {
 	//Rule should return a SecGroupRule struct containing all the information needed
 	// to make a Cloud Foundry security group. The implementation of Flavor can
 	// do this however it needs to, as defined by the purpose of that Flavor
-	// implementation.
-	Rule(creds map[string]interface{}) (cfclient.SecGroupRule, error)
+	// implementation. The destination may be a hostname, in which case it must
+	// be resolved with ResolveRules before being given to Cloud Foundry. The
+	// protocol may be left empty, so that the defaults in the bind config are
+	// used.
+	Rule(creds map[string]interface{}) (SecGroupRule, error)
+}
+
+//MultiRuleFlavor is a Flavor which can open egress to more than one
+// destination for a single binding, such as the nodes of a clustered service.
+// FlavorList prefers Rules over Rule for Flavors that implement this.
+type MultiRuleFlavor interface {
+	Flavor
+	//Rules should return all of the SecGroupRules needed for the given
+	// credentials.
+	Rules(creds map[string]interface{}) ([]SecGroupRule, error)
 }
 
 type flavorMaker func() Flavor
@@ -27,22 +38,40 @@ type flavorMaker func() Flavor
 //FlavorList is a slice of Flavor instances
 type FlavorList []Flavor
 
-//Rules runs Rule on all the flavors in the list, compiling the results into a slice
+//Rules runs Rule (or Rules, for a MultiRuleFlavor) on all the flavors in the
+// list, compiling the results into a slice
 // and all of their errors into a single error object, with the error messages
 // separated by newlines. The error is nil if no flavors return an error.
+// Rules which don't say what protocol they are for are made into tcp rules.
 // The contents of the rules slice are undefined if an error is returned
-func (f FlavorList) Rules(creds map[string]interface{}) (rules []cfclient.SecGroupRule, retErr error) {
+func (f FlavorList) Rules(creds map[string]interface{}) (rules []SecGroupRule, retErr error) {
 	var errs []string
 	for _, flavor := range f {
-		rule, err := flavor.Rule(creds)
+		flavorRules, err := flavorRules(flavor, creds, RuleDefaults{})
 		if err != nil {
 			errs = append(errs, err.Error())
 		} else {
-			rules = append(rules, rule)
+			rules = append(rules, flavorRules...)
 		}
 	}
 	if len(errs) > 0 {
-		retErr = fmt.Errorf(strings.Join(errs, "\n"))
+		retErr = fmt.Errorf("%s", strings.Join(errs, "\n"))
 	}
 	return
 }
+
+//OpensEgress returns false if none of the flavors in the list could ever
+// return a rule, meaning that binds need no security group at all
+func (f FlavorList) OpensEgress() bool {
+	for _, flavor := range f {
+		if defaulted, isDefaulted := flavor.(defaultedFlavor); isDefaulted {
+			flavor = defaulted.Flavor
+		}
+		switch flavor.(type) {
+		case None, *None:
+		default:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package bindparser_test
+
+import (
+	. "github.com/cloudfoundry-community/portcullis/broker/bindparser"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FlavorList", func() {
+	Describe("Rules", func() {
+		var testList FlavorList
+		var testRules []SecGroupRule
+		var err error
+		testCreds := map[string]interface{}{
+			"host":  "10.0.0.1",
+			"port":  float64(6379),
+			"nodes": []interface{}{"10.0.0.2:9042", "10.0.0.3:9042"},
+		}
+
+		JustBeforeEach(func() {
+			testRules, err = testList.Rules(testCreds)
+		})
+
+		Context("With single and multiple rule flavors", func() {
+			BeforeEach(func() {
+				testList = FlavorList{
+					&Dummy{Confirm: true},
+					&Cluster{Hosts: "$.credentials.nodes"},
+				}
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should return the rules from all of the flavors", func() {
+				Expect(testRules).To(ConsistOf(
+					SecGroupRule{Protocol: "tcp", Destination: "10.0.0.1", Ports: "6379"},
+					SecGroupRule{Protocol: "tcp", Destination: "10.0.0.2", Ports: "9042"},
+					SecGroupRule{Protocol: "tcp", Destination: "10.0.0.3", Ports: "9042"},
+				))
+			})
+		})
+
+		Context("When one of the flavors fails", func() {
+			BeforeEach(func() {
+				testList = FlavorList{
+					&Dummy{Confirm: true},
+					&Cluster{Hosts: "$.credentials.missing"},
+				}
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("OpensEgress", func() {
+		It("should return false when every flavor is none", func() {
+			Expect(FlavorList{&None{}}.OpensEgress()).To(BeFalse())
+		})
+
+		It("should return true when any flavor could open egress", func() {
+			Expect(FlavorList{&None{}, &Static{}}.OpensEgress()).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,168 @@
+package bindparser
+
+import (
+	"fmt"
+)
+
+//JSONPath is an implementation of bindparser.Flavor that finds the address and
+// port to open egress to by evaluating configured JSONPath expressions. The
+// expressions are evaluated against the bind response body, so they will
+// usually begin with `$.credentials`.
+type JSONPath struct {
+	//Host is an expression pointing to the address to open egress to. This may
+	// also be a CIDR block, a range of IP addresses, or a hostname
+	Host string `json:"host" yaml:"host"`
+	//Port is an expression pointing to the ports to open egress to. The value
+	// may be a number, or a string holding a port, a range of ports such as
+	// `5000-5010`, or a comma separated list of ports such as `80,443`
+	Port string `json:"port" yaml:"port"`
+	//Protocol is an optional expression pointing to the protocol of the rule.
+	// The bind config defaults are used if not given
+	Protocol string `json:"protocol" yaml:"protocol"`
+	//ICMPType is an optional expression pointing to the ICMP type of the rule
+	ICMPType string `json:"icmp_type" yaml:"icmp_type"`
+	//ICMPCode is an optional expression pointing to the ICMP code of the rule
+	ICMPCode string `json:"icmp_code" yaml:"icmp_code"`
+	//AllowBroadDestinations lets the host be a CIDR block or range of addresses
+	// broader than a /8, or a /32 for IPv6
+	AllowBroadDestinations bool `json:"allow_broad_destinations" yaml:"allow_broad_destinations"`
+}
+
+//NewJSONPath creates a new JSONPath flavor object
+func NewJSONPath() Flavor {
+	return &JSONPath{}
+}
+
+//Verify checks that the host and port expressions are given, and that all of
+// the given expressions compile
+func (j JSONPath) Verify() error {
+	if j.Host == "" {
+		return fmt.Errorf("`host` must be given for the jsonpath flavor")
+	}
+	if j.Port == "" {
+		return fmt.Errorf("`port` must be given for the jsonpath flavor")
+	}
+	for _, expr := range []string{j.Host, j.Port, j.Protocol, j.ICMPType, j.ICMPCode} {
+		if expr == "" {
+			continue
+		}
+		if _, err := compileJSONPath(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Rule returns a cf security group rule made from the values that the configured
+// expressions point to in the credentials
+func (j JSONPath) Rule(creds map[string]interface{}) (rule SecGroupRule, err error) {
+	if creds == nil {
+		return rule, fmt.Errorf("No broker credentials were given")
+	}
+	doc := map[string]interface{}{"credentials": creds}
+
+	rule.Log = false
+
+	rule.Destination, err = j.getDest(doc)
+	if err != nil {
+		return rule, err
+	}
+
+	rule.Ports, err = j.getPorts(doc)
+	if err != nil {
+		return rule, err
+	}
+
+	if j.Protocol != "" {
+		rule.Protocol, err = j.getProtocol(doc)
+		if err != nil {
+			return rule, err
+		}
+	}
+
+	if j.ICMPType != "" {
+		rule.Type, err = j.getICMPControl(j.ICMPType, doc)
+		if err != nil {
+			return rule, err
+		}
+	}
+
+	if j.ICMPCode != "" {
+		rule.Code, err = j.getICMPControl(j.ICMPCode, doc)
+		if err != nil {
+			return rule, err
+		}
+	}
+
+	return rule, nil
+}
+
+func (j JSONPath) evaluate(expr string, doc interface{}) (interface{}, error) {
+	path, err := compileJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return path.lookup(doc)
+}
+
+func (j JSONPath) getDest(doc interface{}) (string, error) {
+	dest, err := j.evaluate(j.Host, doc)
+	if err != nil {
+		return "", err
+	}
+	destAsString, isAString := dest.(string)
+	if !isAString {
+		return "", fmt.Errorf("`%s` in broker credentials JSON was not of type string", j.Host)
+	}
+	if !IsDestination(destAsString) && !IsHostname(destAsString) {
+		return "", fmt.Errorf("`%s` is not a valid IP address, CIDR block, IP range or hostname", j.Host)
+	}
+	if IsBroadDestination(destAsString) && !j.AllowBroadDestinations {
+		return "", fmt.Errorf("`%s` is broader than a /%d for IPv4 or a /%d for IPv6, which needs `allow_broad_destinations` to be set", j.Host, minCredentialPrefixV4, minCredentialPrefixV6)
+	}
+	return destAsString, nil
+}
+
+func (j JSONPath) getPorts(doc interface{}) (string, error) {
+	port, err := j.evaluate(j.Port, doc)
+	if err != nil {
+		return "", err
+	}
+
+	switch port.(type) {
+	case float64, string:
+	default:
+		return "", fmt.Errorf("`%s` in broker credentials JSON was not a number", j.Port)
+	}
+
+	ports, ok := portsFromValue(port)
+	if !ok {
+		return "", fmt.Errorf("`%s` in broker credentials JSON is not a valid port number, range or list", j.Port)
+	}
+	return ports, nil
+}
+
+func (j JSONPath) getProtocol(doc interface{}) (string, error) {
+	protocol, err := j.evaluate(j.Protocol, doc)
+	if err != nil {
+		return "", err
+	}
+	protocolAsString, isAString := protocol.(string)
+	if !isAString || !IsProtocol(protocolAsString) {
+		return "", fmt.Errorf("`%s` in broker credentials JSON is not a valid protocol", j.Protocol)
+	}
+	return protocolAsString, nil
+}
+
+func (j JSONPath) getICMPControl(expr string, doc interface{}) (*int, error) {
+	value, err := j.evaluate(expr, doc)
+	if err != nil {
+		return nil, err
+	}
+	valueAsFloat, isANumber := value.(float64)
+	if !isANumber || valueAsFloat != float64(int(valueAsFloat)) || !IsICMPControl(int(valueAsFloat)) {
+		return nil, fmt.Errorf("`%s` in broker credentials JSON is not a valid ICMP type or code", expr)
+	}
+	ret := int(valueAsFloat)
+	return &ret, nil
+}
@@ -0,0 +1,282 @@
+package bindparser_test
+
+import (
+	. "github.com/cloudfoundry-community/portcullis/broker/bindparser"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSONPath", func() {
+	var testJSONPath JSONPath
+	var err error
+	BeforeEach(func() {
+		testJSONPath = JSONPath{
+			Host: "$.credentials.nodes[0].hostname",
+			Port: "$.credentials.ports.amqp",
+		}
+	})
+
+	Describe("Verify", func() {
+		JustBeforeEach(func() {
+			err = testJSONPath.Verify()
+		})
+
+		Context("With valid expressions", func() {
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("With a bracketed key expression", func() {
+			BeforeEach(func() {
+				testJSONPath.Port = "$.credentials['ports'][\"amqp\"]"
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("When the host expression is missing", func() {
+			BeforeEach(func() {
+				testJSONPath.Host = ""
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the port expression is missing", func() {
+			BeforeEach(func() {
+				testJSONPath.Port = ""
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When an expression doesn't start at the root", func() {
+			BeforeEach(func() {
+				testJSONPath.Host = "credentials.host"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When an expression has an unclosed subscript", func() {
+			BeforeEach(func() {
+				testJSONPath.Host = "$.credentials.nodes[0"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When an expression uses an unsupported subscript", func() {
+			BeforeEach(func() {
+				testJSONPath.Protocol = "$.credentials.nodes[?(@.primary)]"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Rule", func() {
+		var testRule SecGroupRule
+		var testCreds map[string]interface{}
+		JustBeforeEach(func() {
+			testRule, err = testJSONPath.Rule(testCreds)
+		})
+
+		BeforeEach(func() {
+			testCreds = map[string]interface{}{
+				"nodes": []interface{}{
+					map[string]interface{}{"hostname": "10.0.0.7"},
+					map[string]interface{}{"hostname": "10.0.0.8"},
+				},
+				"ports": map[string]interface{}{
+					"amqp":  float64(5672),
+					"mgmt":  "15672",
+					"proto": "udp",
+					"range": "5000-5010",
+					"ping":  "icmp",
+					"echo":  float64(8),
+				},
+				"subnet": "10.1.0.0/24",
+			}
+		})
+
+		Context("When the expressions point at valid values", func() {
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+			Specify("the rule should leave the protocol to the bind config defaults", func() {
+				Expect(testRule.Protocol).To(BeEmpty())
+			})
+			Specify("the rule should have the correct host", func() {
+				Expect(testRule.Destination).To(Equal("10.0.0.7"))
+			})
+			Specify("the rule should have the correct port", func() {
+				Expect(testRule.Ports).To(Equal("5672"))
+			})
+		})
+
+		Context("When the port is a numeric string", func() {
+			BeforeEach(func() {
+				testJSONPath.Port = "$.credentials.ports.mgmt"
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+			Specify("the rule should have the correct port", func() {
+				Expect(testRule.Ports).To(Equal("15672"))
+			})
+		})
+
+		Context("When the host is a CIDR block and the port is a range", func() {
+			BeforeEach(func() {
+				testJSONPath.Host = "$.credentials.subnet"
+				testJSONPath.Port = "$.credentials.ports.range"
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+			Specify("the rule should have the CIDR block as its destination", func() {
+				Expect(testRule.Destination).To(Equal("10.1.0.0/24"))
+			})
+			Specify("the rule should have the port range", func() {
+				Expect(testRule.Ports).To(Equal("5000-5010"))
+			})
+		})
+
+		Context("When the host is a CIDR block broader than a /8", func() {
+			BeforeEach(func() {
+				testCreds["subnet"] = "10.0.0.0/7"
+				testJSONPath.Host = "$.credentials.subnet"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+
+			Context("When the config allows broad destinations", func() {
+				BeforeEach(func() {
+					testJSONPath.AllowBroadDestinations = true
+				})
+
+				It("should not return an error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+		})
+
+		Context("When a protocol expression is given", func() {
+			BeforeEach(func() {
+				testJSONPath.Protocol = "$.credentials.ports.proto"
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+			Specify("the rule should have the protocol from the credentials", func() {
+				Expect(testRule.Protocol).To(Equal("udp"))
+			})
+		})
+
+		Context("When protocol and ICMP type expressions are given", func() {
+			BeforeEach(func() {
+				testJSONPath.Protocol = "$.credentials.ports.ping"
+				testJSONPath.ICMPType = "$.credentials.ports.echo"
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+			Specify("the rule should have the ICMP protocol and type", func() {
+				Expect(testRule.Protocol).To(Equal("icmp"))
+				Expect(testRule.Type).NotTo(BeNil())
+				Expect(*testRule.Type).To(Equal(8))
+			})
+		})
+
+		Context("When the ICMP type is not a number", func() {
+			BeforeEach(func() {
+				testJSONPath.ICMPType = "$.credentials.ports.ping"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the protocol is not valid", func() {
+			BeforeEach(func() {
+				testJSONPath.Protocol = "$.credentials.ports.mgmt"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the host expression points at nothing", func() {
+			BeforeEach(func() {
+				testJSONPath.Host = "$.credentials.nodes[2].hostname"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the host expression indexes into a hash", func() {
+			BeforeEach(func() {
+				testJSONPath.Host = "$.credentials.ports[0]"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the host is not a valid ip address", func() {
+			BeforeEach(func() {
+				testJSONPath.Host = "$.credentials.ports.mgmt"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the port is not a number", func() {
+			BeforeEach(func() {
+				testJSONPath.Port = "$.credentials.ports.proto"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When creds is nil", func() {
+			BeforeEach(func() {
+				testCreds = nil
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
@@ -0,0 +1,31 @@
+package bindparser
+
+import (
+	"fmt"
+)
+
+//None is an implementation of bindparser.MultiRuleFlavor for brokers that
+// shouldn't have any egress opened for them at all. Binds to these brokers are
+// still passed through, but no security group is made.
+type None struct{}
+
+//NewNone creates a new None flavor object
+func NewNone() Flavor {
+	return &None{}
+}
+
+//Verify always succeeds, because there is nothing to configure
+func (n None) Verify() error {
+	return nil
+}
+
+//Rule always errs, because the none flavor has no rule to give. Use Rules,
+// which gives back an empty list.
+func (n None) Rule(creds map[string]interface{}) (rule SecGroupRule, err error) {
+	return rule, fmt.Errorf("The none flavor does not open any egress")
+}
+
+//Rules returns no rules
+func (n None) Rules(creds map[string]interface{}) ([]SecGroupRule, error) {
+	return []SecGroupRule{}, nil
+}
@@ -0,0 +1,33 @@
+package bindparser_test
+
+import (
+	. "github.com/cloudfoundry-community/portcullis/broker/bindparser"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("None", func() {
+	var testNone None
+
+	Describe("Verify", func() {
+		It("should not return an error", func() {
+			Expect(testNone.Verify()).To(Succeed())
+		})
+	})
+
+	Describe("Rules", func() {
+		It("should return no rules and no error", func() {
+			rules, err := testNone.Rules(map[string]interface{}{"host": "10.0.0.1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rules).To(BeEmpty())
+		})
+	})
+
+	Describe("Rule", func() {
+		It("should return an error", func() {
+			_, err := testNone.Rule(nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,100 @@
+package bindparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//jsonPath is a compiled JSONPath expression. Only the subset of JSONPath needed
+// to point at a single value is supported: the root `$`, dotted child names,
+// bracketed child names (`['name']`), and array indices (`[0]`).
+type jsonPath struct {
+	expr  string
+	steps []pathStep
+}
+
+//pathStep is a single step down into a JSON document. If isIndex is true, the
+// step indexes into an array. Otherwise it looks up key in an object.
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+//compileJSONPath parses the given expression into a jsonPath, returning an
+// error if the expression is malformed or uses unsupported syntax
+func compileJSONPath(expr string) (ret jsonPath, err error) {
+	ret.expr = expr
+	if !strings.HasPrefix(expr, "$") {
+		return ret, fmt.Errorf("JSONPath `%s` must begin with `$`", expr)
+	}
+
+	rest := expr[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return ret, fmt.Errorf("JSONPath `%s` has an empty child name", expr)
+			}
+			ret.steps = append(ret.steps, pathStep{key: rest[:end]})
+			rest = rest[end:]
+
+		case '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return ret, fmt.Errorf("JSONPath `%s` has an unclosed `[`", expr)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				ret.steps = append(ret.steps, pathStep{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			index, convErr := strconv.Atoi(inner)
+			if convErr != nil || index < 0 {
+				return ret, fmt.Errorf("JSONPath `%s` has an unsupported subscript `[%s]`", expr, inner)
+			}
+			ret.steps = append(ret.steps, pathStep{index: index, isIndex: true})
+
+		default:
+			return ret, fmt.Errorf("JSONPath `%s` has unexpected character `%c`", expr, rest[0])
+		}
+	}
+	return ret, nil
+}
+
+//lookup walks the given unmarshalled JSON document and returns the value that
+// the path points to, or an error if it doesn't point at anything
+func (p jsonPath) lookup(doc interface{}) (interface{}, error) {
+	current := doc
+	for _, step := range p.steps {
+		if step.isIndex {
+			asSlice, isASlice := current.([]interface{})
+			if !isASlice {
+				return nil, fmt.Errorf("`%s` indexes into something that is not an array", p.expr)
+			}
+			if step.index >= len(asSlice) {
+				return nil, fmt.Errorf("`%s` has an index out of range", p.expr)
+			}
+			current = asSlice[step.index]
+			continue
+		}
+
+		asMap, isAMap := current.(map[string]interface{})
+		if !isAMap {
+			return nil, fmt.Errorf("`%s` looks up a key in something that is not a hash", p.expr)
+		}
+		var found bool
+		current, found = asMap[step.key]
+		if !found {
+			return nil, fmt.Errorf("`%s` key `%s` not found in broker credentials JSON", p.expr, step.key)
+		}
+	}
+	return current, nil
+}
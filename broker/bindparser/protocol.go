@@ -0,0 +1,139 @@
+package bindparser
+
+import (
+	"fmt"
+)
+
+//anyICMP is the ICMP type or code that CF takes to mean all of them
+const anyICMP = -1
+
+//RuleDefaults are values given to the rules that a flavor makes, for the parts
+// of the rule that the flavor leaves unset
+type RuleDefaults struct {
+	//Protocol is the protocol of the rule. One of tcp, udp, icmp or all.
+	// Defaults to tcp
+	Protocol string `json:"protocol,omitempty" yaml:"protocol"`
+	//ICMPType is the ICMP type of icmp rules. Defaults to -1, meaning all types
+	ICMPType *int `json:"icmp_type,omitempty" yaml:"icmp_type"`
+	//ICMPCode is the ICMP code of icmp rules. Defaults to -1, meaning all codes
+	ICMPCode *int `json:"icmp_code,omitempty" yaml:"icmp_code"`
+}
+
+//Verify checks that the defaults are values that CF would accept
+func (d RuleDefaults) Verify() error {
+	if d.Protocol != "" && !IsProtocol(d.Protocol) {
+		return fmt.Errorf("Default protocol `%s` is not a valid protocol", d.Protocol)
+	}
+	if d.ICMPType != nil && !IsICMPControl(*d.ICMPType) {
+		return fmt.Errorf("Default ICMP type %d is not a valid ICMP type", *d.ICMPType)
+	}
+	if d.ICMPCode != nil && !IsICMPControl(*d.ICMPCode) {
+		return fmt.Errorf("Default ICMP code %d is not a valid ICMP code", *d.ICMPCode)
+	}
+	return nil
+}
+
+//CompleteRule fills in the parts of the given rule that were left unset with
+// the defaults, and then checks that the rule has what CF needs for its
+// protocol. tcp and udp rules need ports, and icmp rules need a type and code.
+// Anything a rule has that its protocol doesn't take is removed.
+func (d RuleDefaults) CompleteRule(rule SecGroupRule) (SecGroupRule, error) {
+	if rule.Protocol == "" {
+		rule.Protocol = d.Protocol
+	}
+	if rule.Protocol == "" {
+		rule.Protocol = "tcp"
+	}
+
+	switch rule.Protocol {
+	case "tcp", "udp":
+		if !IsPorts(rule.Ports) {
+			return rule, fmt.Errorf("`%s` is not a valid port number, range or list for a %s rule", rule.Ports, rule.Protocol)
+		}
+		rule.Type, rule.Code = nil, nil
+
+	case "icmp":
+		rule.Ports = ""
+		if rule.Type == nil {
+			rule.Type = intOrDefault(d.ICMPType, anyICMP)
+		}
+		if rule.Code == nil {
+			rule.Code = intOrDefault(d.ICMPCode, anyICMP)
+		}
+		if !IsICMPControl(*rule.Type) {
+			return rule, fmt.Errorf("%d is not a valid ICMP type", *rule.Type)
+		}
+		if !IsICMPControl(*rule.Code) {
+			return rule, fmt.Errorf("%d is not a valid ICMP code", *rule.Code)
+		}
+
+	case "all":
+		rule.Ports = ""
+		rule.Type, rule.Code = nil, nil
+
+	default:
+		return rule, fmt.Errorf("`%s` is not a valid protocol", rule.Protocol)
+	}
+	return rule, nil
+}
+
+func intOrDefault(value *int, def int) *int {
+	if value != nil {
+		ret := *value
+		return &ret
+	}
+	return &def
+}
+
+//defaultedFlavor applies the rule defaults from a bind config to the rules
+// that its flavor makes
+type defaultedFlavor struct {
+	Flavor
+	defaults RuleDefaults
+}
+
+//Verify checks both the wrapped flavor and the defaults
+func (d defaultedFlavor) Verify() error {
+	if err := d.Flavor.Verify(); err != nil {
+		return err
+	}
+	return d.defaults.Verify()
+}
+
+//Rule returns the rule from the wrapped flavor, completed with the defaults
+func (d defaultedFlavor) Rule(creds map[string]interface{}) (SecGroupRule, error) {
+	rule, err := d.Flavor.Rule(creds)
+	if err != nil {
+		return rule, err
+	}
+	return d.defaults.CompleteRule(rule)
+}
+
+//Rules returns all of the rules from the wrapped flavor, completed with the
+// defaults
+func (d defaultedFlavor) Rules(creds map[string]interface{}) ([]SecGroupRule, error) {
+	return flavorRules(d.Flavor, creds, d.defaults)
+}
+
+//flavorRules gets every rule that the given flavor makes, and completes each
+// with the given defaults
+func flavorRules(flavor Flavor, creds map[string]interface{}, defaults RuleDefaults) (rules []SecGroupRule, err error) {
+	if multi, isMulti := flavor.(MultiRuleFlavor); isMulti {
+		rules, err = multi.Rules(creds)
+	} else {
+		var rule SecGroupRule
+		rule, err = flavor.Rule(creds)
+		rules = []SecGroupRule{rule}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rules {
+		rules[i], err = defaults.CompleteRule(rules[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
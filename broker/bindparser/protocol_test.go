@@ -0,0 +1,199 @@
+package bindparser_test
+
+import (
+	. "github.com/cloudfoundry-community/portcullis/broker/bindparser"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+var _ = Describe("Protocol", func() {
+	Describe("RuleDefaults", func() {
+		var testDefaults RuleDefaults
+		var err error
+		BeforeEach(func() {
+			testDefaults = RuleDefaults{}
+		})
+
+		Describe("Verify", func() {
+			JustBeforeEach(func() {
+				err = testDefaults.Verify()
+			})
+
+			Context("With no defaults set", func() {
+				It("should not return an error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			Context("With an icmp protocol, type and code", func() {
+				BeforeEach(func() {
+					testDefaults = RuleDefaults{Protocol: "icmp", ICMPType: intPtr(8), ICMPCode: intPtr(0)}
+				})
+
+				It("should not return an error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			Context("With an unknown protocol", func() {
+				BeforeEach(func() {
+					testDefaults.Protocol = "sctp"
+				})
+
+				It("should return an error", func() {
+					Expect(err).To(HaveOccurred())
+				})
+			})
+
+			Context("With an out of range ICMP type", func() {
+				BeforeEach(func() {
+					testDefaults.ICMPType = intPtr(256)
+				})
+
+				It("should return an error", func() {
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Describe("CompleteRule", func() {
+			var testRule SecGroupRule
+			var completedRule SecGroupRule
+			BeforeEach(func() {
+				testRule = SecGroupRule{Destination: "10.0.0.1", Ports: "514"}
+			})
+
+			JustBeforeEach(func() {
+				completedRule, err = testDefaults.CompleteRule(testRule)
+			})
+
+			Context("When neither the rule nor the defaults give a protocol", func() {
+				It("should make a tcp rule", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(completedRule.Protocol).To(Equal("tcp"))
+				})
+			})
+
+			Context("When the defaults give udp", func() {
+				BeforeEach(func() {
+					testDefaults.Protocol = "udp"
+				})
+
+				It("should make a udp rule with the same ports", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(completedRule).To(Equal(SecGroupRule{Protocol: "udp", Destination: "10.0.0.1", Ports: "514"}))
+				})
+
+				Context("but the rule gives its own protocol", func() {
+					BeforeEach(func() {
+						testRule.Protocol = "tcp"
+					})
+
+					It("should keep the rule's protocol", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(completedRule.Protocol).To(Equal("tcp"))
+					})
+				})
+			})
+
+			Context("When the defaults give icmp", func() {
+				BeforeEach(func() {
+					testDefaults.Protocol = "icmp"
+				})
+
+				It("should drop the ports and allow all types and codes", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(completedRule).To(Equal(SecGroupRule{
+						Protocol: "icmp", Destination: "10.0.0.1", Type: intPtr(-1), Code: intPtr(-1),
+					}))
+				})
+
+				Context("with a default type and code", func() {
+					BeforeEach(func() {
+						testDefaults.ICMPType = intPtr(0)
+						testDefaults.ICMPCode = intPtr(0)
+					})
+
+					It("should use them", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(*completedRule.Type).To(Equal(0))
+						Expect(*completedRule.Code).To(Equal(0))
+					})
+				})
+			})
+
+			Context("When the rule is for all protocols", func() {
+				BeforeEach(func() {
+					testRule.Protocol = "all"
+					testRule.Type = intPtr(3)
+				})
+
+				It("should drop the ports, type and code", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(completedRule).To(Equal(SecGroupRule{Protocol: "all", Destination: "10.0.0.1"}))
+				})
+			})
+
+			Context("When a tcp rule has no ports", func() {
+				BeforeEach(func() {
+					testRule.Ports = ""
+				})
+
+				It("should return an error", func() {
+					Expect(err).To(HaveOccurred())
+				})
+			})
+
+			Context("When the rule has an unknown protocol", func() {
+				BeforeEach(func() {
+					testRule.Protocol = "sctp"
+				})
+
+				It("should return an error", func() {
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+	})
+
+	Describe("Bind config defaults", func() {
+		var testRules []SecGroupRule
+		var err error
+		JustBeforeEach(func() {
+			var flavors FlavorList
+			flavors, err = ConfigList{
+				{
+					FlavorName: "dummy",
+					Config:     map[string]interface{}{"confirm": true},
+					Defaults:   RuleDefaults{Protocol: "udp"},
+				},
+				{
+					FlavorName: "static",
+					Config: map[string]interface{}{
+						"rules": []interface{}{
+							map[string]interface{}{"destination": "10.0.0.9", "protocol": "icmp", "icmp_type": 8},
+						},
+					},
+				},
+			}.CreateFlavors()
+			Expect(err).NotTo(HaveOccurred())
+			testRules, err = flavors.Rules(map[string]interface{}{"host": "10.0.0.5", "port": float64(8125)})
+		})
+
+		It("should not return an error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should apply each config's defaults to the rules of its own flavor", func() {
+			Expect(testRules).To(Equal([]SecGroupRule{
+				{Protocol: "udp", Destination: "10.0.0.5", Ports: "8125"},
+				{Protocol: "icmp", Destination: "10.0.0.9", Type: intPtr(8), Code: intPtr(-1)},
+			}))
+		})
+	})
+})
@@ -0,0 +1,69 @@
+package bindparser
+
+import (
+	"fmt"
+	"net"
+	"sort"
+)
+
+//ResolverFunc looks up the addresses that the given hostname points to
+type ResolverFunc func(host string) (addrs []string, err error)
+
+var resolver ResolverFunc = net.LookupHost
+
+//SetResolver changes the function used to look up hostnames by ResolveRules.
+// Giving nil restores the default, which uses the system resolver.
+func SetResolver(r ResolverFunc) {
+	if r == nil {
+		r = net.LookupHost
+	}
+	resolver = r
+}
+
+//HasHostnames returns true if any of the given rules have a hostname as their
+// destination. CF only accepts addresses, so these need to be resolved with
+// ResolveRules before they are used.
+func HasHostnames(rules []SecGroupRule) bool {
+	for _, rule := range rules {
+		if IsHostname(rule.Destination) {
+			return true
+		}
+	}
+	return false
+}
+
+//ResolveRules returns a copy of the given rules where every rule that has a
+// hostname as its destination is replaced by one rule for each address that
+// the hostname resolves to. Addresses are sorted so that resolving the same
+// records twice gives the same rules.
+func ResolveRules(rules []SecGroupRule) (ret []SecGroupRule, err error) {
+	for _, rule := range rules {
+		if !IsHostname(rule.Destination) {
+			ret = append(ret, rule)
+			continue
+		}
+
+		addrs, err := resolver(rule.Destination)
+		if err != nil {
+			return nil, fmt.Errorf("Could not resolve `%s`: %s", rule.Destination, err)
+		}
+
+		var resolved []string
+		for _, addr := range addrs {
+			if IsIPAddress(addr) {
+				resolved = append(resolved, addr)
+			}
+		}
+		if len(resolved) == 0 {
+			return nil, fmt.Errorf("`%s` did not resolve to any usable addresses", rule.Destination)
+		}
+
+		sort.Strings(resolved)
+		for _, addr := range resolved {
+			resolvedRule := rule
+			resolvedRule.Destination = addr
+			ret = append(ret, resolvedRule)
+		}
+	}
+	return ret, nil
+}
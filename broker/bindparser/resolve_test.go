@@ -0,0 +1,99 @@
+package bindparser_test
+
+import (
+	"fmt"
+
+	. "github.com/cloudfoundry-community/portcullis/broker/bindparser"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resolve", func() {
+	var testRules []SecGroupRule
+	var resolvedRules []SecGroupRule
+	var err error
+
+	BeforeEach(func() {
+		SetResolver(func(host string) ([]string, error) {
+			switch host {
+			case "db.example.com":
+				return []string{"10.0.0.9", "2001:db8::1", "10.0.0.8"}, nil
+			case "garbage.example.com":
+				return []string{"not-an-address"}, nil
+			}
+			return nil, fmt.Errorf("no such host")
+		})
+	})
+
+	AfterEach(func() {
+		SetResolver(nil)
+	})
+
+	Describe("HasHostnames", func() {
+		It("should return true when a destination is a hostname", func() {
+			Expect(HasHostnames([]SecGroupRule{
+				{Destination: "10.0.0.1"},
+				{Destination: "db.example.com"},
+			})).To(BeTrue())
+		})
+
+		It("should return false when all destinations are addresses", func() {
+			Expect(HasHostnames([]SecGroupRule{
+				{Destination: "10.0.0.1"},
+			})).To(BeFalse())
+		})
+	})
+
+	Describe("ResolveRules", func() {
+		JustBeforeEach(func() {
+			resolvedRules, err = ResolveRules(testRules)
+		})
+
+		Context("When a destination is a hostname", func() {
+			BeforeEach(func() {
+				testRules = []SecGroupRule{
+					{Protocol: "tcp", Destination: "10.0.0.1", Ports: "80"},
+					{Protocol: "tcp", Destination: "db.example.com", Ports: "3306"},
+				}
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should replace the hostname with a rule for each usable address, in order", func() {
+				Expect(resolvedRules).To(Equal([]SecGroupRule{
+					{Protocol: "tcp", Destination: "10.0.0.1", Ports: "80"},
+					{Protocol: "tcp", Destination: "10.0.0.8", Ports: "3306"},
+					{Protocol: "tcp", Destination: "10.0.0.9", Ports: "3306"},
+					{Protocol: "tcp", Destination: "2001:db8::1", Ports: "3306"},
+				}))
+			})
+		})
+
+		Context("When a hostname cannot be resolved", func() {
+			BeforeEach(func() {
+				testRules = []SecGroupRule{
+					{Protocol: "tcp", Destination: "nope.example.com", Ports: "3306"},
+				}
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When a hostname resolves to no usable addresses", func() {
+			BeforeEach(func() {
+				testRules = []SecGroupRule{
+					{Protocol: "tcp", Destination: "garbage.example.com", Ports: "3306"},
+				}
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
@@ -0,0 +1,18 @@
+package bindparser
+
+//SecGroupRule is a single rule of a Cloud Foundry security group, as it is
+// written to and read from the CF API. The vendored cfclient rule can't carry
+// ICMP types and codes, so Portcullis uses this one instead.
+type SecGroupRule struct {
+	Protocol string `json:"protocol"`
+	//Type is the ICMP type. Only valid if Protocol is icmp.
+	Type *int `json:"type,omitempty"`
+	//Code is the ICMP code. Only valid if Protocol is icmp.
+	Code *int `json:"code,omitempty"`
+	//Ports is a port, range of ports, or comma separated list of ports. Only
+	// valid if Protocol is tcp or udp.
+	Ports       string `json:"ports,omitempty"`
+	Destination string `json:"destination"`
+	Description string `json:"description,omitempty"`
+	Log         bool   `json:"log,omitempty"`
+}
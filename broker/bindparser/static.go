@@ -0,0 +1,91 @@
+package bindparser
+
+import (
+	"fmt"
+)
+
+//StaticRule is a single fixed rule for the static flavor to open
+type StaticRule struct {
+	//Destination is the address, CIDR block, IP range or hostname to open
+	Destination string `json:"destination" yaml:"destination"`
+	//Ports is a port, range of ports, or comma separated list of ports to open
+	Ports string `json:"ports" yaml:"ports"`
+	//Protocol is the protocol of the rule. The bind config defaults are used if
+	// not given
+	Protocol string `json:"protocol" yaml:"protocol"`
+	//ICMPType is the ICMP type of an icmp rule
+	ICMPType *int `json:"icmp_type" yaml:"icmp_type"`
+	//ICMPCode is the ICMP code of an icmp rule
+	ICMPCode *int `json:"icmp_code" yaml:"icmp_code"`
+}
+
+//Static is an implementation of bindparser.MultiRuleFlavor which opens the same
+// fixed set of rules on every bind, no matter what the credentials say. Useful
+// for brokers whose credentials don't say where the service lives, or for
+// opening a whole range of addresses or ports at once.
+type Static struct {
+	//StaticRules are the rules to open on every bind
+	StaticRules []StaticRule `json:"rules" yaml:"rules"`
+}
+
+//NewStatic creates a new Static flavor object
+func NewStatic() Flavor {
+	return &Static{}
+}
+
+//Verify checks that there is at least one rule, and that every rule is one
+// that CF would accept
+func (s Static) Verify() error {
+	if len(s.StaticRules) == 0 {
+		return fmt.Errorf("`rules` must have at least one rule for the static flavor")
+	}
+	for i, rule := range s.StaticRules {
+		if !IsDestination(rule.Destination) && !IsHostname(rule.Destination) {
+			return fmt.Errorf("Rule %d: `destination` is not a valid IP address, CIDR block, IP range or hostname", i)
+		}
+		if rule.Protocol != "" && !IsProtocol(rule.Protocol) {
+			return fmt.Errorf("Rule %d: `protocol` is not a valid protocol", i)
+		}
+		if rule.Ports != "" && !IsPorts(rule.Ports) {
+			return fmt.Errorf("Rule %d: `ports` is not a valid port number, range or list", i)
+		}
+		if rule.Ports == "" && (rule.Protocol == "tcp" || rule.Protocol == "udp") {
+			return fmt.Errorf("Rule %d: `ports` must be given for %s rules", i, rule.Protocol)
+		}
+		if rule.ICMPType != nil && !IsICMPControl(*rule.ICMPType) {
+			return fmt.Errorf("Rule %d: `icmp_type` is not a valid ICMP type", i)
+		}
+		if rule.ICMPCode != nil && !IsICMPControl(*rule.ICMPCode) {
+			return fmt.Errorf("Rule %d: `icmp_code` is not a valid ICMP code", i)
+		}
+	}
+	return nil
+}
+
+//Rule returns only the first of the configured rules. Use Rules to get all of
+// them.
+func (s Static) Rule(creds map[string]interface{}) (rule SecGroupRule, err error) {
+	rules, err := s.Rules(creds)
+	if err != nil {
+		return rule, err
+	}
+	return rules[0], nil
+}
+
+//Rules returns a cf security group rule for each of the configured rules. The
+// credentials are ignored.
+func (s Static) Rules(creds map[string]interface{}) (rules []SecGroupRule, err error) {
+	if err = s.Verify(); err != nil {
+		return nil, err
+	}
+	for _, rule := range s.StaticRules {
+		rules = append(rules, SecGroupRule{
+			Protocol:    rule.Protocol,
+			Destination: rule.Destination,
+			Ports:       rule.Ports,
+			Type:        rule.ICMPType,
+			Code:        rule.ICMPCode,
+		})
+	}
+	return rules, nil
+}
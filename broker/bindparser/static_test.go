@@ -0,0 +1,200 @@
+package bindparser_test
+
+import (
+	. "github.com/cloudfoundry-community/portcullis/broker/bindparser"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Static", func() {
+	var testStatic Static
+	var err error
+	BeforeEach(func() {
+		testStatic = Static{
+			StaticRules: []StaticRule{
+				{Destination: "10.1.0.0/24", Ports: "5000-5010"},
+				{Destination: "10.0.0.5", Ports: "53", Protocol: "udp"},
+			},
+		}
+	})
+
+	Describe("Verify", func() {
+		JustBeforeEach(func() {
+			err = testStatic.Verify()
+		})
+
+		Context("With valid rules", func() {
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("With no rules", func() {
+			BeforeEach(func() {
+				testStatic.StaticRules = nil
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("With a rule that has a bad destination", func() {
+			BeforeEach(func() {
+				testStatic.StaticRules[0].Destination = "10.1.0.0/33"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("With a rule that has bad ports", func() {
+			BeforeEach(func() {
+				testStatic.StaticRules[1].Ports = "5010-5000"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("With a rule that has a bad protocol", func() {
+			BeforeEach(func() {
+				testStatic.StaticRules[1].Protocol = "sctp"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Rules", func() {
+		var testRules []SecGroupRule
+		JustBeforeEach(func() {
+			testRules, err = testStatic.Rules(nil)
+		})
+
+		It("should not return an error, even without credentials", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should return the configured rules, leaving unset protocols to the defaults", func() {
+			Expect(testRules).To(Equal([]SecGroupRule{
+				{Destination: "10.1.0.0/24", Ports: "5000-5010"},
+				{Protocol: "udp", Destination: "10.0.0.5", Ports: "53"},
+			}))
+		})
+	})
+
+	Describe("Creating from a bind config", func() {
+		var testFlavor Flavor
+		JustBeforeEach(func() {
+			testFlavor, err = Config{
+				FlavorName: "static",
+				Config: map[string]interface{}{
+					"rules": []interface{}{
+						map[string]interface{}{"destination": "10.0.0.1-10.0.0.9", "ports": "80,443"},
+					},
+				},
+			}.CreateFlavor()
+		})
+
+		It("should not return an error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should read the rules out of the config", func() {
+			Expect(testFlavor).To(Equal(&Static{
+				StaticRules: []StaticRule{{Destination: "10.0.0.1-10.0.0.9", Ports: "80,443"}},
+			}))
+		})
+	})
+})
+
+var _ = Describe("VerifyNoHostnames", func() {
+	var testConfigs ConfigList
+	var err error
+	BeforeEach(func() {
+		testConfigs = ConfigList{
+			{FlavorName: "dummy"},
+			{
+				FlavorName: "static",
+				Config: map[string]interface{}{
+					"rules": []interface{}{
+						map[string]interface{}{"destination": "10.0.0.5", "ports": "9100"},
+					},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		err = testConfigs.VerifyNoHostnames()
+	})
+
+	Context("When the static rules only have addresses", func() {
+		It("should not return an error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When a static rule has a hostname destination", func() {
+		BeforeEach(func() {
+			testConfigs[1].Config["rules"] = []interface{}{
+				map[string]interface{}{"destination": "db.example.com", "ports": "5432"},
+			}
+		})
+
+		It("should return an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("Verifying a static bind config", func() {
+	var testConfig Config
+	var err error
+	BeforeEach(func() {
+		testConfig = Config{
+			FlavorName: "static",
+			Config: map[string]interface{}{
+				"rules": []interface{}{
+					map[string]interface{}{"destination": "10.0.0.5"},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		err = testConfig.VerifyFlavor()
+	})
+
+	Context("When a rule has no ports and no protocol", func() {
+		It("should return an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+
+		Context("When the defaults give a protocol that takes ports", func() {
+			BeforeEach(func() {
+				testConfig.Defaults.Protocol = "udp"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When the defaults give a protocol without ports", func() {
+			BeforeEach(func() {
+				testConfig.Defaults.Protocol = "icmp"
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+	})
+})
@@ -1,20 +1,198 @@
 package bindparser
 
-import "regexp"
+import (
+	"bytes"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
 
-//IsIPAddress returns true if the given value is a valid IP address. False otherwise.
+//IsIPAddress returns true if the given value is a valid IPv4 or IPv6 address.
+// False otherwise.
 func IsIPAddress(host string) bool {
-	//I don't know if CF supports leading 0s for octets, so... banning them here
-	const zeroTo255 = `(25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])`
-	const ipAddrRegex = `\A(` + zeroTo255 + `\.){3}` + zeroTo255 + `\z`
-	matched, err := regexp.MatchString(ipAddrRegex, host)
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	//I don't know if CF supports leading 0s for octets, so... banning them here.
+	// IPv6 groups can have leading 0s, so only dotted IPv4 parts are checked.
+	if ip.To4() != nil {
+		for _, octet := range strings.Split(host, ".") {
+			if len(octet) > 1 && octet[0] == '0' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//IsCIDR returns true if the given value is a valid CIDR block, such as
+// `10.1.0.0/24`. False otherwise. Blocks with a prefix length of 0, such as
+// `0.0.0.0/0`, are rejected, because they would let the credentials from a
+// broker open egress to everywhere.
+func IsCIDR(block string) bool {
+	slash := strings.Index(block, "/")
+	if slash < 0 || !IsIPAddress(block[:slash]) {
+		return false
+	}
+	_, ipNet, err := net.ParseCIDR(block)
 	if err != nil {
-		panic("isIPAddress has invalid regexp")
+		return false
+	}
+	ones, _ := ipNet.Mask.Size()
+	return ones > 0
+}
+
+//IsIPRange returns true if the given value is a CF style range of addresses,
+// such as `10.0.0.1-10.0.0.9`. Both ends must be the same kind of address, and
+// the range must not go backwards. False otherwise.
+func IsIPRange(ipRange string) bool {
+	ends := strings.Split(ipRange, "-")
+	if len(ends) != 2 || !IsIPAddress(ends[0]) || !IsIPAddress(ends[1]) {
+		return false
+	}
+	first, last := net.ParseIP(ends[0]), net.ParseIP(ends[1])
+	if (first.To4() == nil) != (last.To4() == nil) {
+		return false
+	}
+	return bytes.Compare(first.To16(), last.To16()) <= 0
+}
+
+//IsDestination returns true if the given value is something that CF accepts as
+// the destination of a security group rule: an IP address, a CIDR block, or a
+// range of IP addresses. False otherwise.
+func IsDestination(dest string) bool {
+	return IsIPAddress(dest) || IsCIDR(dest) || IsIPRange(dest)
+}
+
+//The narrowest prefix lengths that a destination from broker credentials may be
+// as broad as, unless the bind config allows broad destinations
+const (
+	minCredentialPrefixV4 = 8
+	minCredentialPrefixV6 = 32
+)
+
+//IsBroadDestination returns true if the given value is a CIDR block or range of
+// addresses that covers more than a /8 of IPv4 addresses, or more than a /32 of
+// IPv6 addresses. Credentials from a broker shouldn't be able to open egress
+// that widely unless the bind config says they may. False otherwise.
+func IsBroadDestination(dest string) bool {
+	var ones, bits int
+	switch {
+	case IsCIDR(dest):
+		_, ipNet, _ := net.ParseCIDR(dest)
+		ones, bits = ipNet.Mask.Size()
+	case IsIPRange(dest):
+		//A range is as broad as the block of the leading bits its ends share
+		ends := strings.Split(dest, "-")
+		first, last := net.ParseIP(ends[0]), net.ParseIP(ends[1])
+		if first.To4() != nil {
+			first, last = first.To4(), last.To4()
+		}
+		ones, bits = commonPrefixLen(first, last), len(first)*8
+	default:
+		return false
+	}
+	if bits == 32 {
+		return ones < minCredentialPrefixV4
+	}
+	return ones < minCredentialPrefixV6
+}
+
+//commonPrefixLen returns the number of leading bits that two addresses of the
+// same length have in common
+func commonPrefixLen(a, b net.IP) int {
+	for i := range a {
+		if diff := a[i] ^ b[i]; diff != 0 {
+			ones := i * 8
+			for mask := byte(0x80); diff&mask == 0; mask >>= 1 {
+				ones++
+			}
+			return ones
+		}
 	}
-	return matched
+	return len(a) * 8
 }
 
 //IsPort returns true if the given value is a valid port number. False otherwise.
 func IsPort(port int) bool {
 	return port > 0 && port <= 65535
 }
+
+//IsPorts returns true if the given value is something CF accepts as the ports
+// of a security group rule: a comma separated list of single ports and ranges
+// of ports, such as `80`, `5000-5010`, `80,443`, or `4000-5000,9142`. False
+// otherwise.
+func IsPorts(ports string) bool {
+	for _, item := range strings.Split(ports, ",") {
+		if bounds := strings.Split(item, "-"); len(bounds) == 2 {
+			low, lowErr := strconv.Atoi(bounds[0])
+			high, highErr := strconv.Atoi(bounds[1])
+			if lowErr != nil || highErr != nil || !IsPort(low) || !IsPort(high) || low > high {
+				return false
+			}
+			continue
+		}
+
+		port, err := strconv.Atoi(item)
+		if err != nil || !IsPort(port) {
+			return false
+		}
+	}
+	return true
+}
+
+//portsFromValue turns a value from the credentials JSON, which may be a number
+// or a string, into the ports of a security group rule
+func portsFromValue(value interface{}) (ports string, ok bool) {
+	switch v := value.(type) {
+	case float64:
+		if v != float64(int(v)) || !IsPort(int(v)) {
+			return "", false
+		}
+		return strconv.Itoa(int(v)), true
+	case string:
+		ports = strings.Replace(v, " ", "", -1)
+		return ports, IsPorts(ports)
+	}
+	return "", false
+}
+
+//IsProtocol returns true if the given value is a protocol that CF security
+// group rules accept. False otherwise.
+func IsProtocol(protocol string) bool {
+	switch protocol {
+	case "tcp", "udp", "icmp", "all":
+		return true
+	}
+	return false
+}
+
+//IsICMPControl returns true if the given value is something CF accepts as the
+// ICMP type or code of a security group rule. -1 means all types or codes.
+// False otherwise.
+func IsICMPControl(value int) bool {
+	return value >= -1 && value <= 255
+}
+
+//IsHostname returns true if the given value is a valid DNS hostname. False
+// otherwise. Names whose last label is entirely numeric are rejected, so that
+// malformed IP addresses aren't mistaken for hostnames.
+func IsHostname(host string) bool {
+	const labelRegex = `[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?`
+	const hostnameRegex = `\A(` + labelRegex + `\.)*` + labelRegex + `\.?\z`
+	if len(host) > 253 {
+		return false
+	}
+	matched, err := regexp.MatchString(hostnameRegex, host)
+	if err != nil {
+		panic("IsHostname has invalid regexp")
+	}
+	if !matched {
+		return false
+	}
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	last := labels[len(labels)-1]
+	return strings.Trim(last, "0123456789") != ""
+}
@@ -49,6 +49,34 @@ var _ = Describe("Utils", func() {
 				})
 				assertTrue()
 			})
+
+			Context("With an IPv6 address", func() {
+				BeforeEach(func() {
+					testAddress = "2001:db8::68"
+				})
+				assertTrue()
+			})
+
+			Context("With an IPv4-mapped IPv6 address", func() {
+				BeforeEach(func() {
+					testAddress = "::ffff:10.0.0.1"
+				})
+				assertTrue()
+			})
+
+			Context("With an IPv6 address with zero groups", func() {
+				BeforeEach(func() {
+					testAddress = "0:0:0:0:0:0:0:1"
+				})
+				assertTrue()
+			})
+
+			Context("With a compressed IPv6 address starting with zero", func() {
+				BeforeEach(func() {
+					testAddress = "0::1"
+				})
+				assertTrue()
+			})
 		})
 
 		Context("When the address is invalid", func() {
@@ -102,6 +130,80 @@ var _ = Describe("Utils", func() {
 				})
 				assertFalse()
 			})
+			Context("Because an octet has a leading zero", func() {
+				BeforeEach(func() {
+					testAddress = "10.0.0.01"
+				})
+				assertFalse()
+			})
+			Context("Because the IPv6 address is malformed", func() {
+				BeforeEach(func() {
+					testAddress = "2001:db8:::68"
+				})
+				assertFalse()
+			})
+			Context("Because the value is a CIDR block", func() {
+				BeforeEach(func() {
+					testAddress = "10.1.0.0/24"
+				})
+				assertFalse()
+			})
+		})
+	})
+
+	Describe("IsCIDR", func() {
+		It("should return true for valid CIDR blocks", func() {
+			for _, c := range []string{"10.1.0.0/24", "10.0.0.5/32", "2001:db8::/32", "0::1/128"} {
+				Expect(IsCIDR(c)).To(BeTrue(), fmt.Sprintf("Failed on value %s", c))
+			}
+		})
+
+		It("should return false for anything else", func() {
+			for _, c := range []string{"", "10.1.0.0", "10.1.0.0/33", "10.1.0.0/", "010.1.0.0/24", "2001:db8::/129", "db.example.com/24", "0.0.0.0/0", "::/0"} {
+				Expect(IsCIDR(c)).To(BeFalse(), fmt.Sprintf("Failed on value %s", c))
+			}
+		})
+	})
+
+	Describe("IsIPRange", func() {
+		It("should return true for valid ranges", func() {
+			for _, r := range []string{"10.0.0.1-10.0.0.9", "10.0.0.1-10.0.0.1", "2001:db8::1-2001:db8::ff"} {
+				Expect(IsIPRange(r)).To(BeTrue(), fmt.Sprintf("Failed on value %s", r))
+			}
+		})
+
+		It("should return false for anything else", func() {
+			for _, r := range []string{"", "10.0.0.1", "10.0.0.9-10.0.0.1", "10.0.0.1-", "10.0.0.1-2001:db8::1", "10.0.0.1-10.0.0.5-10.0.0.9"} {
+				Expect(IsIPRange(r)).To(BeFalse(), fmt.Sprintf("Failed on value %s", r))
+			}
+		})
+	})
+
+	Describe("IsDestination", func() {
+		It("should return true for addresses, CIDR blocks and ranges", func() {
+			for _, d := range []string{"10.0.0.1", "2001:db8::1", "10.1.0.0/24", "10.0.0.1-10.0.0.9"} {
+				Expect(IsDestination(d)).To(BeTrue(), fmt.Sprintf("Failed on value %s", d))
+			}
+		})
+
+		It("should return false for anything else", func() {
+			for _, d := range []string{"", "db.example.com", "10.0.0.256"} {
+				Expect(IsDestination(d)).To(BeFalse(), fmt.Sprintf("Failed on value %s", d))
+			}
+		})
+	})
+
+	Describe("IsBroadDestination", func() {
+		It("should return true for blocks and ranges broader than a /8, or a /32 for IPv6", func() {
+			for _, d := range []string{"0.0.0.0/1", "10.0.0.0/7", "10.0.0.1-11.0.0.1", "0.0.0.0-255.255.255.255", "2001::/16", "2001:db8::1-2001:db9::1"} {
+				Expect(IsBroadDestination(d)).To(BeTrue(), fmt.Sprintf("Failed on value %s", d))
+			}
+		})
+
+		It("should return false for anything else", func() {
+			for _, d := range []string{"10.0.0.1", "10.0.0.0/8", "10.1.0.0/24", "10.0.0.1-10.255.255.255", "2001:db8::/32", "2001:db8::1-2001:db8::ff", "db.example.com"} {
+				Expect(IsBroadDestination(d)).To(BeFalse(), fmt.Sprintf("Failed on value %s", d))
+			}
 		})
 	})
 
@@ -149,4 +251,60 @@ var _ = Describe("Utils", func() {
 			})
 		})
 	})
+
+	Describe("IsPorts", func() {
+		It("should return true for single ports, ranges and lists", func() {
+			for _, p := range []string{"80", "65535", "5000-5010", "5000-5000", "80,443", "80,443,8080", "4000-5000,9142", "80,5000-5010,443"} {
+				Expect(IsPorts(p)).To(BeTrue(), fmt.Sprintf("Failed on value %s", p))
+			}
+		})
+
+		It("should return false for anything else", func() {
+			for _, p := range []string{"", "0", "65536", "5010-5000", "1-2-3", "80,", ",80", "80-,443", "5010-5000,80", "http"} {
+				Expect(IsPorts(p)).To(BeFalse(), fmt.Sprintf("Failed on value %s", p))
+			}
+		})
+	})
+
+	Describe("IsProtocol", func() {
+		It("should return true for protocols CF accepts", func() {
+			for _, p := range []string{"tcp", "udp", "icmp", "all"} {
+				Expect(IsProtocol(p)).To(BeTrue(), fmt.Sprintf("Failed on value %s", p))
+			}
+		})
+
+		It("should return false for anything else", func() {
+			for _, p := range []string{"", "TCP", "http", "sctp"} {
+				Expect(IsProtocol(p)).To(BeFalse(), fmt.Sprintf("Failed on value %s", p))
+			}
+		})
+	})
+
+	Describe("IsICMPControl", func() {
+		It("should return true from -1 to 255", func() {
+			for i := -1; i <= 255; i++ {
+				Expect(IsICMPControl(i)).To(BeTrue(), fmt.Sprintf("Failed on value %d", i))
+			}
+		})
+
+		It("should return false for anything else", func() {
+			for _, i := range []int{-2, 256} {
+				Expect(IsICMPControl(i)).To(BeFalse(), fmt.Sprintf("Failed on value %d", i))
+			}
+		})
+	})
+
+	Describe("IsHostname", func() {
+		It("should return true for valid hostnames", func() {
+			for _, h := range []string{"localhost", "db.example.com", "redis-1.service.cf.internal", "a.b.c."} {
+				Expect(IsHostname(h)).To(BeTrue(), fmt.Sprintf("Failed on value %s", h))
+			}
+		})
+
+		It("should return false for anything else", func() {
+			for _, h := range []string{"", "10.0.0.256", "1.2.3.4", "-bad.example.com", "under_score.com", "two..dots"} {
+				Expect(IsHostname(h)).To(BeFalse(), fmt.Sprintf("Failed on value %s", h))
+			}
+		})
+	})
 })
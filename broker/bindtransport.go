@@ -6,20 +6,66 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
-	"github.com/pborman/uuid"
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
 	"github.com/starkandwayne/goutils/log"
 )
 
 type BindTransport struct {
-	Flavors bindparser.FlavorList
+	Flavors      bindparser.FlavorList
+	MappingName  string
+	InstanceGUID string
+	BindingGUID  string
+	//ResolveHostnames allows rules with hostname destinations, which are
+	// resolved into addresses before the security group is made
+	ResolveHostnames bool
+	//ServiceKeyPolicy says what to do with bindings that aren't for an app,
+	// such as service keys. One of the config.ServiceKeyPolicy constants.
+	ServiceKeyPolicy string
 }
 
-func (i *BindTransport) RoundTrip(r *http.Request) (*http.Response, error) {
-	//TODO: Probably going to need to do some more graceful error handling
-	// after testing some CF response behaviors
+//bindRequest is the part of an OSBAPI bind request body that Portcullis uses
+type bindRequest struct {
+	ServiceID string `json:"service_id"`
+	PlanID    string `json:"plan_id"`
+	//AppGUID is deprecated by OSBAPI in favor of BindResource.AppGUID, but older
+	// Cloud Controllers only send this
+	AppGUID      string `json:"app_guid"`
+	BindResource struct {
+		AppGUID string `json:"app_guid"`
+		Route   string `json:"route"`
+	} `json:"bind_resource"`
+	Context struct {
+		Platform         string `json:"platform"`
+		SpaceGUID        string `json:"space_guid"`
+		OrganizationGUID string `json:"organization_guid"`
+	} `json:"context"`
+}
+
+//parseBindRequest reads the given bind request body
+func parseBindRequest(reqBody []byte) (ret bindRequest, err error) {
+	err = json.Unmarshal(reqBody, &ret)
+	if err != nil {
+		err = fmt.Errorf("Could not read CF service broker request: %s", err)
+	}
+	return
+}
 
+//appGUID returns the GUID of the app being bound, or the empty string if the
+// binding isn't for an app, as with service keys
+func (b bindRequest) appGUID() string {
+	if b.BindResource.AppGUID != "" {
+		return b.BindResource.AppGUID
+	}
+	return b.AppGUID
+}
+
+func (i *BindTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	//Get a copy of the request body before shipping it off to get read elsewhere
 	reqBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -34,80 +80,279 @@ func (i *BindTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	}
 
 	switch resp.StatusCode {
-	case http.StatusCreated: //fresh new binding
-		//TODO: Skip all of the magic if the binding target doesn't have any rules to make.
-		log.Debugf("BindTransport: StatusCreated")
+	//201 is a fresh new binding, and 200 is a retry of a bind that the broker
+	// already made. Either way, the binding's group needs to be right.
+	case http.StatusCreated, http.StatusOK:
+		log.Debugf("BindTransport: Status %d", resp.StatusCode)
 
 		//Copy of the response body, now
 		credsBody, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
 		if err != nil {
 			return nil, err
 		}
 
 		resp.Body = ioutil.NopCloser(bytes.NewReader(credsBody))
 
-		log.Debugf("BindTransport: credsBody: %s", string(credsBody))
-		//Unmarshal the credentials into something we can use
-		var credsMap = map[string]interface{}{}
-		err = json.Unmarshal(credsBody, &credsMap)
+		err = i.openEgress(reqBody, credsBody)
 		if err != nil {
-			return nil, err
+			//The backend broker has made the binding, but CF is going to be told
+			// that the bind failed, so the binding has to be undone
+			return i.failBind(r, reqBody, err), nil
 		}
 
-		credsInterface, found := credsMap["credentials"]
-		if !found {
-			return nil, fmt.Errorf("No `credentials` key found in response JSON")
-		}
+	case http.StatusAccepted: //the broker is binding asynchronously
+		log.Debugf("BindTransport: StatusAccepted")
 
-		creds, isAMap := credsInterface.(map[string]interface{})
-		if !isAMap {
-			return nil, fmt.Errorf("The `credentials` key in the response JSON was not a hash")
+		//There are no credentials yet, so egress is opened once polling the
+		// binding's last_operation says that the bind succeeded
+		err = store.AddPendingBind(store.PendingBind{
+			BindingGUID:         i.BindingGUID,
+			ServiceInstanceGUID: i.InstanceGUID,
+			MappingName:         i.MappingName,
+			Request:             string(reqBody),
+		})
+		//A retried bind is for the same binding, so the existing record does
+		if err != nil && err != store.ErrDuplicate {
+			resp.Body.Close()
+			return i.failBind(r, reqBody, fmt.Errorf("Could not record asynchronous bind: %s", err)), nil
 		}
+	}
+	return resp, nil
+}
 
-		//Get the security group rules to add
-		rules, err := i.Flavors.Rules(creds)
-		if err != nil {
-			return nil, err
+//openEgress makes the security group rules for a binding out of the bind
+// request and the credentials that the backend broker responded with
+func (i *BindTransport) openEgress(reqBody, credsBody []byte) error {
+	bindReq, err := parseBindRequest(reqBody)
+	if err != nil {
+		return err
+	}
+
+	appGUID := bindReq.appGUID()
+	if appGUID == "" {
+		log.Debugf("BindTransport: Binding %s is not for an app", i.BindingGUID)
+		switch i.ServiceKeyPolicy {
+		case config.ServiceKeyPolicyReject:
+			return brokerAPIError{
+				statusCode:  http.StatusUnprocessableEntity,
+				code:        errorRequiresApp,
+				description: fmt.Sprintf("Bindings that are not for an app are not allowed for mapping `%s`", i.MappingName),
+			}
+		case config.ServiceKeyPolicySpace:
+			if bindReq.Context.SpaceGUID == "" {
+				return brokerAPIError{
+					statusCode:  http.StatusUnprocessableEntity,
+					code:        errorRequiresApp,
+					description: "The binding is not for an app, and the CF service broker request has no context.space_guid",
+				}
+			}
+		default:
+			//Nothing in CF is going to use the credentials, so there's nothing
+			// to open egress for
+			return nil
 		}
+	}
+
+	log.Debugf("BindTransport: credsBody: %s", string(credsBody))
+	//Unmarshal the credentials into something we can use
+	var credsMap = map[string]interface{}{}
+	err = json.Unmarshal(credsBody, &credsMap)
+	if err != nil {
+		return err
+	}
 
-		log.Debugf("BindTransport: rules %s", rules)
+	credsInterface, found := credsMap["credentials"]
+	if !found {
+		return fmt.Errorf("No `credentials` key found in response JSON")
+	}
 
-		//Let's turn the request body JSON into a map we can use
-		var requestMap = map[string]interface{}{}
-		err = json.Unmarshal(reqBody, &requestMap)
-		if err != nil {
-			return nil, err
-		}
-		log.Debugf("BindTransport: RequestBody is a map")
-
-		//Is there an app_guid key?
-		var appGUIDInterface interface{}
-		if appGUIDInterface, found = requestMap["app_guid"]; !found {
-			//When the "skip with no rules" stuff is implemented, this should return
-			// a 422 response with an appropriate body back to CF.
-			return nil, fmt.Errorf("app_guid was not found in CF service broker request")
-		}
+	creds, isAMap := credsInterface.(map[string]interface{})
+	if !isAMap {
+		return fmt.Errorf("The `credentials` key in the response JSON was not a hash")
+	}
 
-		log.Debugf("BindTransport: app_guid exists")
+	rules, sourceRules, err := i.egressRules(creds)
+	if err != nil {
+		return err
+	}
 
-		//Is the app_guid a string like it should be?
-		appGUID, isAString := appGUIDInterface.(string)
-		if !isAString {
-			return nil, fmt.Errorf("app_guid in CF service broker request was not of type string")
-		}
+	//Nothing to open, so there's no need to know where the app lives
+	if len(rules) == 0 {
+		log.Debugf("BindTransport: No rules to make for binding %s", i.BindingGUID)
+		return nil
+	}
 
-		log.Debugf("BindTransport: app_guid is %s", appGUID)
+	//Modern Cloud Controllers say where the binding is being made, which saves
+	// asking CF where the app lives. Bindings that aren't for an app only get
+	// this far if they came with a space.
+	spaceGUID, orgGUID := bindReq.Context.SpaceGUID, bindReq.Context.OrganizationGUID
+	if spaceGUID == "" {
+		log.Debugf("BindTransport: No context.space_guid, so looking up app %s", appGUID)
 
-		//Let's get the space GUID by looking up the app GUID in CF
 		appInfo, err := client.AppByGuid(appGUID)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		spaceGUID = appInfo.SpaceData.Entity.Guid
+		orgGUID = appInfo.SpaceData.Entity.OrgData.Entity.Guid
+	}
 
-		_, err = client.CreateSecGroup(fmt.Sprintf("portcullis-%s", uuid.New()), rules, []string{appInfo.SpaceData.Entity.Guid})
-		if err != nil {
-			return nil, err
+	log.Debugf("BindTransport: Binding %s is in space %s of org %s", i.BindingGUID, spaceGUID, orgGUID)
+
+	//Bindings to the same instance from the same space share a group
+	return addBindingSecGroup(store.BindingInfo{
+		BindingGUID:         i.BindingGUID,
+		ServiceInstanceGUID: i.InstanceGUID,
+		AppGUID:             appGUID,
+		SpaceGUID:           spaceGUID,
+		OrganizationGUID:    orgGUID,
+		Rules:               rules,
+		SourceRules:         sourceRules,
+	}, i.MappingName)
+}
+
+//egressRules makes the security group rules for the given bind credentials with
+// the transport's flavors. CF security groups only take addresses, so hostnames
+// are resolved first, and the unresolved rules are returned as sourceRules so
+// that they can be resolved again if the addresses behind them change.
+func (i *BindTransport) egressRules(creds map[string]interface{}) (rules, sourceRules []bindparser.SecGroupRule, err error) {
+	rules, err = i.Flavors.Rules(creds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Debugf("BindTransport: rules %s", rules)
+
+	if len(rules) == 0 || !bindparser.HasHostnames(rules) {
+		return rules, nil, nil
+	}
+
+	if !i.ResolveHostnames {
+		return nil, nil, fmt.Errorf("The bind credentials name a hostname, but hostname resolution is not enabled for mapping `%s`", i.MappingName)
+	}
+	sourceRules = rules
+	rules, err = bindparser.ResolveRules(sourceRules)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Debugf("BindTransport: resolved rules %s", rules)
+	return rules, sourceRules, nil
+}
+
+//failBind undoes a binding that egress couldn't be opened for, and returns the
+// error response to give to the Cloud Controller in place of the backend
+// broker's response
+func (i *BindTransport) failBind(r *http.Request, reqBody []byte, bindErr error) *http.Response {
+	i.undoBind(r, reqBody, bindErr)
+	if apiErr, isAPIErr := bindErr.(brokerAPIError); isAPIErr {
+		return errorResponse(r, apiErr.statusCode, apiErr.code, apiErr.description)
+	}
+	return errorResponse(r, http.StatusInternalServerError, "",
+		fmt.Sprintf("Portcullis could not open egress for the binding: %s", bindErr))
+}
+
+//undoBind asks the backend broker to undo a binding that egress couldn't be
+// opened for, and records the failure in the store. The given request can be
+// any request to the backend broker for the binding.
+func (i *BindTransport) undoBind(r *http.Request, reqBody []byte, bindErr error) {
+	log.Errorf("BindTransport: Could not open egress for binding %s: %s", i.BindingGUID, bindErr)
+
+	failure := store.BindFailure{
+		BindingGUID:         i.BindingGUID,
+		ServiceInstanceGUID: i.InstanceGUID,
+		MappingName:         i.MappingName,
+		Time:                time.Now().UTC(),
+		Error:               bindErr.Error(),
+	}
+
+	err := compensatingUnbind(r, reqBody)
+	if err != nil {
+		log.Errorf("BindTransport: Could not undo binding %s with the backend broker: %s", i.BindingGUID, err)
+		failure.UnbindError = err.Error()
+	} else {
+		log.Infof("BindTransport: Undid binding %s with the backend broker", i.BindingGUID)
+		failure.Unbound = true
+	}
+
+	if err := store.AddBindFailure(failure); err != nil {
+		log.Errorf("BindTransport: Could not record failure of binding %s: %s", i.BindingGUID, err)
+	}
+}
+
+//compensatingUnbind sends the backend broker an unbind for the binding that the
+// given bind request made
+func compensatingUnbind(r *http.Request, reqBody []byte) error {
+	bindBody, err := parseBindRequest(reqBody)
+	if err != nil {
+		return err
+	}
+
+	unbindReq, err := bindingRequest(r, "DELETE", bindBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultTransport.RoundTrip(unbindReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusGone:
+		return nil
+	}
+	return fmt.Errorf("Backend broker returned with status code %d", resp.StatusCode)
+}
+
+//bindingRequest makes a request to the backend broker for the binding that the
+// given request is about, which may be for the binding or its last_operation.
+// The broker credentials and API version are carried over from that request.
+func bindingRequest(r *http.Request, method string, bindBody bindRequest) (*http.Request, error) {
+	bindingURL := *r.URL
+	bindingURL.Path = strings.TrimSuffix(bindingURL.Path, "/last_operation")
+	bindingURL.RawQuery = url.Values{
+		"service_id": {bindBody.ServiceID},
+		"plan_id":    {bindBody.PlanID},
+	}.Encode()
+
+	ret, err := http.NewRequest(method, bindingURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range []string{"Authorization", "X-Broker-Api-Version", "X-Broker-Api-Originating-Identity"} {
+		if value := r.Header.Get(header); value != "" {
+			ret.Header.Set(header, value)
 		}
 	}
-	return resp, err
+	return ret, nil
+}
+
+//errorResponse makes a response with an OSBAPI error body, for giving back to
+// the Cloud Controller from an http.RoundTripper
+func errorResponse(r *http.Request, statusCode int, code, description string) *http.Response {
+	return bodyResponse(r, statusCode, errorify(code, description))
+}
+
+//jsonResponse makes a response with the given value as its JSON body, for
+// giving back to the Cloud Controller from an http.RoundTripper
+func jsonResponse(r *http.Request, statusCode int, value interface{}) *http.Response {
+	body, _ := json.Marshal(value)
+	return bodyResponse(r, statusCode, body)
+}
+
+func bodyResponse(r *http.Request, statusCode int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}
 }
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httputil"
+	"time"
 
 	"github.com/cloudfoundry-community/go-cfclient"
 	"github.com/cloudfoundry-community/portcullis/config"
@@ -15,6 +17,9 @@ var (
 	port   int
 	router *mux.Router
 	client *cfclient.Client
+	//serviceKeyPolicy is what BindTransport does with bindings that aren't for
+	// an app
+	serviceKeyPolicy string
 )
 
 //Initialize sets up the state of the Broker API to be ready to listen for
@@ -27,6 +32,11 @@ func Initialize(conf config.BrokerConfig) (err error) {
 		return
 	}
 	port = conf.Port
+	resolveInterval = time.Duration(conf.ResolveInterval) * time.Second
+	reconcileInterval = time.Duration(conf.ReconcileInterval) * time.Second
+	reconcileDryRun = conf.ReconcileDryRun
+	serviceKeyPolicy = conf.ServiceKeyPolicy
+	publicURL = conf.PublicURL
 
 	if conf.CFAPIAddress == "" {
 		err = fmt.Errorf("`broker.cf_api_address` is not a valid value in config")
@@ -57,14 +67,20 @@ func Initialize(conf config.BrokerConfig) (err error) {
 		return fmt.Errorf("Error when initially checking CF connection: %s", err.Error())
 	}
 
+	//These are all of the routes in the Open Service Broker API 2.x spec. Only
+	// the ones to do with bindings need anything more than a passthrough.
 	router = mux.NewRouter()
 	router.HandleFunc("/{broker}/v2/catalog", Passthrough).Methods("GET")
 	router.HandleFunc("/{broker}/v2/service_instances/{id}/last_operation", Passthrough).Methods("GET")
-	router.HandleFunc("/{broker}/v2/service_instances/{id}", Passthrough).Methods("PUT", "PATCH", "DELETE")
+	router.HandleFunc("/{broker}/v2/service_instances/{id}", Passthrough).Methods("GET", "PUT", "PATCH", "DELETE")
+	//Fetch service binding
+	router.HandleFunc("/{broker}/v2/service_instances/{inst_id}/service_bindings/{bind_id}", Passthrough).Methods("GET")
+	//Poll an asynchronous bind
+	router.HandleFunc("/{broker}/v2/service_instances/{inst_id}/service_bindings/{bind_id}/last_operation", BindingLastOperation).Methods("GET")
 	//Bind service instance
 	router.HandleFunc("/{broker}/v2/service_instances/{inst_id}/service_bindings/{bind_id}", BindService).Methods("PUT")
 	//Unbind service instance
-	router.HandleFunc("/{broker}/v2/service_instances/{inst_id}/service_bindings/{bind_id}", Passthrough).Methods("DELETE")
+	router.HandleFunc("/{broker}/v2/service_instances/{inst_id}/service_bindings/{bind_id}", UnbindService).Methods("DELETE")
 
 	router.NotFoundHandler = brokerNotFoundHandler{}
 
@@ -100,24 +116,78 @@ func Launch(e chan<- error) {
 	return
 }
 
+//Error codes that the Open Service Broker API gives special meaning to. They
+// go in the `error` key of an error response.
+const (
+	//errorRequiresApp means that the bind has to be for an app
+	errorRequiresApp = "RequiresApp"
+)
+
 type brokerError struct {
+	Error       string `json:"error,omitempty"`
 	Description string `json:"description"`
 }
 
-func errorify(desc string) (body []byte) {
+//brokerAPIError is an error that should be given to the Cloud Controller with
+// the given status code and OSBAPI error code, rather than as a 500
+type brokerAPIError struct {
+	statusCode  int
+	code        string
+	description string
+}
+
+func (e brokerAPIError) Error() string {
+	return e.description
+}
+
+func errorify(code, desc string) (body []byte) {
 	var err error
-	body, err = json.Marshal(brokerError{Description: desc})
+	body, err = json.Marshal(brokerError{Error: code, Description: desc})
 	if err != nil {
 		//This API facing panic makes me uneasy. May switch to logging an error
 		// at a later time and returning a pre-baked response
-		panic(fmt.Sprintf("Could not marshal response in Broker: %+v", brokerError{Description: desc}))
+		panic(fmt.Sprintf("Could not marshal response in Broker: %+v", brokerError{Error: code, Description: desc}))
 	}
 	return
 }
 
+//writeError writes an OSBAPI error response with the given status code, error
+// code and description. The error code can be empty.
+func writeError(w http.ResponseWriter, statusCode int, code, desc string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(errorify(code, desc))
+}
+
+//proxyErrorTransport wraps the transport of every proxy to a backend broker,
+// and answers with an OSBAPI error when the backend broker couldn't be talked
+// to. Left alone, the proxy would answer with an empty 500.
+type proxyErrorTransport struct {
+	inner http.RoundTripper
+}
+
+func (p proxyErrorTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := p.inner.RoundTrip(r)
+	if err != nil {
+		log.Errorf("Could not proxy request to %s: %s", r.URL, err)
+		return errorResponse(r, http.StatusBadGateway, "", fmt.Sprintf("Portcullis: Could not contact backend broker: %s", err)), nil
+	}
+	return resp, nil
+}
+
+//serveProxy passes the request through the proxy to the backend broker, with
+// whichever transport the handler gave the proxy
+func serveProxy(proxy *httputil.ReverseProxy, w http.ResponseWriter, r *http.Request) {
+	inner := proxy.Transport
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	proxy.Transport = proxyErrorTransport{inner: inner}
+	proxy.ServeHTTP(w, r)
+}
+
 type brokerNotFoundHandler struct{}
 
 func (b brokerNotFoundHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusNotFound)
-	w.Write(errorify(fmt.Sprintf("Unrecognized route: %s", r.URL)))
+	writeError(w, http.StatusNotFound, "", fmt.Sprintf("Unrecognized route: %s", r.URL))
 }
@@ -0,0 +1,332 @@
+package broker_test
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/starkandwayne/goutils/log"
+
+	"testing"
+
+	"github.com/cloudfoundry-community/portcullis/broker"
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+	_ "github.com/cloudfoundry-community/portcullis/store/dummy"
+	"github.com/cloudfoundry-community/portcullis/testutil"
+)
+
+func TestBroker(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Broker Suite")
+}
+
+var _ = BeforeSuite(func() {
+	//Squelch the logging
+	log.SetupLogging(log.LogConfig{Type: "console", Level: "emerg"})
+	// log.SetupLogging(log.LogConfig{Type: "console", Level: "debug"})
+
+	err := store.SetStoreType("dummy")
+	Expect(err).NotTo(HaveOccurred())
+	err = store.Initialize(map[string]interface{}{
+		"confirm": true,
+	})
+	Expect(err).NotTo(HaveOccurred())
+})
+
+//Randomly generated alphanumeric string of length between 8 and 22 characters, inclusive
+func genRandomString() string {
+	const numDigits = byte(10)
+	const numLetters = byte(26)
+	const digitOffset = byte(48)
+	const upperOffset = byte(65)
+	const lowerOffset = byte(97)
+	length := (rand.Int() % 15) + 8
+	var ret []byte
+	for i := 0; i < length; i++ {
+		c := byte(rand.Int()) % (numDigits + (numLetters * 2))
+		switch {
+		case c < numDigits: //add digit
+			ret = append(ret, c+digitOffset)
+		case c < numDigits+numLetters: //add uppercase letter
+			ret = append(ret, c+upperOffset-numDigits)
+		default: //add lowercase letter
+			ret = append(ret, c+lowerOffset-(numLetters+numDigits))
+		}
+	}
+	return string(ret)
+}
+
+//addTestMapping puts a mapping to the backend broker at the given location into
+// the store, with a bind config that opens egress to the `host` and `port` of
+// the bind credentials. Returns the name of the mapping.
+func addTestMapping(location string) string {
+	name := genRandomString()
+	Expect(store.AddMapping(store.Mapping{
+		Name:     name,
+		Location: location,
+		BindConfig: bindparser.ConfigList{
+			{
+				FlavorName: "dummy",
+				Config: map[string]interface{}{
+					"confirm": true,
+				},
+			},
+		},
+	})).To(Succeed())
+	return name
+}
+
+//clearStore forgets everything that the specs put in the store
+func clearStore() {
+	store.ClearMappings()
+	store.ClearBindingInfo()
+	store.ClearSecGroupInfo()
+	store.ClearPendingBinds()
+	store.ClearBindFailures()
+}
+
+func readJSONResponse(testResponse *httptest.ResponseRecorder) map[string]interface{} {
+	ret := make(map[string]interface{})
+	err := json.Unmarshal(testResponse.Body.Bytes(), &ret)
+	Expect(err).NotTo(HaveOccurred(),
+		"JSON couldn't be unmarshalled: "+testResponse.Body.String())
+	return ret
+}
+
+//stubCF is the shared stand-in for the CF API, with helpers for the broker
+// specs
+type stubCF struct {
+	*testutil.StubCF
+}
+
+const (
+	appsPath            = testutil.AppsPath
+	serviceBindingsPath = testutil.ServiceBindingsPath
+	secGroupsPath       = testutil.SecGroupsPath
+)
+
+func newStubCF() *stubCF {
+	return &stubCF{testutil.NewStubCF()}
+}
+
+//addApp puts an app into a space of an org. Returns the GUID of the app.
+func (cf *stubCF) addApp(spaceGUID, orgGUID string) string {
+	cf.Lock()
+	defer cf.Unlock()
+
+	appGUID := genRandomString()
+	cf.Lists[appsPath][appGUID] = map[string]interface{}{
+		"space_guid": spaceGUID,
+		//The inlined space that cfclient asks for
+		"space": map[string]interface{}{
+			"metadata": map[string]interface{}{"guid": spaceGUID},
+			"entity": map[string]interface{}{
+				"organization": map[string]interface{}{
+					"metadata": map[string]interface{}{"guid": orgGUID},
+				},
+			},
+		},
+	}
+	return appGUID
+}
+
+//addServiceBinding makes CF know about the binding with the given GUID
+func (cf *stubCF) addServiceBinding(bindingGUID string) {
+	cf.Lock()
+	defer cf.Unlock()
+	cf.Lists[serviceBindingsPath][bindingGUID] = map[string]interface{}{}
+}
+
+//addSecGroup puts a security group with the given name into CF, bound to the
+// given space. Returns the GUID of the group.
+func (cf *stubCF) addSecGroup(name, spaceGUID string) string {
+	cf.Lock()
+	defer cf.Unlock()
+
+	guid := genRandomString()
+	cf.Lists[secGroupsPath][guid] = map[string]interface{}{
+		"name":        name,
+		"rules":       []interface{}{},
+		"space_guids": []interface{}{spaceGUID},
+	}
+	return guid
+}
+
+//clearSecGroups deletes every security group from CF behind Portcullis's back
+func (cf *stubCF) clearSecGroups() {
+	cf.Lock()
+	defer cf.Unlock()
+	cf.Lists[secGroupsPath] = map[string]map[string]interface{}{}
+}
+
+//secGroups returns the security group entities that the stub has, by name
+func (cf *stubCF) secGroups() map[string]map[string]interface{} {
+	cf.Lock()
+	defer cf.Unlock()
+	ret := map[string]map[string]interface{}{}
+	for _, entity := range cf.Lists[secGroupsPath] {
+		ret[entity["name"].(string)] = entity
+	}
+	return ret
+}
+
+//destinations returns the destinations of the rules of the given security
+// group entity
+func destinations(secGroup map[string]interface{}) []string {
+	ret := []string{}
+	rules, _ := secGroup["rules"].([]interface{})
+	for _, rule := range rules {
+		ret = append(ret, rule.(map[string]interface{})["destination"].(string))
+	}
+	return ret
+}
+
+//connectBroker points the broker package at the stub, with the given policy
+// for bindings that aren't for an app
+func (cf *stubCF) connectBroker(serviceKeyPolicy string) {
+	Expect(broker.Initialize(config.BrokerConfig{
+		Port:             5591,
+		CFAPIAddress:     cf.Server.URL,
+		CFAdmin:          "admin",
+		CFPassword:       "admin",
+		ServiceKeyPolicy: serviceKeyPolicy,
+	})).To(Succeed())
+}
+
+func (cf *stubCF) close() {
+	cf.Close()
+}
+
+//stubBackend is a stand-in for a backend service broker, which answers requests
+// about bindings as it is told to, and remembers what it was asked
+type stubBackend struct {
+	server *httptest.Server
+	lock   sync.Mutex
+	//bindStatus is the status code that binds are answered with. 202 makes the
+	// bind asynchronous.
+	bindStatus int
+	//unbindStatus is the status code that unbinds are answered with
+	unbindStatus int
+	//lastOpState is the state that last_operation polls are answered with
+	lastOpState string
+	//credentials are given out for every binding
+	credentials map[string]interface{}
+	//requests are the method, path and query of every request the stub has had
+	requests []string
+}
+
+func newStubBackend() *stubBackend {
+	b := &stubBackend{
+		bindStatus:   http.StatusCreated,
+		unbindStatus: http.StatusOK,
+		lastOpState:  "in progress",
+		credentials: map[string]interface{}{
+			"host": "10.0.0.5",
+			"port": 6379,
+		},
+	}
+	b.server = httptest.NewServer(http.HandlerFunc(b.serveHTTP))
+	return b
+}
+
+func (b *stubBackend) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	request := r.Method + " " + r.URL.Path
+	if r.URL.RawQuery != "" {
+		request += "?" + r.URL.RawQuery
+	}
+	b.requests = append(b.requests, request)
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON := func(code int, value interface{}) {
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(value)
+	}
+
+	switch {
+	case r.URL.Path == "/v2/catalog":
+		writeJSON(http.StatusOK, map[string]interface{}{"services": []interface{}{}})
+	case strings.HasSuffix(r.URL.Path, "/last_operation"):
+		writeJSON(http.StatusOK, map[string]string{"state": b.lastOpState})
+	case !strings.Contains(r.URL.Path, "/service_bindings/"):
+		writeJSON(http.StatusNotFound, map[string]string{"description": "Unknown request"})
+	case r.Method == "PUT" && b.bindStatus == http.StatusAccepted:
+		writeJSON(b.bindStatus, map[string]string{"operation": "binding"})
+	case r.Method == "PUT":
+		writeJSON(b.bindStatus, map[string]interface{}{"credentials": b.credentials})
+	case r.Method == "GET":
+		writeJSON(http.StatusOK, map[string]interface{}{"credentials": b.credentials})
+	case r.Method == "DELETE":
+		writeJSON(b.unbindStatus, map[string]interface{}{})
+	}
+}
+
+//received returns the requests that the stub has had that start with the given
+// method and path
+func (b *stubBackend) received(prefix string) []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ret := []string{}
+	for _, request := range b.requests {
+		if strings.HasPrefix(request, prefix) {
+			ret = append(ret, request)
+		}
+	}
+	return ret
+}
+
+func (b *stubBackend) close() {
+	b.server.Close()
+}
+
+//brokerRequest sends a request with the given JSON body, if it isn't nil, to
+// the broker router. path is relative to the mapping's route.
+func brokerRequest(method, mappingName, path string, body interface{}) *httptest.ResponseRecorder {
+	var reqBody string
+	if body != nil {
+		j, err := json.Marshal(body)
+		Expect(err).NotTo(HaveOccurred())
+		reqBody = string(j)
+	}
+	req := httptest.NewRequest(method, "/"+mappingName+path, strings.NewReader(reqBody))
+	req.Header.Set("X-Broker-Api-Version", "2.13")
+	req.SetBasicAuth("broker-user", "broker-pass")
+	response := httptest.NewRecorder()
+	broker.Router().ServeHTTP(response, req)
+	return response
+}
+
+//bindingPath returns the path of a binding under a mapping's route
+func bindingPath(instanceGUID, bindingGUID string) string {
+	return "/v2/service_instances/" + instanceGUID + "/service_bindings/" + bindingGUID
+}
+
+//bindBody makes the body of a bind request from CF, for an app if appGUID isn't
+// empty, and with a context if spaceGUID isn't empty
+func bindBody(appGUID, spaceGUID, orgGUID string) map[string]interface{} {
+	ret := map[string]interface{}{
+		"service_id": "service-id",
+		"plan_id":    "plan-id",
+	}
+	if appGUID != "" {
+		ret["app_guid"] = appGUID
+		ret["bind_resource"] = map[string]interface{}{"app_guid": appGUID}
+	}
+	if spaceGUID != "" {
+		ret["context"] = map[string]interface{}{
+			"platform":          "cloudfoundry",
+			"space_guid":        spaceGUID,
+			"organization_guid": orgGUID,
+		}
+	}
+	return ret
+}
@@ -0,0 +1,262 @@
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/cloudfoundry-community/go-cfclient"
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
+	"github.com/cloudfoundry-community/portcullis/store"
+	"github.com/starkandwayne/goutils/log"
+)
+
+//Drift describes how a security group in CF differs from what the bindings in
+// the store expect it to be, such as after a CF admin edits it by hand
+type Drift struct {
+	SecGroupName string `json:"secgroup_name"`
+	SecGroupGUID string `json:"secgroup_guid"`
+	//Bindings are the GUIDs of the bindings whose rules are in this group
+	Bindings []string `json:"bindings"`
+	//Missing is true if the group is gone from CF altogether
+	Missing bool `json:"missing"`
+	//MissingRules are expected rules that the group in CF doesn't have
+	MissingRules []bindparser.SecGroupRule `json:"missing_rules,omitempty"`
+	//ExtraRules are rules that the group in CF has, but no binding needs
+	ExtraRules []bindparser.SecGroupRule `json:"extra_rules,omitempty"`
+	//MissingSpaces are GUIDs of spaces that the group should be bound to, but isn't
+	MissingSpaces []string `json:"missing_spaces,omitempty"`
+	//ExtraSpaces are GUIDs of spaces that the group is bound to, but shouldn't be
+	ExtraSpaces []string `json:"extra_spaces,omitempty"`
+	//Repaired is true if the group was put back the way it should be
+	Repaired bool `json:"repaired"`
+	//RepairError says why repairing the group failed, if it did
+	RepairError string `json:"repair_error,omitempty"`
+}
+
+func (d Drift) drifted() bool {
+	return d.Missing || len(d.MissingRules) > 0 || len(d.ExtraRules) > 0 ||
+		len(d.MissingSpaces) > 0 || len(d.ExtraSpaces) > 0
+}
+
+//CheckDrift compares every security group in the store with the group in CF,
+// and returns the ones that differ. If repair is true, the expected rules and
+// spaces are re-applied to each of those groups as well. Groups that no longer
+// have any bindings are left to the reconciler.
+func CheckDrift(repair bool) ([]Drift, error) {
+	if client == nil {
+		return nil, fmt.Errorf("Not connected to Cloud Foundry")
+	}
+
+	secGroupLock.Lock()
+	defer secGroupLock.Unlock()
+
+	infos, err := store.ListSecGroupInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	drifts := []Drift{}
+	for _, info := range infos {
+		bindings, err := store.ListBindingInfoBySecGroup(info.SecGroupName)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(bindings) == 0 {
+			continue
+		}
+
+		drift, err := secGroupDrift(info, bindings)
+		if err != nil {
+			return nil, err
+		}
+
+		if !drift.drifted() {
+			continue
+		}
+
+		if repair {
+			log.Infof("Repairing drift in security group %s", info.SecGroupName)
+			err = repairDrift(info, mergeRules(bindings), drift)
+			if err != nil {
+				log.Errorf("Could not repair drift in security group %s: %s", info.SecGroupName, err)
+				drift.RepairError = err.Error()
+			} else {
+				drift.Repaired = true
+			}
+		}
+		drifts = append(drifts, drift)
+	}
+
+	return drifts, nil
+}
+
+//secGroupDrift works out how the group in CF differs from the given record and
+// the bindings that share it
+func secGroupDrift(info store.SecGroupInfo, bindings []store.BindingInfo) (Drift, error) {
+	drift := Drift{
+		SecGroupName: info.SecGroupName,
+		SecGroupGUID: info.SecGroupGUID,
+	}
+	for _, binding := range bindings {
+		drift.Bindings = append(drift.Bindings, binding.BindingGUID)
+	}
+
+	actual, err := getSecGroup(info.SecGroupGUID)
+	if err != nil {
+		return drift, err
+	}
+
+	if actual == nil {
+		drift.Missing = true
+		return drift, nil
+	}
+
+	drift.MissingRules, drift.ExtraRules = diffRules(mergeRules(bindings), actual.Rules)
+
+	spaces, err := secGroupSpaceGUIDs(info.SecGroupGUID)
+	if err != nil {
+		return drift, err
+	}
+
+	drift.MissingSpaces, drift.ExtraSpaces = diffStrings([]string{info.SpaceGUID}, spaces)
+	return drift, nil
+}
+
+//repairDrift puts the group in CF back the way that the store says it should be
+func repairDrift(info store.SecGroupInfo, rules []bindparser.SecGroupRule, drift Drift) error {
+	if drift.Missing {
+		return recreateSecGroup(info, rules)
+	}
+
+	if len(drift.MissingRules) > 0 || len(drift.ExtraRules) > 0 {
+		_, err := updateSecGroup(info.SecGroupGUID, info.SecGroupName, rules, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, space := range drift.MissingSpaces {
+		if err := client.BindSecGroup(info.SecGroupGUID, space); err != nil {
+			return err
+		}
+	}
+
+	for _, space := range drift.ExtraSpaces {
+		if err := client.UnbindSecGroup(info.SecGroupGUID, space); err != nil {
+			return err
+		}
+	}
+
+	info.Rules = rules
+	return store.EditSecGroupInfo(info.SecGroupName, info)
+}
+
+//getSecGroup gets the security group with the given GUID from CF, or nil if CF
+// doesn't have it
+func getSecGroup(guid string) (*secGroup, error) {
+	resp, err := client.DoRequest(client.NewRequest("GET", "/v2/security_groups/"+guid))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("CF API returned with status code %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalSecGroup(body)
+}
+
+//secGroupSpaceGUIDs gets the GUIDs of all of the spaces that the security group
+// with the given GUID is bound to
+func secGroupSpaceGUIDs(guid string) ([]string, error) {
+	var ret []string
+	path := fmt.Sprintf("/v2/security_groups/%s/spaces", guid)
+	for path != "" {
+		resp, err := client.DoRequest(client.NewRequest("GET", path))
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("CF API returned with status code %d", resp.StatusCode)
+		}
+
+		var spaceResp cfclient.SpaceResponse
+		err = json.Unmarshal(body, &spaceResp)
+		if err != nil {
+			return nil, fmt.Errorf("Could not unmarshal spaces from CF: %s", err)
+		}
+
+		for _, resource := range spaceResp.Resources {
+			ret = append(ret, resource.Meta.Guid)
+		}
+		path = spaceResp.NextUrl
+	}
+	return ret, nil
+}
+
+//diffRules returns the rules that are expected but not in actual, and the rules
+// that are in actual but not expected
+func diffRules(expected, actual []bindparser.SecGroupRule) (missing, extra []bindparser.SecGroupRule) {
+	actualKeys := map[string]bool{}
+	for _, rule := range actual {
+		actualKeys[ruleKey(rule)] = true
+	}
+
+	expectedKeys := map[string]bool{}
+	for _, rule := range expected {
+		expectedKeys[ruleKey(rule)] = true
+		if !actualKeys[ruleKey(rule)] {
+			missing = append(missing, rule)
+		}
+	}
+
+	for _, rule := range actual {
+		if !expectedKeys[ruleKey(rule)] {
+			extra = append(extra, rule)
+		}
+	}
+	return
+}
+
+//diffStrings returns the values that are expected but not in actual, and the
+// values that are in actual but not expected
+func diffStrings(expected, actual []string) (missing, extra []string) {
+	actualSet := map[string]bool{}
+	for _, value := range actual {
+		actualSet[value] = true
+	}
+
+	expectedSet := map[string]bool{}
+	for _, value := range expected {
+		expectedSet[value] = true
+		if !actualSet[value] {
+			missing = append(missing, value)
+		}
+	}
+
+	for _, value := range actual {
+		if !expectedSet[value] {
+			extra = append(extra, value)
+		}
+	}
+	return
+}
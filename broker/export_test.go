@@ -0,0 +1,18 @@
+package broker
+
+import "time"
+
+//ReconcileSecGroups runs a single pass of the reconciler
+var ReconcileSecGroups = reconcileSecGroups
+
+//SetBindingGracePeriod changes how long the reconciler waits before it forgets
+// a binding that CF doesn't have, so that the specs don't have to wait. Returns
+// the grace period from before.
+func SetBindingGracePeriod(d time.Duration) time.Duration {
+	old := bindingGracePeriod
+	bindingGracePeriod = d
+	return old
+}
+
+//ReresolveSecGroups runs a single pass of the hostname resolver
+var ReresolveSecGroups = reresolveSecGroups
@@ -4,10 +4,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"net/url"
-
 	"net/http/httputil"
-
+	"net/url"
 	"strings"
 
 	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
@@ -33,27 +31,33 @@ func Placeholder(w http.ResponseWriter, r *http.Request) {
 // name given in the URL. It performs a lookup in the store to determine where
 // to forward the request to. The response is then passed back to the caller.
 func Passthrough(w http.ResponseWriter, r *http.Request) {
-	var mappingName string
-	if n, found := mux.Vars(r)["broker"]; found {
-		mappingName = n
+	brokerMapping, found := lookupMapping(w, r)
+	if !found {
+		return
 	}
+	proxy, statuscode, err := preparePassthrough(r, brokerMapping)
+	if err != nil {
+		writeError(w, statuscode, "", err.Error())
+		return
+	}
+	serveProxy(proxy, w, r)
+}
+
+//lookupMapping gets the mapping named in the URL of the given request from the
+// store. If it can't, the error is written to the response and found is false.
+func lookupMapping(w http.ResponseWriter, r *http.Request) (brokerMapping store.Mapping, found bool) {
+	mappingName := mux.Vars(r)["broker"]
 	brokerMapping, err := store.GetMapping(mappingName)
 	if err != nil {
 		if err == store.ErrNotFound {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(fmt.Sprintf("Portcullis: Unrecognized Broker Route `%s`", mappingName)))
+			writeError(w, http.StatusNotFound, "", fmt.Sprintf("Portcullis: Unrecognized Broker Route `%s`", mappingName))
 			return
 		}
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Portcullis: Error while contacting backend store"))
-	}
-	proxy, statuscode, err := preparePassthrough(r, brokerMapping)
-	if err != nil {
-		w.WriteHeader(statuscode)
-		w.Write([]byte(err.Error()))
+		log.Errorf("Could not get mapping `%s` from store: %s", mappingName, err)
+		writeError(w, http.StatusInternalServerError, "", "Portcullis: Error while contacting backend store")
 		return
 	}
-	proxy.ServeHTTP(w, r)
+	return brokerMapping, true
 }
 
 //preparePassthrough does the lookup of the mapping and sets up the request and
@@ -64,14 +68,17 @@ func preparePassthrough(r *http.Request, brokerMapping store.Mapping) (proxy *ht
 	// the request object
 	baseURL, err := url.Parse(brokerMapping.Location)
 	if err != nil {
-		return nil, http.StatusInternalServerError, fmt.Errorf(fmt.Sprintf("Portcullis: Mapping location cannot be parsed as URL"))
+		return nil, http.StatusInternalServerError, fmt.Errorf("Portcullis: Mapping location cannot be parsed as URL")
 	}
 	//Create the request url and strip off the broker name from the endpoint path.
 	// This is for the request object and will affect the brokers internal routing
 	url, err := url.Parse(fmt.Sprintf("%s%s", brokerMapping.Location, strings.TrimPrefix(r.URL.Path, fmt.Sprintf("/%s", brokerMapping.Name))))
 	if err != nil {
-		return nil, http.StatusInternalServerError, fmt.Errorf(fmt.Sprintf("Portcullis: Mapping location cannot be parsed as URL"))
+		return nil, http.StatusInternalServerError, fmt.Errorf("Portcullis: Mapping location cannot be parsed as URL")
 	}
+	//Query parameters such as service_id, plan_id, operation and
+	// accepts_incomplete are part of the request to the broker as well
+	url.RawQuery = r.URL.RawQuery
 	proxy = httputil.NewSingleHostReverseProxy(baseURL)
 	r.URL = url
 	return proxy, http.StatusOK, nil
@@ -80,36 +87,115 @@ func preparePassthrough(r *http.Request, brokerMapping store.Mapping) (proxy *ht
 //BindService is an HTTP handler which handles the passthrough and parsing of
 // a CF bind-service call.
 func BindService(w http.ResponseWriter, r *http.Request) {
-	var mappingName string
-	if n, found := mux.Vars(r)["broker"]; found {
-		mappingName = n
+	brokerMapping, found := lookupMapping(w, r)
+	if !found {
+		return
 	}
-	brokerMapping, err := store.GetMapping(mappingName)
+	proxy, statuscode, err := preparePassthrough(r, brokerMapping)
+	if err != nil {
+		writeError(w, statuscode, "", err.Error())
+		return
+	}
+
+	flavors, err := brokerMapping.BindConfig.CreateFlavors()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "",
+			fmt.Sprintf("Portcullis: Bind config of mapping `%s` is invalid: %s", brokerMapping.Name, err))
+		return
+	}
+
+	//Mappings that never open egress don't need anything from the bind response,
+	// so the bind is just passed through
+	if !flavors.OpensEgress() {
+		log.Debugf("BindService: mapping `%s` opens no egress", brokerMapping.Name)
+		serveProxy(proxy, w, r)
+		return
+	}
+
+	//set transport
+	proxy.Transport = newBindTransport(r, brokerMapping, flavors)
+	serveProxy(proxy, w, r)
+}
+
+//newBindTransport makes the BindTransport for the binding in the URL of the
+// given request
+func newBindTransport(r *http.Request, brokerMapping store.Mapping, flavors bindparser.FlavorList) *BindTransport {
+	return &BindTransport{
+		Flavors:      flavors,
+		MappingName:  brokerMapping.Name,
+		InstanceGUID: mux.Vars(r)["inst_id"],
+		BindingGUID:  mux.Vars(r)["bind_id"],
+
+		ResolveHostnames: brokerMapping.ResolveHostnames,
+		ServiceKeyPolicy: serviceKeyPolicy,
+	}
+}
+
+//BindingLastOperation is an HTTP handler which handles the passthrough of a CF
+// poll of an asynchronous bind or unbind. Egress is opened for the binding once
+// the backend broker says that a bind has succeeded, and closed once it says
+// that an unbind has.
+func BindingLastOperation(w http.ResponseWriter, r *http.Request) {
+	brokerMapping, found := lookupMapping(w, r)
+	if !found {
+		return
+	}
+	proxy, statuscode, err := preparePassthrough(r, brokerMapping)
+	if err != nil {
+		writeError(w, statuscode, "", err.Error())
+		return
+	}
+
+	//Binds that finished synchronously, or that open no egress, were never
+	// recorded, and there's nothing to do for them
+	pending, err := store.GetPendingBind(mux.Vars(r)["bind_id"])
 	if err != nil {
 		if err == store.ErrNotFound {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(fmt.Sprintf("Portcullis: Unrecognized Broker Route `%s`", mappingName)))
+			serveProxy(proxy, w, r)
 			return
 		}
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Portcullis: Error while contacting backend store"))
+		writeError(w, http.StatusInternalServerError, "", "Portcullis: Error while contacting backend store")
+		return
 	}
-	proxy, statuscode, err := preparePassthrough(r, brokerMapping)
 
+	if pending.Unbind {
+		proxy.Transport = &UnbindLastOperationTransport{Pending: pending}
+		serveProxy(proxy, w, r)
+		return
+	}
+
+	flavors, err := brokerMapping.BindConfig.CreateFlavors()
 	if err != nil {
-		w.WriteHeader(statuscode)
-		w.Write([]byte(err.Error()))
+		writeError(w, http.StatusInternalServerError, "",
+			fmt.Sprintf("Portcullis: Bind config of mapping `%s` is invalid: %s", brokerMapping.Name, err))
 		return
 	}
 
-	flavor, err := brokerMapping.BindConfig.CreateFlavor()
+	proxy.Transport = &BindLastOperationTransport{
+		Bind:    newBindTransport(r, brokerMapping, flavors),
+		Pending: pending,
+	}
+	serveProxy(proxy, w, r)
+}
+
+//UnbindService is an HTTP handler which handles the passthrough of a CF
+// unbind-service call, and removes the security groups that were created for
+// the binding once the backend broker has unbound it.
+func UnbindService(w http.ResponseWriter, r *http.Request) {
+	brokerMapping, found := lookupMapping(w, r)
+	if !found {
+		return
+	}
+	proxy, statuscode, err := preparePassthrough(r, brokerMapping)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(err.Error()))
+		writeError(w, statuscode, "", err.Error())
+		return
 	}
-	//set transport
-	proxy.Transport = &BindTransport{
-		Flavors: []bindparser.Flavor{flavor},
+
+	proxy.Transport = &UnbindTransport{
+		MappingName:  brokerMapping.Name,
+		InstanceGUID: mux.Vars(r)["inst_id"],
+		BindingGUID:  mux.Vars(r)["bind_id"],
 	}
-	proxy.ServeHTTP(w, r)
+	serveProxy(proxy, w, r)
 }
@@ -0,0 +1,207 @@
+package broker_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Polling the last operation of a binding", func() {
+	var cf *stubCF
+	var backend *stubBackend
+	var mappingName, instanceGUID, bindingGUID, secGroupName string
+	var testResponse *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		cf = newStubCF()
+		backend = newStubBackend()
+		mappingName = addTestMapping(backend.server.URL)
+		cf.connectBroker(config.ServiceKeyPolicyPassthrough)
+
+		instanceGUID, bindingGUID = genRandomString(), genRandomString()
+		secGroupName = "portcullis-" + instanceGUID + "-some-space"
+	})
+
+	AfterEach(func() {
+		cf.close()
+		backend.close()
+		clearStore()
+	})
+
+	JustBeforeEach(func() {
+		testResponse = brokerRequest("GET", mappingName,
+			bindingPath(instanceGUID, bindingGUID)+"/last_operation?service_id=service-id&plan_id=plan-id", nil)
+	})
+
+	bind := func() *httptest.ResponseRecorder {
+		return brokerRequest("PUT", mappingName, bindingPath(instanceGUID, bindingGUID),
+			bindBody(cf.addApp("some-space", "some-org"), "some-space", "some-org"))
+	}
+
+	assertForgotten := func() {
+		It("should forget the pending operation", func() {
+			_, err := store.GetPendingBind(bindingGUID)
+			Expect(err).To(Equal(store.ErrNotFound))
+		})
+	}
+
+	Describe("An asynchronous bind", func() {
+		BeforeEach(func() {
+			backend.bindStatus = http.StatusAccepted
+			Expect(bind().Code).To(Equal(http.StatusAccepted))
+
+			pending, err := store.GetPendingBind(bindingGUID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending.Unbind).To(BeFalse())
+		})
+
+		Context("When the bind is still in progress", func() {
+			It("should pass the state through and wait", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusOK))
+				Expect(readJSONResponse(testResponse)["state"]).To(Equal("in progress"))
+				Expect(cf.secGroups()).To(BeEmpty())
+				_, err := store.GetPendingBind(bindingGUID)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("When the bind has succeeded", func() {
+			BeforeEach(func() {
+				backend.lastOpState = "succeeded"
+			})
+
+			It("should pass the state through", func() {
+				Expect(testResponse.Code).To(Equal(http.StatusOK))
+				Expect(readJSONResponse(testResponse)["state"]).To(Equal("succeeded"))
+			})
+
+			It("should fetch the binding's credentials and open egress for them", func() {
+				Expect(backend.received("GET " + bindingPath(instanceGUID, bindingGUID) + "?")).To(HaveLen(1))
+				Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.5"))
+				_, err := store.GetBindingInfo(bindingGUID)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			assertForgotten()
+
+			It("should do nothing more on a later poll", func() {
+				response := brokerRequest("GET", mappingName, bindingPath(instanceGUID, bindingGUID)+"/last_operation", nil)
+				Expect(readJSONResponse(response)["state"]).To(Equal("succeeded"))
+				Expect(backend.received("GET " + bindingPath(instanceGUID, bindingGUID) + "?")).To(HaveLen(1))
+				Expect(cf.secGroups()).To(HaveLen(1))
+			})
+
+			Context("When egress can't be opened", func() {
+				BeforeEach(func() {
+					backend.credentials = map[string]interface{}{"uri": "redis://10.0.0.5:6379"}
+				})
+
+				It("should tell CF that the bind failed", func() {
+					Expect(testResponse.Code).To(Equal(http.StatusOK))
+					contents := readJSONResponse(testResponse)
+					Expect(contents["state"]).To(Equal("failed"))
+					Expect(contents["description"]).To(ContainSubstring("Portcullis could not open egress"))
+				})
+
+				It("should undo the binding with the backend broker and record the failure", func() {
+					Expect(backend.received("DELETE " + bindingPath(instanceGUID, bindingGUID))).To(HaveLen(1))
+					failures, err := store.ListBindFailures()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(failures).To(HaveLen(1))
+					Expect(failures[0].Unbound).To(BeTrue())
+				})
+
+				assertForgotten()
+			})
+		})
+
+		Context("When the bind has failed", func() {
+			BeforeEach(func() {
+				backend.lastOpState = "failed"
+			})
+
+			It("should pass the state through without opening egress", func() {
+				Expect(readJSONResponse(testResponse)["state"]).To(Equal("failed"))
+				Expect(cf.secGroups()).To(BeEmpty())
+			})
+
+			assertForgotten()
+		})
+	})
+
+	Describe("An asynchronous unbind", func() {
+		BeforeEach(func() {
+			Expect(bind().Code).To(Equal(http.StatusCreated))
+			backend.unbindStatus = http.StatusAccepted
+			response := brokerRequest("DELETE", mappingName, bindingPath(instanceGUID, bindingGUID), nil)
+			Expect(response.Code).To(Equal(http.StatusAccepted))
+		})
+
+		Context("When the unbind is still in progress", func() {
+			It("should leave egress open", func() {
+				Expect(readJSONResponse(testResponse)["state"]).To(Equal("in progress"))
+				Expect(cf.secGroups()).To(HaveKey(secGroupName))
+			})
+		})
+
+		Context("When the unbind has succeeded", func() {
+			BeforeEach(func() {
+				backend.lastOpState = "succeeded"
+			})
+
+			It("should close egress", func() {
+				Expect(readJSONResponse(testResponse)["state"]).To(Equal("succeeded"))
+				Expect(cf.secGroups()).To(BeEmpty())
+				_, err := store.GetBindingInfo(bindingGUID)
+				Expect(err).To(Equal(store.ErrNotFound))
+			})
+
+			assertForgotten()
+
+			Context("When CF can't delete the security group", func() {
+				BeforeEach(func() {
+					cf.Fail = true
+				})
+
+				It("should report the unbind as still in progress, so that CF polls again", func() {
+					contents := readJSONResponse(testResponse)
+					Expect(contents["state"]).To(Equal("in progress"))
+					Expect(contents["description"]).To(ContainSubstring("Portcullis could not remove egress"))
+					_, err := store.GetPendingBind(bindingGUID)
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+		})
+
+		Context("When the unbind has failed", func() {
+			BeforeEach(func() {
+				backend.lastOpState = "failed"
+			})
+
+			It("should leave egress open", func() {
+				Expect(readJSONResponse(testResponse)["state"]).To(Equal("failed"))
+				Expect(cf.secGroups()).To(HaveKey(secGroupName))
+			})
+
+			assertForgotten()
+		})
+	})
+
+	Context("When the binding was made synchronously", func() {
+		BeforeEach(func() {
+			Expect(bind().Code).To(Equal(http.StatusCreated))
+			backend.lastOpState = "succeeded"
+		})
+
+		It("should just pass the poll through", func() {
+			Expect(readJSONResponse(testResponse)["state"]).To(Equal("succeeded"))
+			Expect(backend.received("GET " + bindingPath(instanceGUID, bindingGUID) + "?")).To(BeEmpty())
+			Expect(cf.secGroups()).To(HaveLen(1))
+		})
+	})
+})
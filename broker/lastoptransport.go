@@ -0,0 +1,201 @@
+package broker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/cloudfoundry-community/portcullis/store"
+	"github.com/starkandwayne/goutils/log"
+)
+
+//BindLastOperationTransport is an http.RoundTripper which forwards a binding
+// last_operation poll to the backend broker for an asynchronous bind. Once the
+// broker says that the bind succeeded, the binding is fetched from the broker
+// and egress is opened for its credentials. If that fails, the binding is
+// undone and the Cloud Controller is told that the bind failed.
+type BindLastOperationTransport struct {
+	Bind    *BindTransport
+	Pending store.PendingBind
+}
+
+//lastOperation is the body of an OSBAPI last_operation response
+type lastOperation struct {
+	State       string `json:"state"`
+	Description string `json:"description,omitempty"`
+}
+
+func (l *BindLastOperationTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil {
+		return resp, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusGone: //the broker no longer has the binding
+		l.forget()
+		return resp, nil
+	default:
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var op lastOperation
+	err = json.Unmarshal(body, &op)
+	if err != nil {
+		//Leave it to the Cloud Controller to make sense of
+		log.Errorf("BindLastOperationTransport: Could not read last_operation response: %s", err)
+		return resp, nil
+	}
+
+	switch op.State {
+	case "failed":
+		log.Debugf("BindLastOperationTransport: Binding %s failed in the backend broker", l.Pending.BindingGUID)
+		l.forget()
+	case "succeeded":
+		//Whoever removes the record is the one that opens egress, so that
+		// polls that cross don't both try to
+		err = store.DeletePendingBind(l.Pending.BindingGUID)
+		if err == store.ErrNotFound {
+			return resp, nil
+		}
+		if err != nil {
+			log.Errorf("BindLastOperationTransport: Could not remove pending bind %s: %s", l.Pending.BindingGUID, err)
+		}
+
+		err = l.openEgress(r)
+		if err != nil {
+			l.Bind.undoBind(r, []byte(l.Pending.Request), err)
+			return jsonResponse(r, http.StatusOK, lastOperation{
+				State:       "failed",
+				Description: fmt.Sprintf("Portcullis could not open egress for the binding: %s", err),
+			}), nil
+		}
+	}
+	return resp, nil
+}
+
+//openEgress fetches the credentials of the finished binding from the backend
+// broker and opens egress for them
+func (l *BindLastOperationTransport) openEgress(r *http.Request) error {
+	reqBody := []byte(l.Pending.Request)
+	bindBody, err := parseBindRequest(reqBody)
+	if err != nil {
+		return err
+	}
+
+	fetchReq, err := bindingRequest(r, "GET", bindBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultTransport.RoundTrip(fetchReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Backend broker returned with status code %d when fetching the binding", resp.StatusCode)
+	}
+
+	credsBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return l.Bind.openEgress(reqBody, credsBody)
+}
+
+//forget removes the record of the asynchronous bind, which won't be opening
+// egress any more
+func (l *BindLastOperationTransport) forget() {
+	err := store.DeletePendingBind(l.Pending.BindingGUID)
+	if err != nil && err != store.ErrNotFound {
+		log.Errorf("BindLastOperationTransport: Could not remove pending bind %s: %s", l.Pending.BindingGUID, err)
+	}
+}
+
+//UnbindLastOperationTransport is an http.RoundTripper which forwards a binding
+// last_operation poll to the backend broker for an asynchronous unbind. Once the
+// broker says that the unbind succeeded, or that it no longer has the binding,
+// the binding's rules are taken out of its security group.
+type UnbindLastOperationTransport struct {
+	Pending store.PendingBind
+}
+
+func (u *UnbindLastOperationTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil {
+		return resp, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusGone: //the binding is gone, which is what an unbind is for
+		return u.closeEgress(r, resp), nil
+	default:
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var op lastOperation
+	err = json.Unmarshal(body, &op)
+	if err != nil {
+		log.Errorf("UnbindLastOperationTransport: Could not read last_operation response: %s", err)
+		return resp, nil
+	}
+
+	switch op.State {
+	case "failed":
+		//The binding is still there, and so its egress stays open
+		log.Debugf("UnbindLastOperationTransport: Unbind of %s failed in the backend broker", u.Pending.BindingGUID)
+		u.forget()
+	case "succeeded":
+		return u.closeEgress(r, resp), nil
+	}
+	return resp, nil
+}
+
+//closeEgress takes the binding's rules out of its security group, and returns
+// the response to give the Cloud Controller. If that fails, the unbind is
+// reported as still in progress, so that the next poll tries again.
+func (u *UnbindLastOperationTransport) closeEgress(r *http.Request, resp *http.Response) *http.Response {
+	err := deleteBindingSecGroups(u.Pending.BindingGUID)
+	if err != nil {
+		log.Errorf("UnbindLastOperationTransport: %s", err)
+		resp.Body.Close()
+		return jsonResponse(r, http.StatusOK, lastOperation{
+			State:       "in progress",
+			Description: fmt.Sprintf("Portcullis could not remove egress for the binding: %s", err),
+		})
+	}
+
+	u.forget()
+	return resp
+}
+
+//forget removes the record of the asynchronous unbind
+func (u *UnbindLastOperationTransport) forget() {
+	err := store.DeletePendingBind(u.Pending.BindingGUID)
+	if err != nil && err != store.ErrNotFound {
+		log.Errorf("UnbindLastOperationTransport: Could not remove pending unbind %s: %s", u.Pending.BindingGUID, err)
+	}
+}
@@ -0,0 +1,81 @@
+package broker_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry-community/portcullis/config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Passing requests through", func() {
+	var cf *stubCF
+	var backend *stubBackend
+	var mappingName string
+
+	BeforeEach(func() {
+		cf = newStubCF()
+		backend = newStubBackend()
+		mappingName = addTestMapping(backend.server.URL)
+		cf.connectBroker(config.ServiceKeyPolicyPassthrough)
+	})
+
+	AfterEach(func() {
+		cf.close()
+		backend.close()
+		clearStore()
+	})
+
+	It("should send the request to the mapping's location, without the mapping name", func() {
+		response := brokerRequest("GET", mappingName, "/v2/catalog", nil)
+		Expect(response.Code).To(Equal(http.StatusOK))
+		Expect(readJSONResponse(response)).To(HaveKey("services"))
+		Expect(backend.received("GET /v2/catalog")).To(HaveLen(1))
+	})
+
+	It("should keep the query parameters", func() {
+		brokerRequest("DELETE", mappingName, "/v2/service_instances/some-instance?service_id=service-id&plan_id=plan-id&accepts_incomplete=true", nil)
+		Expect(backend.received("DELETE /v2/service_instances/some-instance")).To(ConsistOf(
+			"DELETE /v2/service_instances/some-instance?service_id=service-id&plan_id=plan-id&accepts_incomplete=true"))
+	})
+
+	It("should pass through routes that were added to the spec later, such as fetching an instance", func() {
+		brokerRequest("GET", mappingName, "/v2/service_instances/some-instance", nil)
+		Expect(backend.received("GET /v2/service_instances/some-instance")).To(HaveLen(1))
+	})
+	Describe("Errors", func() {
+		//assertError checks that the response is an OSBAPI error with the given
+		// status code and a description containing the given text
+		assertError := func(response *httptest.ResponseRecorder, statusCode int, description string) {
+			Expect(response.Code).To(Equal(statusCode))
+			Expect(response.Header().Get("Content-Type")).To(Equal("application/json"))
+			Expect(readJSONResponse(response)["description"]).To(ContainSubstring(description))
+		}
+
+		It("should say when there is no such mapping", func() {
+			assertError(brokerRequest("GET", "no-such-mapping", "/v2/catalog", nil), http.StatusNotFound, "Unrecognized Broker Route `no-such-mapping`")
+		})
+
+		It("should say when the route isn't part of the service broker API", func() {
+			assertError(brokerRequest("GET", mappingName, "/v2/nonsense", nil), http.StatusNotFound, "Unrecognized route")
+		})
+
+		Context("When the backend broker can't be reached", func() {
+			BeforeEach(func() {
+				backend.close()
+			})
+
+			It("should say so for a request that is passed through", func() {
+				assertError(brokerRequest("GET", mappingName, "/v2/catalog", nil), http.StatusBadGateway, "Could not contact backend broker")
+			})
+
+			It("should say so for a bind", func() {
+				assertError(brokerRequest("PUT", mappingName, bindingPath("some-instance", "some-binding"),
+					bindBody("some-app", "some-space", "some-org")), http.StatusBadGateway, "Could not contact backend broker")
+				Expect(cf.secGroups()).To(BeEmpty())
+			})
+		})
+	})
+})
@@ -0,0 +1,308 @@
+package broker
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
+	"github.com/cloudfoundry-community/portcullis/store"
+	"github.com/starkandwayne/goutils/log"
+)
+
+//reconcileInterval is how long to wait between passes of the reconciler. Zero
+// or less turns it off, which it is unless configured.
+var reconcileInterval time.Duration
+
+//reconcileDryRun makes the reconciler only log the changes that it would make
+var reconcileDryRun bool
+
+//bindingGracePeriod is how long a recorded binding has to be missing from CF
+// before the reconciler forgets it. A binding is recorded before the broker
+// answers the Cloud Controller, so CF doesn't have it yet while the bind is
+// still going on.
+var bindingGracePeriod = 5 * time.Minute
+
+//missingBindings is when each recorded binding that CF doesn't have was first
+// seen to be missing, by binding GUID. Only used by planReconcile, which only
+// the reconciler runs.
+var missingBindings = map[string]time.Time{}
+
+//reconcileAction is a single change that the reconciler has decided to make
+type reconcileAction struct {
+	//description says what the action does, for the logs
+	description string
+	//check says whether the action is still needed. Plans are made without
+	// secGroupLock, so a bind or unbind may have changed things since.
+	check func() (bool, error)
+	apply func() error
+}
+
+//LaunchReconciler periodically compares the security groups that Portcullis
+// has made in CF with the bindings in the store and in CF, and deletes or
+// re-creates groups so that they match. Groups leak if Portcullis goes down
+// partway through a bind, or while a binding is deleted. Does not return unless
+// the reconciler is turned off.
+func LaunchReconciler() {
+	if reconcileInterval <= 0 {
+		log.Infof("Security group reconciliation is turned off")
+		return
+	}
+
+	log.Infof("Reconciling security groups every %s", reconcileInterval)
+	if reconcileDryRun {
+		log.Infof("Reconciler is in dry run mode, and will only log its changes")
+	}
+	for range time.Tick(reconcileInterval) {
+		reconcileSecGroups()
+	}
+}
+
+//reconcileSecGroups does a single pass of the reconciler. The plan is made
+// without secGroupLock, so that binds and unbinds don't wait on its lookups in
+// CF. Each action is checked against the store again with the lock held before
+// it is applied.
+func reconcileSecGroups() {
+	actions, err := planReconcile()
+	if err != nil {
+		log.Errorf("Reconciler: Could not plan changes: %s", err)
+		return
+	}
+
+	secGroupLock.Lock()
+	defer secGroupLock.Unlock()
+
+	for _, action := range actions {
+		if reconcileDryRun {
+			log.Infof("Reconciler: Would %s", action.description)
+			continue
+		}
+
+		needed, err := action.check()
+		if err != nil {
+			log.Errorf("Reconciler: Could not check whether to %s: %s", action.description, err)
+			continue
+		}
+		if !needed {
+			log.Infof("Reconciler: Not going to %s, which changed since it was planned", action.description)
+			continue
+		}
+
+		log.Infof("Reconciler: Going to %s", action.description)
+		if err = action.apply(); err != nil {
+			log.Errorf("Reconciler: Could not %s: %s", action.description, err)
+		}
+	}
+}
+
+//planReconcile works out what needs to change for the security groups in CF to
+// match the bindings. Nothing is planned unless everything could be looked up,
+// so that an outage doesn't get mistaken for bindings having gone away.
+func planReconcile() ([]reconcileAction, error) {
+	cfGroups, err := portcullisSecGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := store.ListSecGroupInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	bindings, err := store.ListBindingInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []reconcileAction{}
+
+	//Bindings that CF no longer has were deleted while we weren't looking, unless
+	// they are new enough that CF may not have them yet
+	now := time.Now()
+	stillMissing := map[string]time.Time{}
+	liveBindings := map[string][]store.BindingInfo{}
+	for _, binding := range bindings {
+		exists, err := cfBindingExists(binding)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			since, seen := missingBindings[binding.BindingGUID]
+			if !seen {
+				since = now
+			}
+			stillMissing[binding.BindingGUID] = since
+			if now.Sub(since) >= bindingGracePeriod {
+				actions = append(actions, forgetBindingAction(binding))
+				continue
+			}
+		}
+		liveBindings[binding.SecGroupName] = append(liveBindings[binding.SecGroupName], binding)
+	}
+	missingBindings = stillMissing
+
+	cfGroupsByGUID := map[string]secGroup{}
+	for _, group := range cfGroups {
+		cfGroupsByGUID[group.Guid] = group
+	}
+
+	tracked := map[string]bool{}
+	for _, info := range infos {
+		tracked[info.SecGroupGUID] = true
+		_, inCF := cfGroupsByGUID[info.SecGroupGUID]
+		groupBindings := liveBindings[info.SecGroupName]
+
+		switch {
+		case len(groupBindings) == 0:
+			actions = append(actions, deleteSecGroupAction(info, inCF))
+		case !inCF:
+			actions = append(actions, recreateSecGroupAction(info, mergeRules(groupBindings)))
+		default:
+			if rules := mergeRules(groupBindings); !reflect.DeepEqual(rules, info.Rules) {
+				actions = append(actions, updateSecGroupAction(info, rules))
+			}
+		}
+	}
+
+	for _, group := range cfGroups {
+		if tracked[group.Guid] {
+			continue
+		}
+
+		//A shared group that was made by a bind that failed before recording it is
+		// safe to delete once its service instance is gone. Any other untracked
+		// group may have been made by an older Portcullis for a binding that is
+		// still there, or by an operator, so it is only reported.
+		instanceGUID, _, ok := parseSecGroupName(group.Name)
+		if !ok {
+			log.Debugf("Reconciler: Leaving untracked security group %s (%s) alone", group.Name, group.Guid)
+			continue
+		}
+
+		exists, err := cfResourceExists("/v2/service_instances/" + instanceGUID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			actions = append(actions, deleteUntrackedSecGroupAction(group))
+		}
+	}
+
+	return actions, nil
+}
+
+//secGroupUnchanged returns true if the given security group is still recorded
+// as it was when the plan was made, and its bindings still need the given rules
+func secGroupUnchanged(info store.SecGroupInfo, rules []bindparser.SecGroupRule) (bool, error) {
+	current, err := store.GetSecGroupInfoByName(info.SecGroupName)
+	if err == store.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if current.SecGroupGUID != info.SecGroupGUID || !reflect.DeepEqual(current.Rules, info.Rules) {
+		return false, nil
+	}
+
+	bindings, err := store.ListBindingInfoBySecGroup(info.SecGroupName)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(mergeRules(bindings), rules), nil
+}
+
+func forgetBindingAction(binding store.BindingInfo) reconcileAction {
+	return reconcileAction{
+		description: fmt.Sprintf("forget binding %s, which CF no longer has", binding.BindingGUID),
+		check: func() (bool, error) {
+			_, err := store.GetBindingInfo(binding.BindingGUID)
+			if err == store.ErrNotFound {
+				return false, nil
+			}
+			return err == nil, err
+		},
+		apply: func() error {
+			return store.DeleteBindingInfo(binding.BindingGUID)
+		},
+	}
+}
+
+func deleteSecGroupAction(info store.SecGroupInfo, inCF bool) reconcileAction {
+	//A bind may have given the group a binding since the plan was made
+	check := func() (bool, error) {
+		return secGroupUnchanged(info, mergeRules(nil))
+	}
+
+	if !inCF {
+		return reconcileAction{
+			description: fmt.Sprintf("forget security group %s, which has no bindings and is already gone from CF", info.SecGroupName),
+			check:       check,
+			apply: func() error {
+				return store.DeleteSecGroupInfoByName(info.SecGroupName)
+			},
+		}
+	}
+
+	return reconcileAction{
+		description: fmt.Sprintf("delete security group %s (%s), which has no bindings", info.SecGroupName, info.SecGroupGUID),
+		check:       check,
+		apply: func() error {
+			if err := deleteSecGroup(info.SecGroupGUID); err != nil {
+				return err
+			}
+			return store.DeleteSecGroupInfoByName(info.SecGroupName)
+		},
+	}
+}
+
+func recreateSecGroupAction(info store.SecGroupInfo, rules []bindparser.SecGroupRule) reconcileAction {
+	return reconcileAction{
+		description: fmt.Sprintf("re-create security group %s, which is missing from CF, with rules %v", info.SecGroupName, rules),
+		check: func() (bool, error) {
+			return secGroupUnchanged(info, rules)
+		},
+		apply: func() error {
+			return recreateSecGroup(info, rules)
+		},
+	}
+}
+
+func updateSecGroupAction(info store.SecGroupInfo, rules []bindparser.SecGroupRule) reconcileAction {
+	return reconcileAction{
+		description: fmt.Sprintf("update the rules of security group %s (%s) to %v", info.SecGroupName, info.SecGroupGUID, rules),
+		check: func() (bool, error) {
+			return secGroupUnchanged(info, rules)
+		},
+		apply: func() error {
+			_, err := updateSecGroup(info.SecGroupGUID, info.SecGroupName, rules, nil)
+			if err != nil {
+				return err
+			}
+
+			info.Rules = rules
+			return store.EditSecGroupInfo(info.SecGroupName, info)
+		},
+	}
+}
+
+func deleteUntrackedSecGroupAction(group secGroup) reconcileAction {
+	return reconcileAction{
+		description: fmt.Sprintf("delete security group %s (%s), which is not tracked and whose service instance is gone", group.Name, group.Guid),
+		check: func() (bool, error) {
+			//A bind that records a group by this name makes a new one in CF, but
+			// leave the name alone while it is in use
+			_, err := store.GetSecGroupInfoByName(group.Name)
+			if err == store.ErrNotFound {
+				return true, nil
+			}
+			return false, err
+		},
+		apply: func() error {
+			return deleteSecGroup(group.Guid)
+		},
+	}
+}
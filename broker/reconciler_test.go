@@ -0,0 +1,127 @@
+package broker_test
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cloudfoundry-community/portcullis/broker"
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reconciling security groups", func() {
+	var cf *stubCF
+	var backend *stubBackend
+	var mappingName, instanceGUID, secGroupName string
+	var firstGUID, secondGUID string
+	var oldGracePeriod time.Duration
+
+	BeforeEach(func() {
+		cf = newStubCF()
+		backend = newStubBackend()
+		mappingName = addTestMapping(backend.server.URL)
+		cf.connectBroker(config.ServiceKeyPolicyPassthrough)
+		oldGracePeriod = broker.SetBindingGracePeriod(0)
+
+		instanceGUID = genRandomString()
+		firstGUID, secondGUID = genRandomString(), genRandomString()
+		secGroupName = "portcullis-" + instanceGUID + "-some-space"
+
+		for guid, host := range map[string]string{firstGUID: "10.0.0.5", secondGUID: "10.0.0.6"} {
+			backend.credentials = map[string]interface{}{"host": host, "port": 6379}
+			response := brokerRequest("PUT", mappingName, bindingPath(instanceGUID, guid),
+				bindBody(cf.addApp("some-space", "some-org"), "some-space", "some-org"))
+			Expect(response.Code).To(Equal(http.StatusCreated))
+		}
+	})
+
+	AfterEach(func() {
+		broker.SetBindingGracePeriod(oldGracePeriod)
+		cf.close()
+		backend.close()
+		clearStore()
+	})
+
+	JustBeforeEach(func() {
+		broker.ReconcileSecGroups()
+	})
+
+	bindingRecorded := func(bindingGUID string) bool {
+		_, err := store.GetBindingInfo(bindingGUID)
+		if err == store.ErrNotFound {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	Context("When CF still has both bindings", func() {
+		BeforeEach(func() {
+			cf.addServiceBinding(firstGUID)
+			cf.addServiceBinding(secondGUID)
+		})
+
+		It("should keep both bindings and their rules", func() {
+			Expect(bindingRecorded(firstGUID)).To(BeTrue())
+			Expect(bindingRecorded(secondGUID)).To(BeTrue())
+			Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.5", "10.0.0.6"))
+		})
+	})
+
+	Context("When CF no longer has one of the bindings", func() {
+		BeforeEach(func() {
+			cf.addServiceBinding(secondGUID)
+		})
+
+		It("should forget the stale binding", func() {
+			Expect(bindingRecorded(firstGUID)).To(BeFalse())
+			Expect(bindingRecorded(secondGUID)).To(BeTrue())
+		})
+
+		It("should keep the group for the other binding, with only its rules", func() {
+			Expect(cf.secGroups()).To(HaveKey(secGroupName))
+			Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.6"))
+
+			info, err := store.GetSecGroupInfoByName(secGroupName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Rules).To(HaveLen(1))
+			Expect(info.Rules[0].Destination).To(Equal("10.0.0.6"))
+		})
+
+		Context("When the binding has only just gone missing", func() {
+			BeforeEach(func() {
+				broker.SetBindingGracePeriod(time.Hour)
+			})
+
+			It("should give CF time to catch up before forgetting it", func() {
+				Expect(bindingRecorded(firstGUID)).To(BeTrue())
+				Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.5", "10.0.0.6"))
+			})
+		})
+	})
+
+	Context("When CF has neither of the bindings", func() {
+		It("should forget them and delete their group", func() {
+			Expect(bindingRecorded(firstGUID)).To(BeFalse())
+			Expect(bindingRecorded(secondGUID)).To(BeFalse())
+			Expect(cf.secGroups()).To(BeEmpty())
+			_, err := store.GetSecGroupInfoByName(secGroupName)
+			Expect(err).To(Equal(store.ErrNotFound))
+		})
+	})
+
+	Context("When the group has gone missing from CF", func() {
+		BeforeEach(func() {
+			cf.addServiceBinding(firstGUID)
+			cf.addServiceBinding(secondGUID)
+			cf.clearSecGroups()
+		})
+
+		It("should make it again with the rules of both bindings", func() {
+			Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.5", "10.0.0.6"))
+		})
+	})
+})
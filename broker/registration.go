@@ -0,0 +1,190 @@
+package broker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/starkandwayne/goutils/log"
+)
+
+//publicURL is the URL that the Cloud Controller reaches the broker server at,
+// which the URLs of registered service brokers start with
+var publicURL string
+
+//Registration holds what is needed to register a mapping with CF as a service
+// broker. The credentials are the ones that the Cloud Controller will give to
+// the backend broker, and are passed through to it by Portcullis.
+type Registration struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	//SpaceGUID registers a broker that is only visible in the space with this
+	// GUID. If empty, the broker is registered for the whole of CF.
+	SpaceGUID string `json:"space_guid,omitempty"`
+}
+
+//serviceBroker is the entity of a CF service broker resource
+type serviceBroker struct {
+	Name         string `json:"name"`
+	BrokerURL    string `json:"broker_url"`
+	AuthUsername string `json:"auth_username,omitempty"`
+	AuthPassword string `json:"auth_password,omitempty"`
+	SpaceGUID    string `json:"space_guid,omitempty"`
+}
+
+type serviceBrokerResource struct {
+	Meta struct {
+		Guid string `json:"guid"`
+	} `json:"metadata"`
+	Entity serviceBroker `json:"entity"`
+}
+
+type serviceBrokerResponse struct {
+	Resources []serviceBrokerResource `json:"resources"`
+}
+
+//BrokerURL returns the URL that the Cloud Controller should use to reach the
+// backend broker of the mapping with the given name through Portcullis
+func BrokerURL(mappingName string) string {
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(publicURL, "/"), mappingName)
+}
+
+//RegisterBroker creates a CF service broker with the given name that points at
+// the mapping of the same name
+func RegisterBroker(name string, reg Registration) error {
+	if err := checkCanRegister(); err != nil {
+		return err
+	}
+
+	log.Infof("Registering service broker %s with CF", name)
+	_, err := cfJSONRequest("POST", "/v2/service_brokers", serviceBroker{
+		Name:         name,
+		BrokerURL:    BrokerURL(name),
+		AuthUsername: reg.Username,
+		AuthPassword: reg.Password,
+		SpaceGUID:    reg.SpaceGUID,
+	}, http.StatusCreated)
+	return err
+}
+
+//UpdateBrokerRegistration points the CF service broker with the name oldName at
+// the mapping with the given name, renaming it to match. Credentials are only
+// changed if they are given. If CF has no such broker, it is registered.
+func UpdateBrokerRegistration(oldName, name string, reg Registration) error {
+	if err := checkCanRegister(); err != nil {
+		return err
+	}
+
+	existing, err := serviceBrokerByName(oldName)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		return RegisterBroker(name, reg)
+	}
+
+	//CF can't move a broker into or out of a space
+	if reg.SpaceGUID != "" && reg.SpaceGUID != existing.Entity.SpaceGUID {
+		return fmt.Errorf("Service broker %s is not registered in space %s, and cannot be moved there", oldName, reg.SpaceGUID)
+	}
+
+	log.Infof("Updating service broker %s in CF", oldName)
+	_, err = cfJSONRequest("PUT", "/v2/service_brokers/"+existing.Meta.Guid, serviceBroker{
+		Name:         name,
+		BrokerURL:    BrokerURL(name),
+		AuthUsername: reg.Username,
+		AuthPassword: reg.Password,
+	}, http.StatusOK)
+	return err
+}
+
+//UnregisterBroker deletes the CF service broker with the given name, if there
+// is one. CF refuses to delete brokers which still have service instances.
+func UnregisterBroker(name string) error {
+	if err := checkCanRegister(); err != nil {
+		return err
+	}
+
+	existing, err := serviceBrokerByName(name)
+	if err != nil || existing == nil {
+		return err
+	}
+
+	log.Infof("Deleting service broker %s from CF", name)
+	_, err = cfJSONRequest("DELETE", "/v2/service_brokers/"+existing.Meta.Guid, nil, http.StatusNoContent)
+	return err
+}
+
+func checkCanRegister() error {
+	if client == nil {
+		return fmt.Errorf("Not connected to Cloud Foundry")
+	}
+
+	if publicURL == "" {
+		return fmt.Errorf("`broker.public_url` must be set in config to register service brokers")
+	}
+	return nil
+}
+
+//serviceBrokerByName gets the CF service broker with the given name, or nil if
+// there isn't one
+func serviceBrokerByName(name string) (*serviceBrokerResource, error) {
+	body, err := cfJSONRequest("GET", "/v2/service_brokers?q="+url.QueryEscape("name:"+name), nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var brokerResp serviceBrokerResponse
+	err = json.Unmarshal(body, &brokerResp)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal service brokers from CF: %s", err)
+	}
+
+	for _, resource := range brokerResp.Resources {
+		if resource.Entity.Name == name {
+			return &resource, nil
+		}
+	}
+	return nil, nil
+}
+
+//cfJSONRequest sends a request to the CF API, with the given value as its JSON
+// body if it isn't nil. The response body is returned if the status code is the
+// expected one. Otherwise, the error includes the reason that CF gives.
+func cfJSONRequest(method, path string, value interface{}, expected int) ([]byte, error) {
+	req := client.NewRequest(method, path)
+	if value != nil {
+		reqBody, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		req = client.NewRequestWithBody(method, path, bytes.NewReader(reqBody))
+	}
+
+	resp, err := client.DoRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != expected {
+		var cfErr struct {
+			Description string `json:"description"`
+		}
+		if json.Unmarshal(body, &cfErr) == nil && cfErr.Description != "" {
+			return nil, fmt.Errorf("CF API returned with status code %d: %s", resp.StatusCode, cfErr.Description)
+		}
+		return nil, fmt.Errorf("CF API returned with status code %d", resp.StatusCode)
+	}
+	return body, nil
+}
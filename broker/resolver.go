@@ -0,0 +1,113 @@
+package broker
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
+	"github.com/cloudfoundry-community/portcullis/store"
+	"github.com/starkandwayne/goutils/log"
+)
+
+//resolveInterval is how long to wait between passes of re-resolving the
+// hostnames that security groups were made from. Zero or less turns it off.
+var resolveInterval time.Duration
+
+//LaunchResolver periodically re-resolves the hostnames behind every binding
+// whose rules were made from one, and updates its security group in CF if the
+// addresses have changed. Does not return unless re-resolution is turned off.
+func LaunchResolver() {
+	if resolveInterval <= 0 {
+		log.Infof("Hostname re-resolution is turned off")
+		return
+	}
+
+	log.Infof("Re-resolving hostnames every %s", resolveInterval)
+	for range time.Tick(resolveInterval) {
+		reresolveSecGroups()
+	}
+}
+
+//reresolveSecGroups does a single pass over the recorded bindings, and then
+// updates the security groups of any bindings whose addresses changed. The
+// hostnames are looked up without secGroupLock, so that binds and unbinds don't
+// wait on DNS. The lock is only taken to update each group in turn.
+func reresolveSecGroups() {
+	bindings, err := store.ListBindingInfo()
+	if err != nil {
+		log.Errorf("Resolver: Could not list bindings: %s", err)
+		return
+	}
+
+	changed := map[string][]resolvedBinding{}
+	for _, binding := range bindings {
+		if len(binding.SourceRules) == 0 {
+			continue
+		}
+
+		rules, err := bindparser.ResolveRules(binding.SourceRules)
+		if err != nil {
+			//Keep the last known addresses rather than locking the app out
+			log.Errorf("Resolver: Could not re-resolve rules for binding %s: %s", binding.BindingGUID, err)
+			continue
+		}
+
+		if reflect.DeepEqual(rules, binding.Rules) {
+			continue
+		}
+		changed[binding.SecGroupName] = append(changed[binding.SecGroupName], resolvedBinding{binding: binding, rules: rules})
+	}
+
+	for name, resolved := range changed {
+		err = updateResolvedSecGroup(name, resolved)
+		if err != nil {
+			log.Errorf("Resolver: Could not update security group %s: %s", name, err)
+		}
+	}
+}
+
+//resolvedBinding is a binding as it was read before its hostnames were looked
+// up, along with the rules that they now resolve to
+type resolvedBinding struct {
+	binding store.BindingInfo
+	rules   []bindparser.SecGroupRule
+}
+
+//updateResolvedSecGroup records the new rules of the given bindings, and then
+// brings the security group with the given name in line with them. A binding
+// that a bind or unbind changed since it was read is left for the next pass.
+func updateResolvedSecGroup(name string, resolved []resolvedBinding) error {
+	secGroupLock.Lock()
+	defer secGroupLock.Unlock()
+
+	changed := false
+	for _, r := range resolved {
+		current, err := store.GetBindingInfo(r.binding.BindingGUID)
+		if err == store.ErrNotFound {
+			log.Debugf("Resolver: Binding %s was unbound while its hostnames were resolved", r.binding.BindingGUID)
+			continue
+		}
+		if err != nil {
+			log.Errorf("Resolver: Could not get binding %s: %s", r.binding.BindingGUID, err)
+			continue
+		}
+		if !reflect.DeepEqual(current, r.binding) {
+			log.Debugf("Resolver: Binding %s changed while its hostnames were resolved", r.binding.BindingGUID)
+			continue
+		}
+
+		log.Infof("Resolver: Addresses for binding %s have changed to %s", current.BindingGUID, r.rules)
+		current.Rules = r.rules
+		err = store.EditBindingInfo(current.BindingGUID, current)
+		if err != nil {
+			log.Errorf("Resolver: Could not record new rules for binding %s: %s", current.BindingGUID, err)
+			continue
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return refreshSecGroup(name, "")
+}
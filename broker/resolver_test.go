@@ -0,0 +1,78 @@
+package broker_test
+
+import (
+	"net/http"
+
+	"github.com/cloudfoundry-community/portcullis/broker"
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Re-resolving hostnames", func() {
+	var cf *stubCF
+	var backend *stubBackend
+	var instanceGUID, bindingGUID, secGroupName string
+	var address string
+
+	BeforeEach(func() {
+		cf = newStubCF()
+		backend = newStubBackend()
+		mappingName := addTestMapping(backend.server.URL)
+		mapping, err := store.GetMapping(mappingName)
+		Expect(err).NotTo(HaveOccurred())
+		mapping.ResolveHostnames = true
+		Expect(store.EditMapping(mappingName, mapping)).To(Succeed())
+		cf.connectBroker(config.ServiceKeyPolicyPassthrough)
+
+		address = "10.0.0.5"
+		bindparser.SetResolver(func(host string) ([]string, error) {
+			return []string{address}, nil
+		})
+
+		instanceGUID, bindingGUID = genRandomString(), genRandomString()
+		secGroupName = "portcullis-" + instanceGUID + "-some-space"
+		backend.credentials = map[string]interface{}{"host": "db.example.com", "port": 5432}
+		response := brokerRequest("PUT", mappingName, bindingPath(instanceGUID, bindingGUID),
+			bindBody(cf.addApp("some-space", "some-org"), "some-space", "some-org"))
+		Expect(response.Code).To(Equal(http.StatusCreated))
+		Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.5"))
+	})
+
+	AfterEach(func() {
+		bindparser.SetResolver(nil)
+		cf.close()
+		backend.close()
+		clearStore()
+	})
+
+	JustBeforeEach(func() {
+		broker.ReresolveSecGroups()
+	})
+
+	Context("When the hostname still has the same address", func() {
+		It("should leave the security group alone", func() {
+			Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.5"))
+		})
+	})
+
+	Context("When the hostname has changed address", func() {
+		BeforeEach(func() {
+			address = "10.0.0.9"
+		})
+
+		It("should update the security group in CF", func() {
+			Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.9"))
+		})
+
+		It("should record the new rules for the binding", func() {
+			binding, err := store.GetBindingInfo(bindingGUID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(binding.Rules).To(HaveLen(1))
+			Expect(binding.Rules[0].Destination).To(Equal("10.0.0.9"))
+		})
+	})
+})
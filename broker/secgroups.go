@@ -0,0 +1,491 @@
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
+	"github.com/cloudfoundry-community/portcullis/store"
+	"github.com/starkandwayne/goutils/log"
+)
+
+//secGroupPrefix is prepended to the name of every security group that
+// Portcullis creates in Cloud Foundry
+const secGroupPrefix = "portcullis-"
+
+//secGroupName returns the name of the security group that Portcullis shares
+// between all of the bindings to the given service instance from apps in the
+// given space
+func secGroupName(instanceGUID, spaceGUID string) string {
+	return secGroupPrefix + instanceGUID + "-" + spaceGUID
+}
+
+//guidPattern matches the GUIDs that CF gives its resources
+const guidPattern = `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`
+
+var sharedSecGroupNameRegexp = regexp.MustCompile(`\A` + regexp.QuoteMeta(secGroupPrefix) + `(` + guidPattern + `)-(` + guidPattern + `)\z`)
+
+//parseSecGroupName returns the service instance and space GUIDs from the name
+// of a shared security group, as made by secGroupName. ok is false if the name
+// isn't exactly of that form.
+func parseSecGroupName(name string) (instanceGUID, spaceGUID string, ok bool) {
+	matches := sharedSecGroupNameRegexp.FindStringSubmatch(name)
+	if matches == nil {
+		return "", "", false
+	}
+	return matches[1], matches[2], true
+}
+
+//legacySecGroupName returns the name that Portcullis used to give the security
+// group it made for each individual binding, before groups were shared
+func legacySecGroupName(bindingGUID string) string {
+	return secGroupPrefix + bindingGUID
+}
+
+//secGroup is the entity of a CF security group resource. The vendored cfclient
+// can't read or write ICMP types and codes in rules, so Portcullis reads and
+// writes security groups with its own types.
+type secGroup struct {
+	Guid  string                    `json:"-"`
+	Name  string                    `json:"name"`
+	Rules []bindparser.SecGroupRule `json:"rules"`
+	//SpaceGUIDs is only sent when writing a group. It is omitted when empty, so
+	// that an update leaves the spaces of the group alone.
+	SpaceGUIDs []string `json:"space_guids,omitempty"`
+}
+
+type secGroupResource struct {
+	Meta struct {
+		Guid string `json:"guid"`
+	} `json:"metadata"`
+	Entity secGroup `json:"entity"`
+}
+
+type secGroupResponse struct {
+	NextUrl   string             `json:"next_url"`
+	Resources []secGroupResource `json:"resources"`
+}
+
+//secGroupLock is held while a shared security group and the bindings that
+// point at it are being changed, so that two bindings to the same instance
+// can't both create its group, or drop its last reference and add a new one
+// at the same time
+var secGroupLock sync.Mutex
+
+//secGroupsByName asks Cloud Foundry for all of the security groups with the
+// given name. CF doesn't enforce unique names, so there may be more than one.
+func secGroupsByName(name string) ([]secGroup, error) {
+	return listSecGroups(fmt.Sprintf("/v2/security_groups?q=%s", url.QueryEscape("name:"+name)))
+}
+
+//portcullisSecGroups asks Cloud Foundry for all of the security groups whose
+// names start with secGroupPrefix
+func portcullisSecGroups() ([]secGroup, error) {
+	groups, err := listSecGroups("/v2/security_groups")
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []secGroup
+	for _, group := range groups {
+		if strings.HasPrefix(group.Name, secGroupPrefix) {
+			ret = append(ret, group)
+		}
+	}
+	return ret, nil
+}
+
+//listSecGroups gets every page of security groups from the given CF API path
+func listSecGroups(path string) ([]secGroup, error) {
+	var ret []secGroup
+	for path != "" {
+		resp, err := client.DoRequest(client.NewRequest("GET", path))
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("CF API returned with status code %d", resp.StatusCode)
+		}
+
+		var secGroupResp secGroupResponse
+		err = json.Unmarshal(body, &secGroupResp)
+		if err != nil {
+			return nil, fmt.Errorf("Could not unmarshal security groups from CF: %s", err)
+		}
+
+		for _, resource := range secGroupResp.Resources {
+			resource.Entity.Guid = resource.Meta.Guid
+			ret = append(ret, resource.Entity)
+		}
+		path = secGroupResp.NextUrl
+	}
+	return ret, nil
+}
+
+//createSecGroup creates a security group in CF with the given rules, bound to
+// the spaces with the given GUIDs
+func createSecGroup(name string, rules []bindparser.SecGroupRule, spaceGUIDs []string) (*secGroup, error) {
+	body, err := cfJSONRequest("POST", "/v2/security_groups", secGroup{
+		Name:       name,
+		Rules:      rules,
+		SpaceGUIDs: spaceGUIDs,
+	}, http.StatusCreated)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalSecGroup(body)
+}
+
+//updateSecGroup replaces the name and rules of the security group in CF with
+// the given GUID. Its spaces are only replaced if spaceGUIDs isn't empty.
+func updateSecGroup(guid, name string, rules []bindparser.SecGroupRule, spaceGUIDs []string) (*secGroup, error) {
+	body, err := cfJSONRequest("PUT", "/v2/security_groups/"+guid, secGroup{
+		Name:       name,
+		Rules:      rules,
+		SpaceGUIDs: spaceGUIDs,
+	}, http.StatusCreated)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalSecGroup(body)
+}
+
+//deleteSecGroup deletes the security group in CF with the given GUID. A group
+// that CF doesn't have was deleted already, so that isn't an error.
+func deleteSecGroup(guid string) error {
+	resp, err := client.DoRequest(client.NewRequest("DELETE", "/v2/security_groups/"+guid))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		log.Debugf("Security group %s was already gone from CF", guid)
+		return nil
+	}
+	return fmt.Errorf("CF API returned with status code %d", resp.StatusCode)
+}
+
+//unmarshalSecGroup reads a security group resource returned by CF
+func unmarshalSecGroup(body []byte) (*secGroup, error) {
+	var resource secGroupResource
+	err := json.Unmarshal(body, &resource)
+	if err != nil {
+		return nil, fmt.Errorf("Could not unmarshal security group from CF: %s", err)
+	}
+	resource.Entity.Guid = resource.Meta.Guid
+	return &resource.Entity, nil
+}
+
+//cfResourceExists returns true if a GET of the given CF API path succeeds, and
+// false if CF says that there is no such thing
+func cfResourceExists(path string) (bool, error) {
+	resp, err := client.DoRequest(client.NewRequest("GET", path))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("CF API returned with status code %d for %s", resp.StatusCode, path)
+}
+
+//cfBindingExists returns true if CF still knows about the given binding.
+// Bindings without an app are service keys, which CF keeps apart from the
+// service bindings of apps.
+func cfBindingExists(binding store.BindingInfo) (bool, error) {
+	if binding.AppGUID == "" {
+		return cfResourceExists("/v2/service_keys/" + binding.BindingGUID)
+	}
+	return cfResourceExists("/v2/service_bindings/" + binding.BindingGUID)
+}
+
+//mergeRules combines the rules of all of the given bindings into the rules of
+// the security group that they share. Rules that more than one binding needs
+// only appear once, and the result is sorted so that it doesn't change with
+// the order the bindings were listed in.
+func mergeRules(bindings []store.BindingInfo) []bindparser.SecGroupRule {
+	seen := map[string]bindparser.SecGroupRule{}
+	keys := []string{}
+	for _, binding := range bindings {
+		for _, rule := range binding.Rules {
+			key := ruleKey(rule)
+			if _, found := seen[key]; !found {
+				seen[key] = rule
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	ret := make([]bindparser.SecGroupRule, 0, len(keys))
+	for _, key := range keys {
+		ret = append(ret, seen[key])
+	}
+	return ret
+}
+
+//ruleKey returns a string that is the same for any two rules that are the same
+func ruleKey(rule bindparser.SecGroupRule) string {
+	key, _ := json.Marshal(rule)
+	return string(key)
+}
+
+//addBindingSecGroup puts the rules of the given binding into the security
+// group shared by its service instance and space, making the group if this is
+// the first binding to need it. The binding is recorded in the store, which is
+// what keeps the group around until its last binding is gone.
+func addBindingSecGroup(binding store.BindingInfo, mappingName string) error {
+	secGroupLock.Lock()
+	defer secGroupLock.Unlock()
+
+	binding.SecGroupName = secGroupName(binding.ServiceInstanceGUID, binding.SpaceGUID)
+
+	//The Cloud Controller retries binds, so the binding may already be recorded
+	existing, err := store.GetBindingInfo(binding.BindingGUID)
+	switch {
+	case err == store.ErrNotFound:
+	case err != nil:
+		return err
+	case existing.SecGroupName == binding.SecGroupName:
+		return retryBindingSecGroup(binding)
+	default:
+		//Bindings from before groups were shared have a group of their own
+		log.Infof("Moving binding %s from security group %s to %s", binding.BindingGUID, existing.SecGroupName, binding.SecGroupName)
+		err = refreshSecGroup(existing.SecGroupName, existing.BindingGUID)
+		if err != nil && err != store.ErrNotFound {
+			return err
+		}
+		err = store.DeleteBindingInfo(existing.BindingGUID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = store.GetSecGroupInfoByName(binding.SecGroupName)
+	if err == store.ErrNotFound {
+		return createBindingSecGroup(binding, mappingName)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = store.AddBindingInfo(binding)
+	if err != nil {
+		return err
+	}
+
+	err = refreshSecGroup(binding.SecGroupName, "")
+	if err != nil {
+		//The group doesn't have this binding's rules, so don't count it
+		if delErr := store.DeleteBindingInfo(binding.BindingGUID); delErr != nil {
+			log.Errorf("Could not remove binding %s from store: %s", binding.BindingGUID, delErr)
+		}
+		return err
+	}
+	return nil
+}
+
+//retryBindingSecGroup makes sure that the security group of an already
+// recorded binding exists in CF and has the binding's current rules
+func retryBindingSecGroup(binding store.BindingInfo) error {
+	log.Debugf("Binding %s is already recorded, so checking its security group %s", binding.BindingGUID, binding.SecGroupName)
+	err := store.EditBindingInfo(binding.BindingGUID, binding)
+	if err != nil {
+		return err
+	}
+
+	info, err := store.GetSecGroupInfoByName(binding.SecGroupName)
+	if err != nil {
+		return err
+	}
+
+	actual, err := getSecGroup(info.SecGroupGUID)
+	if err != nil {
+		return err
+	}
+
+	if actual == nil {
+		bindings, err := store.ListBindingInfoBySecGroup(info.SecGroupName)
+		if err != nil {
+			return err
+		}
+		return recreateSecGroup(info, mergeRules(bindings))
+	}
+	return refreshSecGroup(binding.SecGroupName, "")
+}
+
+//recreateSecGroup makes the security group for the given record again, with
+// the given rules, after it has gone missing from CF
+func recreateSecGroup(info store.SecGroupInfo, rules []bindparser.SecGroupRule) error {
+	log.Debugf("Re-creating security group %s", info.SecGroupName)
+	secGroup, err := createSecGroup(info.SecGroupName, rules, []string{info.SpaceGUID})
+	if err != nil {
+		return err
+	}
+
+	info.SecGroupGUID = secGroup.Guid
+	info.Rules = rules
+	return store.EditSecGroupInfo(info.SecGroupName, info)
+}
+
+//createBindingSecGroup makes the security group for the first binding to an
+// instance from a space, and records both the group and the binding
+func createBindingSecGroup(binding store.BindingInfo, mappingName string) error {
+	rules := mergeRules([]store.BindingInfo{binding})
+
+	//A bind that didn't get as far as recording the group may have left it in
+	// CF, and there should only ever be one group with this name
+	existing, err := secGroupsByName(binding.SecGroupName)
+	if err != nil {
+		return err
+	}
+
+	var secGroup *secGroup
+	if len(existing) > 0 {
+		log.Debugf("Taking over untracked security group %s (%s)", existing[0].Name, existing[0].Guid)
+		secGroup, err = updateSecGroup(existing[0].Guid, binding.SecGroupName, rules, []string{binding.SpaceGUID})
+	} else {
+		log.Debugf("Creating security group %s", binding.SecGroupName)
+		secGroup, err = createSecGroup(binding.SecGroupName, rules, []string{binding.SpaceGUID})
+	}
+	if err != nil {
+		return err
+	}
+
+	//Keep track of what we made so that it can be cleaned up later
+	err = store.AddSecGroupInfo(store.SecGroupInfo{
+		ServiceInstanceGUID: binding.ServiceInstanceGUID,
+		SecGroupName:        secGroup.Name,
+		SpaceGUID:           binding.SpaceGUID,
+		MappingName:         mappingName,
+		SecGroupGUID:        secGroup.Guid,
+		Rules:               rules,
+	})
+	if err == nil {
+		err = store.AddBindingInfo(binding)
+		if err != nil {
+			if delErr := store.DeleteSecGroupInfoByName(secGroup.Name); delErr != nil {
+				log.Errorf("Could not remove security group %s from store: %s", secGroup.Name, delErr)
+			}
+		}
+	}
+
+	if err != nil {
+		log.Errorf("Could not record security group %s in store: %s", secGroup.Name, err)
+		//Don't leave a group lying around that nobody knows about
+		if delErr := client.DeleteSecGroup(secGroup.Guid); delErr != nil {
+			log.Errorf("Could not delete unrecorded security group %s: %s", secGroup.Name, delErr)
+		}
+		return err
+	}
+	return nil
+}
+
+//refreshSecGroup brings the rules of the security group with the given name
+// in line with the bindings that point at it, leaving out the binding with the
+// GUID given as without, if any. If no bindings are left, the group is deleted.
+// secGroupLock must be held.
+func refreshSecGroup(name, without string) error {
+	info, err := store.GetSecGroupInfoByName(name)
+	if err != nil {
+		return err
+	}
+
+	allBindings, err := store.ListBindingInfoBySecGroup(name)
+	if err != nil {
+		return err
+	}
+
+	bindings := []store.BindingInfo{}
+	for _, binding := range allBindings {
+		if binding.BindingGUID != without {
+			bindings = append(bindings, binding)
+		}
+	}
+
+	if len(bindings) == 0 {
+		log.Debugf("Deleting security group %s (%s), which has no bindings left", info.SecGroupName, info.SecGroupGUID)
+		err = deleteSecGroup(info.SecGroupGUID)
+		if err != nil {
+			return fmt.Errorf("Could not delete security group %s: %s", info.SecGroupName, err)
+		}
+		return store.DeleteSecGroupInfoByName(info.SecGroupName)
+	}
+
+	rules := mergeRules(bindings)
+	if reflect.DeepEqual(rules, info.Rules) {
+		return nil
+	}
+
+	log.Debugf("Updating security group %s (%s) for %d bindings", info.SecGroupName, info.SecGroupGUID, len(bindings))
+	_, err = updateSecGroup(info.SecGroupGUID, info.SecGroupName, rules, nil)
+	if err != nil {
+		return fmt.Errorf("Could not update security group %s: %s", info.SecGroupName, err)
+	}
+
+	info.Rules = rules
+	return store.EditSecGroupInfo(info.SecGroupName, info)
+}
+
+//deleteBindingSecGroups takes the binding with the given GUID out of the
+// security group it shares, deleting the group if it was the last binding to
+// need it. It is not an error for the binding to have no group.
+func deleteBindingSecGroups(bindingGUID string) error {
+	secGroupLock.Lock()
+	defer secGroupLock.Unlock()
+
+	binding, err := store.GetBindingInfo(bindingGUID)
+	if err != nil && err != store.ErrNotFound {
+		return err
+	}
+
+	if err == nil {
+		//Fix the group before forgetting the binding, so that a retried unbind
+		// can still find the group if this fails partway through
+		err = refreshSecGroup(binding.SecGroupName, bindingGUID)
+		if err != nil && err != store.ErrNotFound {
+			return err
+		}
+		return store.DeleteBindingInfo(bindingGUID)
+	}
+
+	//Not in the store, so it might be from before we kept track of groups.
+	// Look for it by name instead.
+	groups, err := secGroupsByName(legacySecGroupName(bindingGUID))
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		log.Debugf("Deleting untracked security group %s (%s)", group.Name, group.Guid)
+		err = deleteSecGroup(group.Guid)
+		if err != nil {
+			return fmt.Errorf("Could not delete security group %s: %s", group.Name, err)
+		}
+	}
+	return nil
+}
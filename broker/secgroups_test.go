@@ -0,0 +1,205 @@
+package broker_test
+
+import (
+	"net/http"
+
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shared security groups", func() {
+	var cf *stubCF
+	var backend *stubBackend
+	var mappingName, instanceGUID, secGroupName string
+	var firstGUID, secondGUID string
+
+	BeforeEach(func() {
+		cf = newStubCF()
+		backend = newStubBackend()
+		mappingName = addTestMapping(backend.server.URL)
+		cf.connectBroker(config.ServiceKeyPolicyPassthrough)
+
+		instanceGUID = genRandomString()
+		firstGUID, secondGUID = genRandomString(), genRandomString()
+		secGroupName = "portcullis-" + instanceGUID + "-some-space"
+	})
+
+	AfterEach(func() {
+		cf.close()
+		backend.close()
+		clearStore()
+	})
+
+	//bind makes a binding from a new app in some-space, for which the backend
+	// broker gives out the given host
+	bind := func(bindingGUID, host string) {
+		backend.credentials = map[string]interface{}{"host": host, "port": 6379}
+		response := brokerRequest("PUT", mappingName, bindingPath(instanceGUID, bindingGUID),
+			bindBody(cf.addApp("some-space", "some-org"), "some-space", "some-org"))
+		Expect(response.Code).To(Equal(http.StatusCreated))
+	}
+
+	unbind := func(bindingGUID string) {
+		response := brokerRequest("DELETE", mappingName,
+			bindingPath(instanceGUID, bindingGUID)+"?service_id=service-id&plan_id=plan-id", nil)
+		Expect(response.Code).To(Equal(http.StatusOK))
+	}
+
+	refCount := func() int {
+		count, err := store.SecGroupRefCount(secGroupName)
+		Expect(err).NotTo(HaveOccurred())
+		return count
+	}
+
+	Context("With two bindings from the same space", func() {
+		BeforeEach(func() {
+			bind(firstGUID, "10.0.0.5")
+			bind(secondGUID, "10.0.0.6")
+		})
+
+		It("should share one group between them", func() {
+			Expect(cf.secGroups()).To(HaveLen(1))
+			Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.5", "10.0.0.6"))
+			Expect(refCount()).To(Equal(2))
+		})
+
+		Context("When one of them is unbound", func() {
+			BeforeEach(func() {
+				unbind(firstGUID)
+			})
+
+			It("should keep the group, with only the rules of the other", func() {
+				Expect(cf.secGroups()).To(HaveLen(1))
+				Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.6"))
+
+				info, err := store.GetSecGroupInfoByName(secGroupName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Rules).To(HaveLen(1))
+				Expect(info.Rules[0].Destination).To(Equal("10.0.0.6"))
+			})
+
+			It("should drop the reference of the unbound binding", func() {
+				Expect(refCount()).To(Equal(1))
+				_, err := store.GetBindingInfo(firstGUID)
+				Expect(err).To(Equal(store.ErrNotFound))
+			})
+
+			Context("When the other is unbound too", func() {
+				BeforeEach(func() {
+					unbind(secondGUID)
+				})
+
+				It("should delete the group", func() {
+					Expect(cf.secGroups()).To(BeEmpty())
+					_, err := store.GetSecGroupInfoByName(secGroupName)
+					Expect(err).To(Equal(store.ErrNotFound))
+				})
+			})
+
+			Context("When the group is deleted from CF before the other is unbound", func() {
+				BeforeEach(func() {
+					cf.clearSecGroups()
+					unbind(secondGUID)
+				})
+
+				It("should forget the group", func() {
+					_, err := store.GetSecGroupInfoByName(secGroupName)
+					Expect(err).To(Equal(store.ErrNotFound))
+					Expect(refCount()).To(Equal(0))
+				})
+			})
+
+			Context("When the unbind is retried", func() {
+				BeforeEach(func() {
+					unbind(firstGUID)
+				})
+
+				It("should leave the other's egress alone", func() {
+					Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.6"))
+					Expect(refCount()).To(Equal(1))
+				})
+			})
+		})
+	})
+
+	Context("With two bindings that need the same rule", func() {
+		BeforeEach(func() {
+			bind(firstGUID, "10.0.0.5")
+			bind(secondGUID, "10.0.0.5")
+		})
+
+		It("should only put the rule in the group once", func() {
+			Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.5"))
+			Expect(refCount()).To(Equal(2))
+		})
+
+		It("should keep the rule while either binding needs it", func() {
+			unbind(firstGUID)
+			Expect(destinations(cf.secGroups()[secGroupName])).To(ConsistOf("10.0.0.5"))
+		})
+	})
+
+	Describe("Bindings from before groups were shared", func() {
+		var legacyName, legacyGUID string
+
+		BeforeEach(func() {
+			legacyName = "portcullis-" + firstGUID
+			legacyGUID = cf.addSecGroup(legacyName, "some-space")
+		})
+
+		Context("When the binding was never recorded", func() {
+			It("should find its group by name and delete it on unbind", func() {
+				cf.addSecGroup("portcullis-"+secondGUID, "some-space")
+				unbind(firstGUID)
+				secGroups := cf.secGroups()
+				Expect(secGroups).NotTo(HaveKey(legacyName))
+				Expect(secGroups).To(HaveKey("portcullis-" + secondGUID))
+			})
+		})
+
+		Context("When the binding was recorded with a group of its own", func() {
+			BeforeEach(func() {
+				rules := []bindparser.SecGroupRule{{Protocol: "tcp", Ports: "6379", Destination: "10.0.0.5"}}
+				Expect(store.AddSecGroupInfo(store.SecGroupInfo{
+					ServiceInstanceGUID: instanceGUID,
+					SecGroupName:        legacyName,
+					SpaceGUID:           "some-space",
+					MappingName:         mappingName,
+					SecGroupGUID:        legacyGUID,
+					Rules:               rules,
+				})).To(Succeed())
+				Expect(store.AddBindingInfo(store.BindingInfo{
+					BindingGUID:         firstGUID,
+					ServiceInstanceGUID: instanceGUID,
+					SecGroupName:        legacyName,
+					SpaceGUID:           "some-space",
+					OrganizationGUID:    "some-org",
+					Rules:               rules,
+				})).To(Succeed())
+			})
+
+			It("should delete the group on unbind", func() {
+				unbind(firstGUID)
+				Expect(cf.secGroups()).To(BeEmpty())
+				_, err := store.GetSecGroupInfoByName(legacyName)
+				Expect(err).To(Equal(store.ErrNotFound))
+			})
+
+			It("should move the binding into the shared group when CF retries the bind", func() {
+				bind(firstGUID, "10.0.0.5")
+				secGroups := cf.secGroups()
+				Expect(secGroups).NotTo(HaveKey(legacyName))
+				Expect(secGroups).To(HaveKey(secGroupName))
+
+				binding, err := store.GetBindingInfo(firstGUID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(binding.SecGroupName).To(Equal(secGroupName))
+				Expect(refCount()).To(Equal(1))
+			})
+		})
+	})
+})
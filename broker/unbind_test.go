@@ -0,0 +1,164 @@
+package broker_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry-community/portcullis/config"
+	"github.com/cloudfoundry-community/portcullis/store"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unbinding", func() {
+	var cf *stubCF
+	var backend *stubBackend
+	var mappingName, instanceGUID, bindingGUID, secGroupName string
+	var testResponse *httptest.ResponseRecorder
+
+	BeforeEach(func() {
+		cf = newStubCF()
+		backend = newStubBackend()
+		mappingName = addTestMapping(backend.server.URL)
+		cf.connectBroker(config.ServiceKeyPolicyPassthrough)
+
+		instanceGUID, bindingGUID = genRandomString(), genRandomString()
+		secGroupName = "portcullis-" + instanceGUID + "-some-space"
+		response := brokerRequest("PUT", mappingName, bindingPath(instanceGUID, bindingGUID),
+			bindBody(cf.addApp("some-space", "some-org"), "some-space", "some-org"))
+		Expect(response.Code).To(Equal(http.StatusCreated))
+		Expect(cf.secGroups()).To(HaveKey(secGroupName))
+	})
+
+	AfterEach(func() {
+		cf.close()
+		backend.close()
+		clearStore()
+	})
+
+	JustBeforeEach(func() {
+		testResponse = brokerRequest("DELETE", mappingName,
+			bindingPath(instanceGUID, bindingGUID)+"?service_id=service-id&plan_id=plan-id", nil)
+	})
+
+	assertEgressClosed := func() {
+		It("should delete the security group and forget the binding", func() {
+			Expect(cf.secGroups()).To(BeEmpty())
+			_, err := store.GetBindingInfo(bindingGUID)
+			Expect(err).To(Equal(store.ErrNotFound))
+			_, err = store.GetSecGroupInfoByName(secGroupName)
+			Expect(err).To(Equal(store.ErrNotFound))
+		})
+	}
+
+	assertEgressOpen := func() {
+		It("should leave the security group and binding alone", func() {
+			Expect(cf.secGroups()).To(HaveKey(secGroupName))
+			_, err := store.GetBindingInfo(bindingGUID)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	}
+
+	Context("When the backend broker removes the binding", func() {
+		It("should pass the backend broker's response through", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusOK))
+			Expect(backend.received("DELETE " + bindingPath(instanceGUID, bindingGUID))).To(ConsistOf(
+				"DELETE " + bindingPath(instanceGUID, bindingGUID) + "?service_id=service-id&plan_id=plan-id"))
+		})
+
+		assertEgressClosed()
+	})
+
+	Context("When the backend broker has already forgotten the binding", func() {
+		BeforeEach(func() {
+			backend.unbindStatus = http.StatusGone
+		})
+
+		It("should pass the 410 through", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusGone))
+		})
+
+		assertEgressClosed()
+	})
+
+	Context("When the backend broker fails to unbind", func() {
+		BeforeEach(func() {
+			backend.unbindStatus = http.StatusInternalServerError
+		})
+
+		It("should pass the failure through", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusInternalServerError))
+		})
+
+		assertEgressOpen()
+	})
+
+	Context("When CF can't delete the security group", func() {
+		BeforeEach(func() {
+			cf.Fail = true
+		})
+
+		It("should fail the unbind with an OSBAPI error, so that CF retries it", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusInternalServerError))
+			Expect(readJSONResponse(testResponse)["description"]).To(ContainSubstring("Portcullis could not remove egress"))
+		})
+
+		assertEgressOpen()
+
+		It("should clean up when CF retries the unbind", func() {
+			cf.Fail = false
+			backend.unbindStatus = http.StatusGone
+			response := brokerRequest("DELETE", mappingName,
+				bindingPath(instanceGUID, bindingGUID)+"?service_id=service-id&plan_id=plan-id", nil)
+			Expect(response.Code).To(Equal(http.StatusGone))
+			Expect(cf.secGroups()).To(BeEmpty())
+		})
+	})
+
+	Context("When the backend broker unbinds asynchronously", func() {
+		BeforeEach(func() {
+			backend.unbindStatus = http.StatusAccepted
+		})
+
+		It("should pass the 202 through", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusAccepted))
+		})
+
+		It("should record the unbind as pending", func() {
+			pending, err := store.GetPendingBind(bindingGUID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending.Unbind).To(BeTrue())
+			Expect(pending.MappingName).To(Equal(mappingName))
+		})
+
+		assertEgressOpen()
+	})
+
+	Context("When a binding to another instance has egress open", func() {
+		var otherGroupName string
+
+		BeforeEach(func() {
+			otherInstanceGUID := genRandomString()
+			otherGroupName = "portcullis-" + otherInstanceGUID + "-some-space"
+			response := brokerRequest("PUT", mappingName, bindingPath(otherInstanceGUID, genRandomString()),
+				bindBody(cf.addApp("some-space", "some-org"), "some-space", "some-org"))
+			Expect(response.Code).To(Equal(http.StatusCreated))
+		})
+
+		It("should only delete the security group of the unbound binding", func() {
+			Expect(cf.secGroups()).To(ConsistOf(HaveKeyWithValue("name", otherGroupName)))
+		})
+	})
+
+	Context("When Portcullis never opened egress for the binding", func() {
+		BeforeEach(func() {
+			bindingGUID = genRandomString()
+		})
+
+		It("should pass the unbind through", func() {
+			Expect(testResponse.Code).To(Equal(http.StatusOK))
+			Expect(cf.secGroups()).To(HaveKey(secGroupName))
+		})
+	})
+})
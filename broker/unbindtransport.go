@@ -0,0 +1,90 @@
+package broker
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cloudfoundry-community/portcullis/store"
+	"github.com/starkandwayne/goutils/log"
+)
+
+//UnbindTransport is an http.RoundTripper which forwards an unbind request to
+// the backend broker, and then takes the binding's rules out of its security
+// group once the broker confirms that the binding is gone. The group itself is
+// deleted if no other bindings are using it. If the broker unbinds
+// asynchronously, the unbind is recorded, and the rules are taken out once a
+// last_operation poll says that it has finished.
+type UnbindTransport struct {
+	MappingName  string
+	InstanceGUID string
+	BindingGUID  string
+}
+
+func (u *UnbindTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil {
+		return resp, err
+	}
+
+	switch resp.StatusCode {
+	//410 means the broker already forgot about the binding, but we may not have
+	// cleaned up after it yet (e.g. a CF retry after a failed deletion)
+	case http.StatusOK, http.StatusGone:
+		log.Debugf("UnbindTransport: Binding %s removed by broker", u.BindingGUID)
+		//An asynchronous bind may never have been seen to finish
+		if err = store.DeletePendingBind(u.BindingGUID); err != nil && err != store.ErrNotFound {
+			log.Errorf("UnbindTransport: Could not remove pending bind %s: %s", u.BindingGUID, err)
+		}
+		err = deleteBindingSecGroups(u.BindingGUID)
+		if err != nil {
+			log.Errorf("UnbindTransport: %s", err)
+			//The Cloud Controller retries failed unbinds, which gives another
+			// go at cleaning up
+			resp.Body.Close()
+			return errorResponse(r, http.StatusInternalServerError, "",
+				fmt.Sprintf("Portcullis could not remove egress for the binding: %s", err)), nil
+		}
+	case http.StatusAccepted:
+		log.Debugf("UnbindTransport: Binding %s is being removed asynchronously", u.BindingGUID)
+		err = u.recordPending()
+		if err != nil {
+			log.Errorf("UnbindTransport: Could not record asynchronous unbind %s: %s", u.BindingGUID, err)
+			//As above, the Cloud Controller will retry the unbind
+			resp.Body.Close()
+			return errorResponse(r, http.StatusInternalServerError, "",
+				fmt.Sprintf("Portcullis could not record the asynchronous unbind: %s", err)), nil
+		}
+	}
+	return resp, nil
+}
+
+//recordPending stores the unbind as pending, in place of the record of a bind
+// that may not have finished yet. A record that is already there for an unbind
+// is left alone, as it will be when the Cloud Controller retries.
+func (u *UnbindTransport) recordPending() error {
+	existing, err := store.GetPendingBind(u.BindingGUID)
+	switch {
+	case err == store.ErrNotFound:
+	case err != nil:
+		return err
+	case existing.Unbind:
+		return nil
+	default:
+		err = store.DeletePendingBind(u.BindingGUID)
+		if err != nil && err != store.ErrNotFound {
+			return err
+		}
+	}
+
+	err = store.AddPendingBind(store.PendingBind{
+		BindingGUID:         u.BindingGUID,
+		ServiceInstanceGUID: u.InstanceGUID,
+		MappingName:         u.MappingName,
+		Unbind:              true,
+	})
+	if err == store.ErrDuplicate {
+		//A retry got there first
+		return nil
+	}
+	return err
+}
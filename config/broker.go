@@ -6,4 +6,32 @@ type BrokerConfig struct {
 	CFAPIAddress string `yaml:"cf_api_address"`
 	CFAdmin      string `yaml:"cf_admin"`
 	CFPassword   string `yaml:"cf_password"`
+	//PublicURL is the URL that the Cloud Controller reaches the broker server
+	// at. It is only needed for registering service brokers with CF.
+	PublicURL string `yaml:"public_url"`
+	//ResolveInterval is how many seconds to wait between re-resolving the
+	// hostnames in bind credentials. Negative values turn re-resolution off.
+	ResolveInterval int `yaml:"resolve_interval"`
+	//ReconcileInterval is how many seconds to wait between passes of cleaning up
+	// security groups that don't match the bindings. The reconciler is off unless
+	// this is more than zero.
+	ReconcileInterval int `yaml:"reconcile_interval"`
+	//ReconcileDryRun makes the reconciler log what it would change instead of
+	// changing it
+	ReconcileDryRun bool `yaml:"reconcile_dry_run"`
+	//ServiceKeyPolicy is what to do with bindings that aren't for an app, such
+	// as service keys. One of the ServiceKeyPolicy constants.
+	ServiceKeyPolicy string `yaml:"service_key_policy"`
 }
+
+//Values for BrokerConfig.ServiceKeyPolicy
+const (
+	//ServiceKeyPolicyPassthrough passes the backend broker's response through
+	// without opening any egress
+	ServiceKeyPolicyPassthrough = "passthrough"
+	//ServiceKeyPolicySpace opens egress for the space that the binding was made
+	// in, as given by the request context
+	ServiceKeyPolicySpace = "space"
+	//ServiceKeyPolicyReject fails the bind, and undoes it with the backend broker
+	ServiceKeyPolicyReject = "reject"
+)
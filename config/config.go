@@ -47,6 +47,7 @@ func Load(path string) (c Config, err error) {
 func (c *Config) setDefaults() {
 	const defaultAPIDescription = "Portcullis API"
 	const defaultLogLevel = "info"
+	const defaultResolveInterval = 300
 
 	if c.API.Description == "" {
 		log.Infof("Setting API Description to default: %s", defaultAPIDescription)
@@ -57,6 +58,16 @@ func (c *Config) setDefaults() {
 		log.Infof("Setting Log Level to default: %s", defaultLogLevel)
 		c.LogLevel = defaultLogLevel
 	}
+
+	if c.Broker.ResolveInterval == 0 {
+		log.Infof("Setting Broker Resolve Interval to default: %d", defaultResolveInterval)
+		c.Broker.ResolveInterval = defaultResolveInterval
+	}
+
+	if c.Broker.ServiceKeyPolicy == "" {
+		log.Infof("Setting Broker Service Key Policy to default: %s", ServiceKeyPolicyPassthrough)
+		c.Broker.ServiceKeyPolicy = ServiceKeyPolicyPassthrough
+	}
 }
 
 func (c *Config) verifyBaseConfig() error {
@@ -65,6 +76,12 @@ func (c *Config) verifyBaseConfig() error {
 		return fmt.Errorf("Configured log level was not understood: %s", c.LogLevel)
 	}
 
+	switch c.Broker.ServiceKeyPolicy {
+	case ServiceKeyPolicyPassthrough, ServiceKeyPolicySpace, ServiceKeyPolicyReject:
+	default:
+		return fmt.Errorf("Configured broker service key policy was not understood: %s", c.Broker.ServiceKeyPolicy)
+	}
+
 	if c.LogLevel == "off" {
 		//We don't use "emerg" level in the program, so this squelches everything
 		c.LogLevel = "emerg"
@@ -10,10 +10,11 @@ import (
 var _ = Describe("Config", func() {
 	Describe("Load", func() {
 		var path string
+		var conf Config
 		var err error
 
 		JustBeforeEach(func() {
-			_, err = Load(path)
+			conf, err = Load(path)
 		})
 
 		Context("when given a non-existent file name", func() {
@@ -54,6 +55,32 @@ var _ = Describe("Config", func() {
 			It("should not return an error", func() {
 				Expect(err).To(BeNil())
 			})
+
+			It("should default the broker resolve interval", func() {
+				Expect(conf.Broker.ResolveInterval).To(Equal(300))
+			})
+
+			It("should leave the reconciler off", func() {
+				Expect(conf.Broker.ReconcileInterval).To(Equal(0))
+			})
+
+			It("should not default to a reconciler dry run", func() {
+				Expect(conf.Broker.ReconcileDryRun).To(BeFalse())
+			})
+
+			It("should default to passing service keys through", func() {
+				Expect(conf.Broker.ServiceKeyPolicy).To(Equal(ServiceKeyPolicyPassthrough))
+			})
+		})
+
+		Context("given a service key policy that doesn't exist", func() {
+			BeforeEach(func() {
+				path = confAssets("bad_service_key_policy.yml")
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
 		})
 	})
 })
@@ -91,7 +91,7 @@ func validateConfKey(key string) {
 
 func errorFromMessages(messages []string) (err error) {
 	if len(messages) > 0 {
-		err = fmt.Errorf(strings.Join(messages, "\n"))
+		err = fmt.Errorf("%s", strings.Join(messages, "\n"))
 	}
 	return err
 }
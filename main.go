@@ -1,10 +1,18 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
 
 	"github.com/cloudfoundry-community/portcullis/api"
 	"github.com/cloudfoundry-community/portcullis/broker"
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
 	"github.com/cloudfoundry-community/portcullis/config"
 	"github.com/cloudfoundry-community/portcullis/store"
 	"github.com/starkandwayne/goutils/log"
@@ -18,21 +26,48 @@ var (
 	cmdLine        = kingpin.New("portcullis", "A server which makes managing your CF service brokers easier").Version("portcullis " + config.Version)
 	configPath     = cmdLine.Flag("config", "The path to the configuration file").Short('c').Default(os.Getenv("PORTCULLIS_CONFIG")).PlaceHolder("/path/to/config").String()
 	skipBrokerFlag = cmdLine.Flag("test-without-broker", "Skip starting the broker server").Hidden().Bool()
+	apiURLFlag     = cmdLine.Flag("api-url", "The URL of the running Portcullis API, for the commands that use it. Defaults to the configured API port on this host").PlaceHolder("http://host:port").String()
+
+	serveCmd        = cmdLine.Command("serve", "Run the Portcullis API and broker servers").Default()
+	driftCmd        = cmdLine.Command("drift", "Ask the running Portcullis to report the security groups in CF that differ from what their bindings expect. Exits non-zero if any drift is left unrepaired")
+	driftRepairFlag = driftCmd.Flag("repair", "Re-apply the expected rules and spaces to groups that have drifted").Bool()
+
+	adoptCmd          = cmdLine.Command("adopt", "Move an existing CF service broker behind a running Portcullis, creating a mapping to its current URL")
+	adoptBrokerArg    = adoptCmd.Arg("cf-broker-name", "The name of the service broker in CF").Required().String()
+	adoptUsernameFlag = adoptCmd.Flag("username", "The username that CF gives the broker, used to check its catalog through Portcullis. The password is read from $"+adoptPasswordEnv+", or from standard input").String()
+	adoptConfigFlag   = adoptCmd.Flag("bind-config", "A JSON file with the bind config list for the new mapping. Needed unless the mapping already exists").PlaceHolder("/path/to/bind_config.json").ExistingFile()
+	adoptRollbackFlag = adoptCmd.Flag("rollback", "Point an adopted broker back at its original URL instead").Bool()
+
+	backfillCmd        = cmdLine.Command("backfill", "Ask the running Portcullis to create security groups for the CF service bindings of a mapping that were made before it was behind Portcullis. Exits non-zero if any binding fails")
+	backfillMappingArg = backfillCmd.Arg("mapping", "The name of the mapping").Required().String()
+	backfillDryRunFlag = backfillCmd.Flag("dry-run", "Report what would be created without changing anything").Bool()
 )
 
+//adoptPasswordEnv is the environment variable that the adopt command reads the
+// broker's password from, so that it isn't in the process list
+const adoptPasswordEnv = "PORTCULLIS_BROKER_PASSWORD"
+
 func main() {
 	cmdLine.HelpFlag.Short('h')
 	cmdLine.VersionFlag.Short('v')
 	command := kingpin.MustParse(cmdLine.Parse(os.Args[1:]))
 	switch command {
-	case "":
+	case serveCmd.FullCommand():
 		initializePortcullis()
+	case driftCmd.FullCommand():
+		checkDrift()
+	case adoptCmd.FullCommand():
+		adoptBroker()
+	case backfillCmd.FullCommand():
+		backfillBindings()
 	default:
 		bailWith("Unrecognized command: %s", command)
 	}
 }
 
-func initializePortcullis() {
+//loadConfig sets up logging from the config file, and returns the config,
+// which every command needs
+func loadConfig() config.Config {
 	//Need a default logging endpoint if the program needs to log before the config
 	// can be loaded
 	log.SetupLogging(log.LogConfig{
@@ -55,8 +90,12 @@ func initializePortcullis() {
 	})
 
 	log.Debugf("Logging settings configured")
+	return conf
+}
 
-	err = store.SetStoreType(conf.Store.Type)
+//initializeStore sets up the store from the config, for the server
+func initializeStore(conf config.Config) {
+	err := store.SetStoreType(conf.Store.Type)
 	if err != nil {
 		bailWith("Error while setting store type: %s", err)
 	}
@@ -64,7 +103,23 @@ func initializePortcullis() {
 	if err != nil {
 		bailWith("Error while initializing store: %s", err)
 	}
-	err = api.Initialize(conf.API)
+}
+
+//connectAPI makes a client for the API of the running server that the config
+// is for
+func connectAPI(conf config.Config) *apiClient {
+	client, err := newAPIClient(conf.API, *apiURLFlag)
+	if err != nil {
+		bailWith("Error while setting up API client: %s", err)
+	}
+	return client
+}
+
+func initializePortcullis() {
+	conf := loadConfig()
+	initializeStore(conf)
+
+	err := api.Initialize(conf.API)
 	if err != nil {
 		bailWith("Error while initializing API server: %s", err)
 	}
@@ -85,6 +140,8 @@ func initializePortcullis() {
 	brokerChan := make(chan error)
 	if !*skipBrokerFlag {
 		go broker.Launch(brokerChan)
+		go broker.LaunchResolver()
+		go broker.LaunchReconciler()
 	} else {
 		log.Infof("Skipping broker launch")
 	}
@@ -97,6 +154,142 @@ func initializePortcullis() {
 	}
 }
 
+//checkDrift asks the running server to check for drift, because a repair has
+// to hold the same lock as the server's binds and unbinds
+func checkDrift() {
+	client := connectAPI(loadConfig())
+
+	method, path := "GET", "/drift"
+	if *driftRepairFlag {
+		method, path = "POST", "/drift/repair"
+	}
+	var resp api.DriftResponse
+	err := client.do(method, path, nil, &resp)
+	if err != nil {
+		bailWith("Error while checking for drift: %s", err)
+	}
+	drifts := resp.Drift
+
+	output, err := json.MarshalIndent(drifts, "", "  ")
+	if err != nil {
+		bailWith("Error while writing out drift: %s", err)
+	}
+	fmt.Println(string(output))
+
+	for _, drift := range drifts {
+		if !drift.Repaired {
+			os.Exit(1)
+		}
+	}
+}
+
+//adoptBroker asks the running server to adopt or roll back a broker, because
+// the mapping it makes is used by the server
+func adoptBroker() {
+	client := connectAPI(loadConfig())
+
+	var adoption broker.Adoption
+	if *adoptRollbackFlag {
+		err := client.do("POST", "/adopt/"+*adoptBrokerArg+"/rollback", nil, &adoption)
+		if err != nil {
+			bailWith("Error while rolling back adoption: %s", err)
+		}
+		printJSON(adoption)
+		return
+	}
+
+	if *adoptUsernameFlag == "" {
+		bailWith("Please give the broker's username with `--username`")
+	}
+
+	var bindConfig bindparser.ConfigList
+	if *adoptConfigFlag != "" {
+		configBytes, err := ioutil.ReadFile(*adoptConfigFlag)
+		if err == nil {
+			err = json.Unmarshal(configBytes, &bindConfig)
+		}
+		if err != nil {
+			bailWith("Error while reading bind config: %s", err)
+		}
+	} else {
+		//Without a bind config, the adoption can only use an existing mapping
+		err := client.do("GET", "/mappings/"+*adoptBrokerArg, nil, nil)
+		if apiErr, isAPIErr := err.(apiError); isAPIErr && apiErr.StatusCode == http.StatusNotFound {
+			bailWith("There is no mapping named `%s` yet, so please give a bind config for it with `--bind-config`", *adoptBrokerArg)
+		}
+		if err != nil {
+			bailWith("Error while looking up mapping: %s", err)
+		}
+	}
+
+	password := readAdoptPassword()
+
+	body := map[string]interface{}{
+		"username": *adoptUsernameFlag,
+		"password": password,
+	}
+	if bindConfig != nil {
+		body["bind_config"] = bindConfig
+	}
+	err := client.do("POST", "/adopt/"+*adoptBrokerArg, body, &adoption)
+	if err != nil {
+		bailWith("Error while adopting service broker: %s", err)
+	}
+	printJSON(adoption)
+}
+
+//readAdoptPassword gets the broker's password from the environment, or else
+// reads it from the first line of standard input
+func readAdoptPassword() string {
+	if password := os.Getenv(adoptPasswordEnv); password != "" {
+		return password
+	}
+
+	fmt.Fprintf(os.Stderr, "Broker password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		bailWith("Error while reading broker password: %s", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		bailWith("Please give the broker's password in $%s or on standard input", adoptPasswordEnv)
+	}
+	return password
+}
+
+//backfillBindings asks the running server to backfill a mapping's bindings,
+// because the security groups it makes have to be changed under the same lock
+// as the server's binds and unbinds
+func backfillBindings() {
+	client := connectAPI(loadConfig())
+
+	method := "POST"
+	if *backfillDryRunFlag {
+		method = "GET"
+	}
+	var resp api.BackfillResponse
+	err := client.do(method, "/mappings/"+*backfillMappingArg+"/backfill", nil, &resp)
+	if err != nil {
+		bailWith("Error while backfilling bindings: %s", err)
+	}
+	printJSON(resp.Bindings)
+
+	for _, backfill := range resp.Bindings {
+		if backfill.Status == broker.BackfillFailed {
+			os.Exit(1)
+		}
+	}
+}
+
+//printJSON writes the given value out as indented JSON
+func printJSON(value interface{}) {
+	output, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		bailWith("Error while writing out JSON: %s", err)
+	}
+	fmt.Println(string(output))
+}
+
 func bailWith(mess string, args ...interface{}) {
 	log.Critf(mess, args...)
 	os.Exit(1)
@@ -0,0 +1,17 @@
+package store
+
+import "time"
+
+//AccessChange records a change to which orgs can see a service plan of a
+// mapping's service broker in CF, and who asked for it
+type AccessChange struct {
+	MappingName      string `json:"mapping_name"`
+	OrganizationGUID string `json:"organization_guid"`
+	ServiceName      string `json:"service"`
+	PlanName         string `json:"plan"`
+	PlanGUID         string `json:"plan_guid"`
+	//Granted is true if the org was given access, and false if it was revoked
+	Granted bool      `json:"granted"`
+	User    string    `json:"user"`
+	Time    time.Time `json:"time"`
+}
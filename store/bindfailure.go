@@ -0,0 +1,20 @@
+package store
+
+import "time"
+
+//BindFailure records a bind that the backend broker accepted, but that
+// Portcullis could not open egress for. Portcullis asks the backend broker to
+// unbind in that case, so that nothing is left behind that CF doesn't know
+// about.
+type BindFailure struct {
+	BindingGUID         string    `json:"binding_guid"`
+	ServiceInstanceGUID string    `json:"service_instance_guid"`
+	MappingName         string    `json:"mapping_name"`
+	Time                time.Time `json:"time"`
+	//Error is why egress could not be opened
+	Error string `json:"error"`
+	//Unbound is true if the backend broker confirmed the compensating unbind
+	Unbound bool `json:"unbound"`
+	//UnbindError is why the compensating unbind failed, if it did
+	UnbindError string `json:"unbind_error,omitempty"`
+}
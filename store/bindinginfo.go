@@ -0,0 +1,34 @@
+package store
+
+import "github.com/cloudfoundry-community/portcullis/broker/bindparser"
+
+//BindingInfo contains the information about a service binding that Portcullis
+// opened egress for, and the name of the security group that its rules were
+// put into. The number of BindingInfo objects with a given SecGroupName is the
+// reference count of that security group.
+type BindingInfo struct {
+	BindingGUID         string                    `json:"binding_guid"`
+	ServiceInstanceGUID string                    `json:"service_instance_guid"`
+	SecGroupName        string                    `json:"secgroup_name"`
+	AppGUID             string                    `json:"app_guid"`
+	SpaceGUID           string                    `json:"space_guid"`
+	OrganizationGUID    string                    `json:"organization_guid"`
+	Rules               []bindparser.SecGroupRule `json:"rules"`
+	//SourceRules are the rules as the bind flavors made them, before hostnames
+	// were resolved into addresses. Empty if no hostnames needed resolving.
+	SourceRules []bindparser.SecGroupRule `json:"source_rules,omitempty"`
+}
+
+//WithBindingGUID returns a copy of the receiver BindingInfo, except that the
+// BindingGUID is set to the given string
+func (b BindingInfo) WithBindingGUID(guid string) BindingInfo {
+	b.BindingGUID = guid
+	return b
+}
+
+//WithGroupName returns a copy of the receiver BindingInfo, except that the
+// SecGroupName is set to the given string
+func (b BindingInfo) WithGroupName(name string) BindingInfo {
+	b.SecGroupName = name
+	return b
+}
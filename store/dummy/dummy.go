@@ -8,6 +8,7 @@ package dummy
 import (
 	"fmt"
 
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
 	"github.com/cloudfoundry-community/portcullis/config"
 	"github.com/cloudfoundry-community/portcullis/store"
 )
@@ -19,6 +20,10 @@ import (
 type Dummy struct {
 	storage     map[string]store.Mapping
 	secgroups   map[string]store.SecGroupInfo
+	bindings    map[string]store.BindingInfo
+	failures    []store.BindFailure
+	pending     map[string]store.PendingBind
+	changes     []store.AccessChange
 	initialized bool
 }
 
@@ -47,6 +52,11 @@ func (d *Dummy) Initialize(conf map[string]interface{}) error {
 	}
 
 	d.storage = map[string]store.Mapping{}
+	d.secgroups = map[string]store.SecGroupInfo{}
+	d.bindings = map[string]store.BindingInfo{}
+	d.failures = []store.BindFailure{}
+	d.pending = map[string]store.PendingBind{}
+	d.changes = []store.AccessChange{}
 	d.initialized = true
 	return nil
 }
@@ -89,7 +99,7 @@ func (d *Dummy) AddMapping(m store.Mapping) error {
 		return store.ErrDuplicate
 	}
 
-	d.storage[m.Name] = m
+	d.storage[m.Name] = copyMapping(m)
 	return nil
 }
 
@@ -117,10 +127,19 @@ func (d *Dummy) EditMapping(name string, m store.Mapping) error {
 	}
 
 	delete(d.storage, name)
-	d.storage[m.Name] = m
+	d.storage[m.Name] = copyMapping(m)
 	return nil
 }
 
+//copyMapping gives the stored mapping its own list of bind configs, so that
+// changes the caller makes to theirs don't leak into the store
+func copyMapping(m store.Mapping) store.Mapping {
+	if m.BindConfig != nil {
+		m.BindConfig = append(bindparser.ConfigList{}, m.BindConfig...)
+	}
+	return m
+}
+
 //DeleteMapping removes a mapping from the Dummy store if it exists, and
 // returns an error otherwise
 func (d *Dummy) DeleteMapping(name string) error {
@@ -157,83 +176,120 @@ func (d *Dummy) ClearMappings() error {
 
 //GetSecGroupInfoByName returns the SecGroupInfo in the map with that name if it
 // exists and returns ErrNotFound otherwise
-// The map is indexed by ServiceInstanceGUID, not this, so this access takes O(n)
 func (d *Dummy) GetSecGroupInfoByName(name string) (result store.SecGroupInfo, err error) {
 	if !d.initialized {
 		return result, fmt.Errorf("Dummy not initialized")
 	}
 
-	for _, secgroup := range d.secgroups {
-		if secgroup.SecGroupName == name {
-			return secgroup, nil
-		}
+	if secgroup, ok := d.secgroups[name]; ok {
+		return secgroup, nil
 	}
 
 	return result, store.ErrNotFound
 }
 
-//GetSecGroupInfoByInstance returns the SecGroupInfo in the map with the given
-// ServiceInstanceGUID value if it exists, and returns ErrNotFound otherwise.
-func (d *Dummy) GetSecGroupInfoByInstance(GUID string) (result store.SecGroupInfo, err error) {
+//ListSecGroupInfoByInstance returns all the SecGroupInfo objects in the map with
+// the given ServiceInstanceGUID value. This access takes O(n)
+func (d *Dummy) ListSecGroupInfoByInstance(GUID string) ([]store.SecGroupInfo, error) {
 	if !d.initialized {
-		return result, fmt.Errorf("Dummy not initialized")
+		return nil, fmt.Errorf("Dummy not initialized")
 	}
 
-	if secgroup, ok := d.secgroups[GUID]; ok {
-		return secgroup, nil
+	ret := []store.SecGroupInfo{}
+	for _, secgroup := range d.secgroups {
+		if secgroup.ServiceInstanceGUID == GUID {
+			ret = append(ret, secgroup)
+		}
 	}
+	return ret, nil
+}
 
-	return result, store.ErrNotFound
+//ListSecGroupInfo returns all of the SecGroupInfo objects in the map
+func (d *Dummy) ListSecGroupInfo() ([]store.SecGroupInfo, error) {
+	if !d.initialized {
+		return nil, fmt.Errorf("Dummy not initialized")
+	}
+
+	ret := []store.SecGroupInfo{}
+	for _, secgroup := range d.secgroups {
+		ret = append(ret, secgroup)
+	}
+	return ret, nil
 }
 
 //AddSecGroupInfo puts a copy of the given SecGroupInfo object into the map.
-// ErrDuplicate is thrown if a SecGroupInfo with that ServiceInstanceGUID already
+// ErrDuplicate is thrown if a SecGroupInfo with that SecGroupName already
 // exists.
 func (d *Dummy) AddSecGroupInfo(toAdd store.SecGroupInfo) error {
 	if !d.initialized {
 		return fmt.Errorf("Dummy not initialized")
 	}
 
-	if _, exists := d.secgroups[toAdd.ServiceInstanceGUID]; exists {
+	if _, exists := d.secgroups[toAdd.SecGroupName]; exists {
 		return store.ErrDuplicate
 	}
 
-	d.secgroups[toAdd.ServiceInstanceGUID] = toAdd
+	d.secgroups[toAdd.SecGroupName] = toAdd
 
 	return nil
 }
 
-//DeleteSecGroupInfoByInstance finds the SecGroupInfo object in the map with the
-// given ServiceInstanceGUID and then, if it exists, it removes it from the map.
-// Otherwise, it returns ErrNotFound
+//EditSecGroupInfo replaces the SecGroupInfo object in the map with the given
+// SecGroupName with the given SecGroupInfo object. Returns ErrNotFound if
+// there is no such object, and ErrDuplicate if the new name is already taken
+// by a different object
+func (d *Dummy) EditSecGroupInfo(name string, changeTo store.SecGroupInfo) error {
+	if !d.initialized {
+		return fmt.Errorf("Dummy not initialized")
+	}
+
+	if _, exists := d.secgroups[name]; !exists {
+		return store.ErrNotFound
+	}
+
+	if changeTo.SecGroupName != name {
+		if _, exists := d.secgroups[changeTo.SecGroupName]; exists {
+			return store.ErrDuplicate
+		}
+	}
+
+	delete(d.secgroups, name)
+	d.secgroups[changeTo.SecGroupName] = changeTo
+	return nil
+}
+
+//DeleteSecGroupInfoByInstance finds the SecGroupInfo objects in the map with
+// the given ServiceInstanceGUID and then, if there are any, it removes them
+// from the map. Otherwise, it returns ErrNotFound
 func (d *Dummy) DeleteSecGroupInfoByInstance(GUID string) error {
 	if !d.initialized {
 		return fmt.Errorf("Dummy not initialized")
 	}
 
-	if _, found := d.secgroups[GUID]; !found {
+	secgroups, _ := d.ListSecGroupInfoByInstance(GUID)
+	if len(secgroups) == 0 {
 		return store.ErrNotFound
 	}
 
-	delete(d.secgroups, GUID)
+	for _, secgroup := range secgroups {
+		delete(d.secgroups, secgroup.SecGroupName)
+	}
 	return nil
 }
 
 //DeleteSecGroupInfoByName finds the SecGroupInfo object in the map with the
 // given SecGroupName and then, if it exists, it removes it from the map.
 // Otherwise, it returns err not found.
-// The search for the item to delete takes O(n)
 func (d *Dummy) DeleteSecGroupInfoByName(name string) error {
 	if !d.initialized {
 		return fmt.Errorf("Dummy not initialized")
 	}
 
-	secgroup, err := d.GetSecGroupInfoByName(name)
-	if err != nil {
-		return err
+	if _, exists := d.secgroups[name]; !exists {
+		return store.ErrNotFound
 	}
 
-	delete(d.secgroups, secgroup.ServiceInstanceGUID)
+	delete(d.secgroups, name)
 	return nil
 }
 
@@ -247,3 +303,218 @@ func (d *Dummy) ClearSecGroupInfo() error {
 	d.secgroups = map[string]store.SecGroupInfo{}
 	return nil
 }
+
+//GetBindingInfo returns the BindingInfo in the map with the given BindingGUID
+// if it exists, and returns ErrNotFound otherwise.
+func (d *Dummy) GetBindingInfo(GUID string) (result store.BindingInfo, err error) {
+	if !d.initialized {
+		return result, fmt.Errorf("Dummy not initialized")
+	}
+
+	if binding, ok := d.bindings[GUID]; ok {
+		return binding, nil
+	}
+
+	return result, store.ErrNotFound
+}
+
+//ListBindingInfo returns all of the BindingInfo objects in the map
+func (d *Dummy) ListBindingInfo() ([]store.BindingInfo, error) {
+	if !d.initialized {
+		return nil, fmt.Errorf("Dummy not initialized")
+	}
+
+	ret := []store.BindingInfo{}
+	for _, binding := range d.bindings {
+		ret = append(ret, binding)
+	}
+	return ret, nil
+}
+
+//ListBindingInfoBySecGroup returns all of the BindingInfo objects in the map
+// with the given SecGroupName. This access takes O(n)
+func (d *Dummy) ListBindingInfoBySecGroup(name string) ([]store.BindingInfo, error) {
+	if !d.initialized {
+		return nil, fmt.Errorf("Dummy not initialized")
+	}
+
+	ret := []store.BindingInfo{}
+	for _, binding := range d.bindings {
+		if binding.SecGroupName == name {
+			ret = append(ret, binding)
+		}
+	}
+	return ret, nil
+}
+
+//AddBindingInfo puts a copy of the given BindingInfo object into the map.
+// ErrDuplicate is thrown if a BindingInfo with that BindingGUID already exists.
+func (d *Dummy) AddBindingInfo(toAdd store.BindingInfo) error {
+	if !d.initialized {
+		return fmt.Errorf("Dummy not initialized")
+	}
+
+	if _, exists := d.bindings[toAdd.BindingGUID]; exists {
+		return store.ErrDuplicate
+	}
+
+	d.bindings[toAdd.BindingGUID] = toAdd
+	return nil
+}
+
+//EditBindingInfo replaces the BindingInfo object in the map with the given
+// BindingGUID with the given BindingInfo object. Returns ErrNotFound if there
+// is no such object, and ErrDuplicate if the new BindingGUID is already taken
+// by a different object
+func (d *Dummy) EditBindingInfo(GUID string, changeTo store.BindingInfo) error {
+	if !d.initialized {
+		return fmt.Errorf("Dummy not initialized")
+	}
+
+	if _, exists := d.bindings[GUID]; !exists {
+		return store.ErrNotFound
+	}
+
+	if changeTo.BindingGUID != GUID {
+		if _, exists := d.bindings[changeTo.BindingGUID]; exists {
+			return store.ErrDuplicate
+		}
+	}
+
+	delete(d.bindings, GUID)
+	d.bindings[changeTo.BindingGUID] = changeTo
+	return nil
+}
+
+//DeleteBindingInfo removes the BindingInfo object with the given BindingGUID
+// from the map if it exists. Otherwise, it returns ErrNotFound
+func (d *Dummy) DeleteBindingInfo(GUID string) error {
+	if !d.initialized {
+		return fmt.Errorf("Dummy not initialized")
+	}
+
+	if _, exists := d.bindings[GUID]; !exists {
+		return store.ErrNotFound
+	}
+
+	delete(d.bindings, GUID)
+	return nil
+}
+
+//NumBindingInfo returns the length of the bindings map
+func (d *Dummy) NumBindingInfo() (int, error) {
+	return len(d.bindings), nil
+}
+
+//ClearBindingInfo puts an empty map in place of the existing bindings map.
+func (d *Dummy) ClearBindingInfo() error {
+	d.bindings = map[string]store.BindingInfo{}
+	return nil
+}
+
+//AddBindFailure appends the given BindFailure to the failures slice
+func (d *Dummy) AddBindFailure(toAdd store.BindFailure) error {
+	if !d.initialized {
+		return fmt.Errorf("Dummy not initialized")
+	}
+
+	d.failures = append(d.failures, toAdd)
+	return nil
+}
+
+//ListBindFailures returns a copy of the failures slice
+func (d *Dummy) ListBindFailures() ([]store.BindFailure, error) {
+	if !d.initialized {
+		return nil, fmt.Errorf("Dummy not initialized")
+	}
+
+	return append([]store.BindFailure{}, d.failures...), nil
+}
+
+//ClearBindFailures puts an empty slice in place of the existing failures slice.
+func (d *Dummy) ClearBindFailures() error {
+	d.failures = []store.BindFailure{}
+	return nil
+}
+
+//GetPendingBind returns the PendingBind in the map with the given BindingGUID.
+// Returns ErrNotFound if there is no such PendingBind
+func (d *Dummy) GetPendingBind(GUID string) (store.PendingBind, error) {
+	if !d.initialized {
+		return store.PendingBind{}, fmt.Errorf("Dummy not initialized")
+	}
+
+	ret, found := d.pending[GUID]
+	if !found {
+		return store.PendingBind{}, store.ErrNotFound
+	}
+	return ret, nil
+}
+
+//AddPendingBind puts a copy of the given PendingBind into the map.
+// ErrDuplicate is thrown if a PendingBind with that BindingGUID already exists.
+func (d *Dummy) AddPendingBind(toAdd store.PendingBind) error {
+	if !d.initialized {
+		return fmt.Errorf("Dummy not initialized")
+	}
+
+	if _, exists := d.pending[toAdd.BindingGUID]; exists {
+		return store.ErrDuplicate
+	}
+
+	d.pending[toAdd.BindingGUID] = toAdd
+	return nil
+}
+
+//DeletePendingBind removes the PendingBind with the given BindingGUID from the
+// map if it exists. Otherwise, it returns ErrNotFound
+func (d *Dummy) DeletePendingBind(GUID string) error {
+	if !d.initialized {
+		return fmt.Errorf("Dummy not initialized")
+	}
+
+	if _, exists := d.pending[GUID]; !exists {
+		return store.ErrNotFound
+	}
+
+	delete(d.pending, GUID)
+	return nil
+}
+
+//ClearPendingBinds puts an empty map in place of the existing pending map.
+func (d *Dummy) ClearPendingBinds() error {
+	d.pending = map[string]store.PendingBind{}
+	return nil
+}
+
+//AddAccessChange appends the given AccessChange to the changes slice
+func (d *Dummy) AddAccessChange(toAdd store.AccessChange) error {
+	if !d.initialized {
+		return fmt.Errorf("Dummy not initialized")
+	}
+
+	d.changes = append(d.changes, toAdd)
+	return nil
+}
+
+//ListAccessChanges returns the AccessChanges in the changes slice with the
+// given MappingName. This access takes O(n)
+func (d *Dummy) ListAccessChanges(mappingName string) ([]store.AccessChange, error) {
+	if !d.initialized {
+		return nil, fmt.Errorf("Dummy not initialized")
+	}
+
+	ret := []store.AccessChange{}
+	for _, change := range d.changes {
+		if change.MappingName == mappingName {
+			ret = append(ret, change)
+		}
+	}
+	return ret, nil
+}
+
+//ClearAccessChanges puts an empty slice in place of the existing changes slice.
+func (d *Dummy) ClearAccessChanges() error {
+	d.changes = []store.AccessChange{}
+	return nil
+}
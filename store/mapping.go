@@ -6,14 +6,19 @@ import "github.com/cloudfoundry-community/portcullis/broker/bindparser"
 //Mapping represents a mapping between a service broker name and a service
 //broker backend, as well as the configuration details of how to work with it
 type Mapping struct {
-	Name       string            `json:"name"`
-	Location   string            `json:"location"`
-	BindConfig bindparser.Config `json:"bind_config"`
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	//BindConfig is the list of flavors whose rules are merged into the security
+	// group for each binding
+	BindConfig bindparser.ConfigList `json:"bind_config"`
+	//ResolveHostnames opts this mapping into resolving hostnames in bind
+	// credentials into addresses for its security groups
+	ResolveHostnames bool `json:"resolve_hostnames"`
 }
 
 //MappingFields is an array of all the top-level fields in a JSON object
 // representing a Mapping that are understood by the program
-var MappingFields = [3]string{"name", "location", "bind_config"}
+var MappingFields = [4]string{"name", "location", "bind_config", "resolve_hostnames"}
 
 //RequiredMappingFields is an array of all the top-level fields in a JSON object
 // representing a Mapping in order for there to be enough information for the
@@ -29,10 +34,10 @@ func (m Mapping) WithName(name string) Mapping {
 }
 
 //WithConfig generates a new Mapping with all the properties of the target Mapping,
-// except with the given config
-func (m Mapping) WithConfig(config bindparser.Config) Mapping {
+// except with the given configs
+func (m Mapping) WithConfig(configs ...bindparser.Config) Mapping {
 	ret := m
-	ret.BindConfig = config
+	ret.BindConfig = configs
 	return ret
 }
 
@@ -72,3 +77,16 @@ func (m MappingList) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
 //Less returns true if the Name of Mapping at index i is lexically earlier than
 // the name at j
 func (m MappingList) Less(i, j int) bool { return m[i].Name < m[j].Name }
+
+//verify checks that the bind configs of the mapping are valid, and that they
+// only use hostname destinations if the mapping resolves hostnames
+func (m Mapping) verify() error {
+	err := m.BindConfig.VerifyFlavors()
+	if err != nil {
+		return err
+	}
+	if !m.ResolveHostnames {
+		return m.BindConfig.VerifyNoHostnames()
+	}
+	return nil
+}
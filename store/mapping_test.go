@@ -19,4 +19,51 @@ var _ = Describe("Mapping", func() {
 			Expect(len(RequiredMappingFields)).To(BeNumerically("<=", len(MappingFields)))
 		})
 	})
+
+	Describe("MappingFromMap", func() {
+		var testMapping Mapping
+		var err error
+
+		Context("With a list of bind configs", func() {
+			BeforeEach(func() {
+				testMapping, err = MappingFromMap(map[string]interface{}{
+					"name":     "multi",
+					"location": "http://10.0.0.1:8080",
+					"bind_config": []interface{}{
+						map[string]interface{}{"flavor": "uri", "config": map[string]interface{}{}},
+						map[string]interface{}{"flavor": "none", "config": map[string]interface{}{}},
+					},
+				})
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should keep all of the bind configs in order", func() {
+				Expect(testMapping.BindConfig).To(HaveLen(2))
+				Expect(testMapping.BindConfig[0].FlavorName).To(Equal("uri"))
+				Expect(testMapping.BindConfig[1].FlavorName).To(Equal("none"))
+			})
+		})
+
+		Context("With a single bind config object", func() {
+			BeforeEach(func() {
+				testMapping, err = MappingFromMap(map[string]interface{}{
+					"name":        "single",
+					"location":    "http://10.0.0.1:8080",
+					"bind_config": map[string]interface{}{"flavor": "dummy", "config": map[string]interface{}{"confirm": true}},
+				})
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should read it as a list of one", func() {
+				Expect(testMapping.BindConfig).To(HaveLen(1))
+				Expect(testMapping.BindConfig[0].FlavorName).To(Equal("dummy"))
+			})
+		})
+	})
 })
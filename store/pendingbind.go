@@ -0,0 +1,19 @@
+package store
+
+//PendingBind records a bind that the backend broker is doing asynchronously.
+// Egress can only be opened once the broker says that the bind has succeeded,
+// and the bind request is kept until then because the credentials fetched at
+// that point don't say which app or space the binding is for. Asynchronous
+// unbinds are recorded the same way, so that egress is closed once the broker
+// says that the binding is gone.
+type PendingBind struct {
+	BindingGUID         string `json:"binding_guid"`
+	ServiceInstanceGUID string `json:"service_instance_guid"`
+	MappingName         string `json:"mapping_name"`
+	//Request is the body of the bind request that the Cloud Controller sent.
+	// Empty for an unbind.
+	Request string `json:"request"`
+	//Unbind is true if the backend broker is deleting the binding rather than
+	// creating it
+	Unbind bool `json:"unbind"`
+}
@@ -38,14 +38,27 @@ type postgresConfig struct {
 }
 
 const (
-	schemaTable   = "schema_info"
-	mappingsTable = "mappings"
+	schemaTable    = "schema_info"
+	mappingsTable  = "mappings"
+	secGroupsTable = "secgroups"
+	bindingsTable  = "bindings"
+	failuresTable  = "bind_failures"
+	pendingTable   = "pending_binds"
+	changesTable   = "access_changes"
 )
 
 //If you're making a new schema, it needs to be added to the end of this array
 var schemas = map[int]schema{
-	1: v1{},
-	2: v2{},
+	1:  v1{},
+	2:  v2{},
+	3:  v3{},
+	4:  v4{},
+	5:  v5{},
+	6:  v6{},
+	7:  v7{},
+	8:  v8{},
+	9:  v9{},
+	10: v10{},
 }
 
 func init() {
@@ -139,7 +152,7 @@ func (p *Postgres) Initialize(conf map[string]interface{}) error {
 //ListMappings returns the list of all mappings stored in the Postgres database
 func (p *Postgres) ListMappings() ([]store.Mapping, error) {
 	log.Debugf("Attempting to retrieve all rows from mappings table...")
-	rows, err := p.connection.Query("SELECT name, location, config FROM mappings")
+	rows, err := p.connection.Query("SELECT name, location, config, resolve_hostnames FROM mappings")
 	if err != nil {
 		log.Infof("Scan error attempting to retrieve all rows from mapping")
 		return []store.Mapping{}, err
@@ -147,20 +160,21 @@ func (p *Postgres) ListMappings() ([]store.Mapping, error) {
 	ret := []store.Mapping{}
 	for rows.Next() {
 		var name, location, mappingConfig string
-		err := rows.Scan(&name, &location, &mappingConfig)
+		var resolveHostnames bool
+		err := rows.Scan(&name, &location, &mappingConfig, &resolveHostnames)
 		if err != nil {
 			log.Infof("Scan error attempting to retrieve all rows from mapping")
 			return []store.Mapping{}, err
 		}
 
-		var bc bindparser.Config
+		var bc bindparser.ConfigList
 
 		if err := json.Unmarshal([]byte(mappingConfig), &bc); err != nil {
 			log.Infof("Scan error attempting to retrieve row with name: %s, cloud not unmarshal config %s", name, err.Error())
 		}
 
 		log.Debugf("Found row: %s, %s", name, location)
-		ret = append(ret, store.Mapping{Name: name, Location: location, BindConfig: bc})
+		ret = append(ret, store.Mapping{Name: name, Location: location, BindConfig: bc, ResolveHostnames: resolveHostnames})
 	}
 
 	return ret, err
@@ -173,8 +187,9 @@ func (p *Postgres) GetMapping(name string) (store.Mapping, error) {
 
 	ret := store.Mapping{}
 	var mappingName, location, mappingConfig string
+	var resolveHostnames bool
 
-	err := p.connection.QueryRow("SELECT name, location, config FROM mappings WHERE name = $1", name).Scan(&mappingName, &location, &mappingConfig)
+	err := p.connection.QueryRow("SELECT name, location, config, resolve_hostnames FROM mappings WHERE name = $1", name).Scan(&mappingName, &location, &mappingConfig, &resolveHostnames)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Infof("No rows found while attempting to retrieve row with name: %s", name)
@@ -185,7 +200,7 @@ func (p *Postgres) GetMapping(name string) (store.Mapping, error) {
 		return ret, err
 	}
 
-	var bc bindparser.Config
+	var bc bindparser.ConfigList
 
 	if err := json.Unmarshal([]byte(mappingConfig), &bc); err != nil {
 		log.Infof("Scan error attempting to retrieve row with name: %s, cloud not unmarshal config %s", name, err.Error())
@@ -196,6 +211,8 @@ func (p *Postgres) GetMapping(name string) (store.Mapping, error) {
 		Name:       mappingName,
 		Location:   location,
 		BindConfig: bc,
+
+		ResolveHostnames: resolveHostnames,
 	}
 
 	return retMapping, err
@@ -209,7 +226,7 @@ func (p *Postgres) AddMapping(m store.Mapping) error {
 
 	bc, _ := json.Marshal(m.BindConfig)
 
-	_, err := p.connection.Exec(`INSERT INTO mappings (name, location, config) VALUES ($1, $2, $3)`, m.Name, m.Location, string(bc))
+	_, err := p.connection.Exec(`INSERT INTO mappings (name, location, config, resolve_hostnames) VALUES ($1, $2, $3, $4)`, m.Name, m.Location, string(bc), m.ResolveHostnames)
 	if err != nil {
 		if err.(*pq.Error).Code == "23505" {
 			log.Infof("Could not insert into %s table, duplicate row: %s", mappingsTable, err.Error())
@@ -240,7 +257,7 @@ func (p *Postgres) EditMapping(name string, m store.Mapping) error {
 
 	bc, _ := json.Marshal(m.BindConfig)
 
-	_, err = p.connection.Exec(`UPDATE mappings SET name = $1, location = $2, config = $3 WHERE name = $4`, m.Name, m.Location, string(bc), name)
+	_, err = p.connection.Exec(`UPDATE mappings SET name = $1, location = $2, config = $3, resolve_hostnames = $4 WHERE name = $5`, m.Name, m.Location, string(bc), m.ResolveHostnames, name)
 
 	if err != nil {
 		if err.(*pq.Error).Code == "23505" {
@@ -304,37 +321,557 @@ func (p *Postgres) ClearMappings() error {
 	return err
 }
 
-//TODO: Comment
+//secGroupColumns are the columns of the secgroups table in the order that
+// scanSecGroupInfo expects them to be selected in
+const secGroupColumns = `name, instance_guid, space_guid, mapping_name, secgroup_guid, rules`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSecGroupInfo(row rowScanner) (ret store.SecGroupInfo, err error) {
+	var rules string
+	err = row.Scan(&ret.SecGroupName, &ret.ServiceInstanceGUID, &ret.SpaceGUID,
+		&ret.MappingName, &ret.SecGroupGUID, &rules)
+	if err != nil {
+		return
+	}
+
+	if err := json.Unmarshal([]byte(rules), &ret.Rules); err != nil {
+		log.Infof("Could not unmarshal rules for security group %s: %s", ret.SecGroupName, err.Error())
+	}
+	return
+}
+
+//marshalRules turns a list of rules into the JSON text stored in the rules
+// columns
+func marshalRules(rules []bindparser.SecGroupRule) string {
+	ret, _ := json.Marshal(rules)
+	return string(ret)
+}
+
+func (p *Postgres) querySecGroupInfo(query string, args ...interface{}) ([]store.SecGroupInfo, error) {
+	rows, err := p.connection.Query(query, args...)
+	if err != nil {
+		log.Infof("Error attempting to retrieve rows from secgroups: %s", err.Error())
+		return []store.SecGroupInfo{}, err
+	}
+	defer rows.Close()
+
+	results := []store.SecGroupInfo{}
+	for rows.Next() {
+		info, err := scanSecGroupInfo(rows)
+		if err != nil {
+			log.Infof("Scan error attempting to retrieve rows from secgroups")
+			return []store.SecGroupInfo{}, err
+		}
+		results = append(results, info)
+	}
+
+	return results, rows.Err()
+}
+
+func (p *Postgres) getSecGroupInfoWhere(column, value string) (store.SecGroupInfo, error) {
+	ret, err := scanSecGroupInfo(p.connection.QueryRow(
+		fmt.Sprintf("SELECT %s FROM secgroups WHERE %s = $1", secGroupColumns, column), value))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Infof("No rows found while attempting to retrieve secgroup with %s: %s", column, value)
+			return ret, store.ErrNotFound
+		}
+		log.Infof("Scan error attempting to retrieve secgroup with %s: %s", column, value)
+	}
+	return ret, err
+}
+
+func (p *Postgres) deleteSecGroupInfoWhere(column, value string) error {
+	result, err := p.connection.Exec(fmt.Sprintf("DELETE FROM secgroups WHERE %s = $1", column), value)
+	if err != nil {
+		log.Infof("Could not delete secgroups entry with %s %s: %s", column, value, err.Error())
+		return err
+	}
+
+	numRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if numRows < 1 {
+		log.Infof("No secgroups found with %s: %s", column, value)
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+//GetSecGroupInfoByName returns the SecGroupInfo with the given security group
+// name. Errs with ErrNotFound if there is no such row in the Postgres database
 func (p *Postgres) GetSecGroupInfoByName(name string) (result store.SecGroupInfo, err error) {
-	return result, fmt.Errorf("Not yet implemented")
+	log.Debugf("Attempting to get a row from the secgroups table by name...")
+	return p.getSecGroupInfoWhere("name", name)
 }
 
-//TODO: Comment
-func (p *Postgres) GetSecGroupInfoByInstance(GUID string) (result store.SecGroupInfo, err error) {
-	return result, fmt.Errorf("Not yet implemented")
+//ListSecGroupInfoByInstance returns all of the SecGroupInfo rows in the
+// Postgres database for the given service instance GUID
+func (p *Postgres) ListSecGroupInfoByInstance(GUID string) (results []store.SecGroupInfo, err error) {
+	log.Debugf("Attempting to retrieve rows from secgroups table by instance...")
+	return p.querySecGroupInfo(fmt.Sprintf("SELECT %s FROM secgroups WHERE instance_guid = $1", secGroupColumns), GUID)
 }
 
-//TODO: Comment
+//ListSecGroupInfo returns all of the SecGroupInfo rows in the Postgres database
+func (p *Postgres) ListSecGroupInfo() (results []store.SecGroupInfo, err error) {
+	log.Debugf("Attempting to retrieve all rows from secgroups table...")
+	return p.querySecGroupInfo(fmt.Sprintf("SELECT %s FROM secgroups", secGroupColumns))
+}
+
+//AddSecGroupInfo stores a new SecGroupInfo in a row in the Postgres database.
+// Will return ErrDuplicate if a row with that name already exists in the db
 func (p *Postgres) AddSecGroupInfo(toAdd store.SecGroupInfo) error {
-	return fmt.Errorf("Not yet implemented")
+	log.Debugf("Attempting to add a row into secgroups table...")
+
+	_, err := p.connection.Exec(fmt.Sprintf(`INSERT INTO secgroups (%s) VALUES ($1, $2, $3, $4, $5, $6)`, secGroupColumns),
+		toAdd.SecGroupName, toAdd.ServiceInstanceGUID, toAdd.SpaceGUID, toAdd.MappingName,
+		toAdd.SecGroupGUID, marshalRules(toAdd.Rules))
+	if err != nil {
+		if pqErr, isPQErr := err.(*pq.Error); isPQErr && pqErr.Code == "23505" {
+			log.Infof("Could not insert into %s table, duplicate row: %s", secGroupsTable, err.Error())
+			return store.ErrDuplicate
+		}
+		log.Infof("Could not insert into %s table: %s", secGroupsTable, err.Error())
+	}
+	return err
+}
+
+//EditSecGroupInfo changes the SecGroupInfo row with the given name to have the
+// values in the given SecGroupInfo. Errs with ErrNotFound if there is no such
+// row, or ErrDuplicate if the new name is already taken
+func (p *Postgres) EditSecGroupInfo(name string, changeTo store.SecGroupInfo) error {
+	log.Debugf("Attempting to update a row in secgroups table...")
+
+	result, err := p.connection.Exec(`UPDATE secgroups SET name = $1, instance_guid = $2, space_guid = $3,
+		mapping_name = $4, secgroup_guid = $5, rules = $6 WHERE name = $7`,
+		changeTo.SecGroupName, changeTo.ServiceInstanceGUID, changeTo.SpaceGUID,
+		changeTo.MappingName, changeTo.SecGroupGUID, marshalRules(changeTo.Rules), name)
+	if err != nil {
+		if pqErr, isPQErr := err.(*pq.Error); isPQErr && pqErr.Code == "23505" {
+			log.Infof("Could not update %s table, duplicate row: %s", secGroupsTable, err.Error())
+			return store.ErrDuplicate
+		}
+		log.Infof("Could not update secgroups entry %s: %s", name, err.Error())
+		return err
+	}
+
+	numRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if numRows < 1 {
+		log.Infof("No secgroups found with name: %s", name)
+		return store.ErrNotFound
+	}
+	return nil
 }
 
-//TODO: Comment
+//DeleteSecGroupInfoByInstance removes all SecGroupInfo rows for the given
+// service instance GUID from the Postgres database, and errs with ErrNotFound
+// if there were none
 func (p *Postgres) DeleteSecGroupInfoByInstance(GUID string) error {
-	return fmt.Errorf("Not yet implemented")
+	log.Debugf("Attempting to delete rows from secgroups table by instance...")
+	return p.deleteSecGroupInfoWhere("instance_guid", GUID)
 }
 
-//TODO: Comment
+//DeleteSecGroupInfoByName removes the SecGroupInfo row with the given name from
+// the Postgres database, and errs with ErrNotFound if there was no such row
 func (p *Postgres) DeleteSecGroupInfoByName(name string) error {
-	return fmt.Errorf("Not yet implemented")
+	log.Debugf("Attempting to delete a row from secgroups table by name...")
+	return p.deleteSecGroupInfoWhere("name", name)
 }
 
-//TODO: Comment
+//NumSecGroupInfo returns the number of rows in the secgroups table
 func (p *Postgres) NumSecGroupInfo() (int, error) {
-	return -1, fmt.Errorf("Not yet implemented")
+	log.Debugf("Getting the row count in the secgroups table...")
+
+	var numRows int
+	err := p.connection.QueryRow(`SELECT COUNT(name) FROM secgroups`).Scan(&numRows)
+	if err != nil {
+		log.Infof("Scan error attempting to retrieve row count")
+		return 0, err
+	}
+
+	return numRows, nil
 }
 
-//TODO: Comment
+//ClearSecGroupInfo removes all SecGroupInfo from the Postgres database by
+// truncating the secgroups table
 func (p *Postgres) ClearSecGroupInfo() error {
-	return fmt.Errorf("Not yet implemented")
+	log.Debugf("Truncating table secgroups...")
+
+	_, err := p.connection.Exec(`TRUNCATE TABLE secgroups`)
+
+	if err != nil {
+		log.Infof("Could not TRUNCATE TABLE secgroups: %s", err.Error())
+	}
+	return err
+}
+
+//bindingColumns are the columns of the bindings table in the order that
+// scanBindingInfo expects them to be selected in
+const bindingColumns = `binding_guid, instance_guid, secgroup_name, app_guid, space_guid, organization_guid, rules, source_rules`
+
+func scanBindingInfo(row rowScanner) (ret store.BindingInfo, err error) {
+	var rules, sourceRules string
+	err = row.Scan(&ret.BindingGUID, &ret.ServiceInstanceGUID, &ret.SecGroupName,
+		&ret.AppGUID, &ret.SpaceGUID, &ret.OrganizationGUID, &rules, &sourceRules)
+	if err != nil {
+		return
+	}
+
+	if err := json.Unmarshal([]byte(rules), &ret.Rules); err != nil {
+		log.Infof("Could not unmarshal rules for binding %s: %s", ret.BindingGUID, err.Error())
+	}
+	if err := json.Unmarshal([]byte(sourceRules), &ret.SourceRules); err != nil {
+		log.Infof("Could not unmarshal source rules for binding %s: %s", ret.BindingGUID, err.Error())
+	}
+	return
+}
+
+func (p *Postgres) queryBindingInfo(query string, args ...interface{}) ([]store.BindingInfo, error) {
+	rows, err := p.connection.Query(query, args...)
+	if err != nil {
+		log.Infof("Error attempting to retrieve rows from bindings: %s", err.Error())
+		return []store.BindingInfo{}, err
+	}
+	defer rows.Close()
+
+	results := []store.BindingInfo{}
+	for rows.Next() {
+		info, err := scanBindingInfo(rows)
+		if err != nil {
+			log.Infof("Scan error attempting to retrieve rows from bindings")
+			return []store.BindingInfo{}, err
+		}
+		results = append(results, info)
+	}
+
+	return results, rows.Err()
+}
+
+//GetBindingInfo returns the BindingInfo with the given binding GUID. Errs with
+// ErrNotFound if there is no such row in the Postgres database
+func (p *Postgres) GetBindingInfo(GUID string) (store.BindingInfo, error) {
+	log.Debugf("Attempting to get a row from the bindings table...")
+
+	ret, err := scanBindingInfo(p.connection.QueryRow(
+		fmt.Sprintf("SELECT %s FROM bindings WHERE binding_guid = $1", bindingColumns), GUID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Infof("No rows found while attempting to retrieve binding: %s", GUID)
+			return ret, store.ErrNotFound
+		}
+		log.Infof("Scan error attempting to retrieve binding: %s", GUID)
+	}
+	return ret, err
+}
+
+//ListBindingInfo returns all of the BindingInfo rows in the Postgres database
+func (p *Postgres) ListBindingInfo() (results []store.BindingInfo, err error) {
+	log.Debugf("Attempting to retrieve all rows from bindings table...")
+	return p.queryBindingInfo(fmt.Sprintf("SELECT %s FROM bindings", bindingColumns))
+}
+
+//ListBindingInfoBySecGroup returns all of the BindingInfo rows in the Postgres
+// database that point at the security group with the given name
+func (p *Postgres) ListBindingInfoBySecGroup(name string) (results []store.BindingInfo, err error) {
+	log.Debugf("Attempting to retrieve rows from bindings table by security group...")
+	return p.queryBindingInfo(fmt.Sprintf("SELECT %s FROM bindings WHERE secgroup_name = $1", bindingColumns), name)
+}
+
+//AddBindingInfo stores a new BindingInfo in a row in the Postgres database.
+// Will return ErrDuplicate if a row with that binding GUID already exists in
+// the db
+func (p *Postgres) AddBindingInfo(toAdd store.BindingInfo) error {
+	log.Debugf("Attempting to add a row into bindings table...")
+
+	_, err := p.connection.Exec(fmt.Sprintf(`INSERT INTO bindings (%s) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, bindingColumns),
+		toAdd.BindingGUID, toAdd.ServiceInstanceGUID, toAdd.SecGroupName, toAdd.AppGUID,
+		toAdd.SpaceGUID, toAdd.OrganizationGUID, marshalRules(toAdd.Rules), marshalRules(toAdd.SourceRules))
+	if err != nil {
+		if pqErr, isPQErr := err.(*pq.Error); isPQErr && pqErr.Code == "23505" {
+			log.Infof("Could not insert into %s table, duplicate row: %s", bindingsTable, err.Error())
+			return store.ErrDuplicate
+		}
+		log.Infof("Could not insert into %s table: %s", bindingsTable, err.Error())
+	}
+	return err
+}
+
+//EditBindingInfo changes the BindingInfo row with the given binding GUID to
+// have the values in the given BindingInfo. Errs with ErrNotFound if there is
+// no such row, or ErrDuplicate if the new binding GUID is already taken
+func (p *Postgres) EditBindingInfo(GUID string, changeTo store.BindingInfo) error {
+	log.Debugf("Attempting to update a row in bindings table...")
+
+	result, err := p.connection.Exec(`UPDATE bindings SET binding_guid = $1, instance_guid = $2,
+		secgroup_name = $3, app_guid = $4, space_guid = $5, organization_guid = $6, rules = $7,
+		source_rules = $8 WHERE binding_guid = $9`,
+		changeTo.BindingGUID, changeTo.ServiceInstanceGUID, changeTo.SecGroupName, changeTo.AppGUID,
+		changeTo.SpaceGUID, changeTo.OrganizationGUID, marshalRules(changeTo.Rules),
+		marshalRules(changeTo.SourceRules), GUID)
+	if err != nil {
+		if pqErr, isPQErr := err.(*pq.Error); isPQErr && pqErr.Code == "23505" {
+			log.Infof("Could not update %s table, duplicate row: %s", bindingsTable, err.Error())
+			return store.ErrDuplicate
+		}
+		log.Infof("Could not update bindings entry %s: %s", GUID, err.Error())
+		return err
+	}
+
+	numRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if numRows < 1 {
+		log.Infof("No bindings found with binding GUID: %s", GUID)
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+//DeleteBindingInfo removes the BindingInfo row with the given binding GUID from
+// the Postgres database, and errs with ErrNotFound if there was no such row
+func (p *Postgres) DeleteBindingInfo(GUID string) error {
+	log.Debugf("Attempting to delete a row from bindings table...")
+
+	result, err := p.connection.Exec(`DELETE FROM bindings WHERE binding_guid = $1`, GUID)
+	if err != nil {
+		log.Infof("Could not delete bindings entry %s: %s", GUID, err.Error())
+		return err
+	}
+
+	numRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if numRows < 1 {
+		log.Infof("No bindings found with binding GUID: %s", GUID)
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+//NumBindingInfo returns the number of rows in the bindings table
+func (p *Postgres) NumBindingInfo() (int, error) {
+	log.Debugf("Getting the row count in the bindings table...")
+
+	var numRows int
+	err := p.connection.QueryRow(`SELECT COUNT(binding_guid) FROM bindings`).Scan(&numRows)
+	if err != nil {
+		log.Infof("Scan error attempting to retrieve row count")
+		return 0, err
+	}
+
+	return numRows, nil
+}
+
+//ClearBindingInfo removes all BindingInfo from the Postgres database by
+// truncating the bindings table
+func (p *Postgres) ClearBindingInfo() error {
+	log.Debugf("Truncating table bindings...")
+
+	_, err := p.connection.Exec(`TRUNCATE TABLE bindings`)
+
+	if err != nil {
+		log.Infof("Could not TRUNCATE TABLE bindings: %s", err.Error())
+	}
+	return err
+}
+
+//AddBindFailure stores the given BindFailure in a new row in the Postgres
+// database
+func (p *Postgres) AddBindFailure(toAdd store.BindFailure) error {
+	log.Debugf("Attempting to add a row into bind_failures table...")
+
+	_, err := p.connection.Exec(`INSERT INTO bind_failures
+		(binding_guid, instance_guid, mapping_name, failed_at, error, unbound, unbind_error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		toAdd.BindingGUID, toAdd.ServiceInstanceGUID, toAdd.MappingName, toAdd.Time,
+		toAdd.Error, toAdd.Unbound, toAdd.UnbindError)
+	if err != nil {
+		log.Infof("Could not insert into %s table: %s", failuresTable, err.Error())
+	}
+	return err
+}
+
+//ListBindFailures returns all of the BindFailure rows in the Postgres database,
+// oldest first
+func (p *Postgres) ListBindFailures() ([]store.BindFailure, error) {
+	log.Debugf("Attempting to retrieve all rows from bind_failures table...")
+
+	rows, err := p.connection.Query(`SELECT binding_guid, instance_guid, mapping_name, failed_at,
+		error, unbound, unbind_error FROM bind_failures ORDER BY id`)
+	if err != nil {
+		log.Infof("Error attempting to retrieve rows from bind_failures: %s", err.Error())
+		return []store.BindFailure{}, err
+	}
+	defer rows.Close()
+
+	results := []store.BindFailure{}
+	for rows.Next() {
+		var failure store.BindFailure
+		err = rows.Scan(&failure.BindingGUID, &failure.ServiceInstanceGUID, &failure.MappingName,
+			&failure.Time, &failure.Error, &failure.Unbound, &failure.UnbindError)
+		if err != nil {
+			log.Infof("Scan error attempting to retrieve rows from bind_failures")
+			return []store.BindFailure{}, err
+		}
+		results = append(results, failure)
+	}
+
+	return results, rows.Err()
+}
+
+//ClearBindFailures removes all BindFailures from the Postgres database by
+// truncating the bind_failures table
+func (p *Postgres) ClearBindFailures() error {
+	log.Debugf("Truncating table bind_failures...")
+
+	_, err := p.connection.Exec(`TRUNCATE TABLE bind_failures`)
+
+	if err != nil {
+		log.Infof("Could not TRUNCATE TABLE bind_failures: %s", err.Error())
+	}
+	return err
+}
+
+//GetPendingBind returns the PendingBind with the given binding GUID. Errs with
+// ErrNotFound if there is no such row in the Postgres database
+func (p *Postgres) GetPendingBind(GUID string) (store.PendingBind, error) {
+	log.Debugf("Attempting to get a row from the pending_binds table...")
+
+	var ret store.PendingBind
+	err := p.connection.QueryRow(`SELECT binding_guid, instance_guid, mapping_name, request, unbind
+		FROM pending_binds WHERE binding_guid = $1`, GUID).Scan(
+		&ret.BindingGUID, &ret.ServiceInstanceGUID, &ret.MappingName, &ret.Request, &ret.Unbind)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Infof("No rows found while attempting to retrieve pending bind: %s", GUID)
+			return ret, store.ErrNotFound
+		}
+		log.Infof("Scan error attempting to retrieve pending bind: %s", GUID)
+	}
+	return ret, err
+}
+
+//AddPendingBind stores a new PendingBind in a row in the Postgres database.
+// Will return ErrDuplicate if a row with that binding GUID already exists in
+// the db
+func (p *Postgres) AddPendingBind(toAdd store.PendingBind) error {
+	log.Debugf("Attempting to add a row into pending_binds table...")
+
+	_, err := p.connection.Exec(`INSERT INTO pending_binds
+		(binding_guid, instance_guid, mapping_name, request, unbind) VALUES ($1, $2, $3, $4, $5)`,
+		toAdd.BindingGUID, toAdd.ServiceInstanceGUID, toAdd.MappingName, toAdd.Request, toAdd.Unbind)
+	if err != nil {
+		if pqErr, isPQErr := err.(*pq.Error); isPQErr && pqErr.Code == "23505" {
+			log.Infof("Could not insert into %s table, duplicate row: %s", pendingTable, err.Error())
+			return store.ErrDuplicate
+		}
+		log.Infof("Could not insert into %s table: %s", pendingTable, err.Error())
+	}
+	return err
+}
+
+//DeletePendingBind removes the PendingBind row with the given binding GUID from
+// the Postgres database, and errs with ErrNotFound if there was no such row
+func (p *Postgres) DeletePendingBind(GUID string) error {
+	log.Debugf("Attempting to delete a row from pending_binds table...")
+
+	result, err := p.connection.Exec(`DELETE FROM pending_binds WHERE binding_guid = $1`, GUID)
+	if err != nil {
+		log.Infof("Could not delete pending_binds entry %s: %s", GUID, err.Error())
+		return err
+	}
+
+	numRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if numRows < 1 {
+		log.Infof("No pending binds found with binding GUID: %s", GUID)
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+//ClearPendingBinds removes all PendingBinds from the Postgres database by
+// truncating the pending_binds table
+func (p *Postgres) ClearPendingBinds() error {
+	log.Debugf("Truncating table pending_binds...")
+
+	_, err := p.connection.Exec(`TRUNCATE TABLE pending_binds`)
+
+	if err != nil {
+		log.Infof("Could not TRUNCATE TABLE pending_binds: %s", err.Error())
+	}
+	return err
+}
+
+//AddAccessChange stores the given AccessChange in a new row in the Postgres
+// database
+func (p *Postgres) AddAccessChange(toAdd store.AccessChange) error {
+	log.Debugf("Attempting to add a row into access_changes table...")
+
+	_, err := p.connection.Exec(`INSERT INTO access_changes
+		(mapping_name, organization_guid, service_name, plan_name, plan_guid, granted, username, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		toAdd.MappingName, toAdd.OrganizationGUID, toAdd.ServiceName, toAdd.PlanName,
+		toAdd.PlanGUID, toAdd.Granted, toAdd.User, toAdd.Time)
+	if err != nil {
+		log.Infof("Could not insert into %s table: %s", changesTable, err.Error())
+	}
+	return err
+}
+
+//ListAccessChanges returns all of the AccessChange rows in the Postgres
+// database for the mapping with the given name, oldest first
+func (p *Postgres) ListAccessChanges(mappingName string) ([]store.AccessChange, error) {
+	log.Debugf("Attempting to retrieve rows from access_changes table by mapping...")
+
+	rows, err := p.connection.Query(`SELECT mapping_name, organization_guid, service_name, plan_name,
+		plan_guid, granted, username, changed_at FROM access_changes WHERE mapping_name = $1 ORDER BY id`, mappingName)
+	if err != nil {
+		log.Infof("Error attempting to retrieve rows from access_changes: %s", err.Error())
+		return []store.AccessChange{}, err
+	}
+	defer rows.Close()
+
+	results := []store.AccessChange{}
+	for rows.Next() {
+		var change store.AccessChange
+		err = rows.Scan(&change.MappingName, &change.OrganizationGUID, &change.ServiceName, &change.PlanName,
+			&change.PlanGUID, &change.Granted, &change.User, &change.Time)
+		if err != nil {
+			log.Infof("Scan error attempting to retrieve rows from access_changes")
+			return []store.AccessChange{}, err
+		}
+		results = append(results, change)
+	}
+
+	return results, rows.Err()
+}
+
+//ClearAccessChanges removes all AccessChanges from the Postgres database by
+// truncating the access_changes table
+func (p *Postgres) ClearAccessChanges() error {
+	log.Debugf("Truncating table access_changes...")
+
+	_, err := p.connection.Exec(`TRUNCATE TABLE access_changes`)
+
+	if err != nil {
+		log.Infof("Could not TRUNCATE TABLE access_changes: %s", err.Error())
+	}
+	return err
 }
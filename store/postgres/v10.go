@@ -0,0 +1,53 @@
+package postgres
+
+import "github.com/starkandwayne/goutils/log"
+
+type v10 struct {
+}
+
+func (v v10) migrate(p *Postgres) error {
+
+	log.Debugf("Starting v10 Migration...")
+
+	transaction, err := p.connection.Begin()
+
+	defer func() {
+		if err != nil {
+			err = transaction.Rollback()
+			if err != nil {
+				log.Infof("Failed to roll back transaction: %s", err.Error())
+			} else {
+				log.Infof("Rolled back transaction for v10")
+			}
+		}
+	}()
+
+	// Pending binds also record asynchronous unbinds, so that egress can be
+	// closed once the backend broker says that the binding is gone
+	_, err = transaction.Exec(`ALTER TABLE pending_binds ADD COLUMN unbind BOOLEAN NOT NULL DEFAULT FALSE`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	// Forces that this schema update was done via transaction, this leaves an
+	// artifact that the migration is complete
+	_, err = transaction.Exec(`UPDATE schema_info SET version = $1`, v.version())
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		log.Errorf(err.Error())
+		return err
+	}
+
+	return nil
+
+}
+
+func (v v10) version() int {
+	return 10
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import "github.com/starkandwayne/goutils/log"
+
+type v3 struct {
+}
+
+func (v v3) migrate(p *Postgres) error {
+
+	log.Debugf("Starting v3 Migration...")
+
+	transaction, err := p.connection.Begin()
+
+	defer func() {
+		if err != nil {
+			err = transaction.Rollback()
+			if err != nil {
+				log.Infof("Failed to roll back transaction: %s", err.Error())
+			} else {
+				log.Infof("Rolled back transaction for v3")
+			}
+		}
+	}()
+
+	// Creates the secgroups table to keep track of the security groups that
+	// Portcullis has created in Cloud Foundry. Names are unique. instance_guid
+	// is deliberately not: an instance has a group for each of its bindings
+	// (and later, each space it's bound from), so a unique constraint would
+	// make the second bind to an instance fail. It is indexed instead, and the
+	// store lists groups by instance rather than getting a single one.
+	_, err = transaction.Exec(`CREATE TABLE secgroups (
+						 name           TEXT PRIMARY KEY,
+						 instance_guid  TEXT NOT NULL,
+						 binding_guid   TEXT NOT NULL UNIQUE,
+						 app_guid       TEXT NOT NULL DEFAULT '',
+						 space_guid     TEXT NOT NULL DEFAULT '',
+						 mapping_name   TEXT NOT NULL DEFAULT '',
+						 secgroup_guid  TEXT NOT NULL DEFAULT '',
+						 rules          TEXT NOT NULL DEFAULT '[]'
+					 )`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	_, err = transaction.Exec(`CREATE INDEX secgroups_instance_guid_idx ON secgroups (instance_guid)`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	// Forces that this schema update was done via transaction, this leaves an
+	// artifact that the migration is complete
+	_, err = transaction.Exec(`UPDATE schema_info SET version = $1`, v.version())
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		log.Errorf(err.Error())
+		return err
+	}
+
+	return nil
+
+}
+
+func (v v3) version() int {
+	return 3
+}
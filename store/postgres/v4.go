@@ -0,0 +1,59 @@
+package postgres
+
+import "github.com/starkandwayne/goutils/log"
+
+type v4 struct {
+}
+
+func (v v4) migrate(p *Postgres) error {
+
+	log.Debugf("Starting v4 Migration...")
+
+	transaction, err := p.connection.Begin()
+
+	defer func() {
+		if err != nil {
+			err = transaction.Rollback()
+			if err != nil {
+				log.Infof("Failed to roll back transaction: %s", err.Error())
+			} else {
+				log.Infof("Rolled back transaction for v4")
+			}
+		}
+	}()
+
+	// Lets mappings opt into hostname resolution, and keeps the unresolved
+	// rules for each security group so that they can be resolved again later
+	_, err = transaction.Exec(`ALTER TABLE mappings ADD COLUMN resolve_hostnames BOOLEAN NOT NULL DEFAULT FALSE`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	_, err = transaction.Exec(`ALTER TABLE secgroups ADD COLUMN source_rules TEXT NOT NULL DEFAULT '[]'`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	// Forces that this schema update was done via transaction, this leaves an
+	// artifact that the migration is complete
+	_, err = transaction.Exec(`UPDATE schema_info SET version = $1`, v.version())
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		log.Errorf(err.Error())
+		return err
+	}
+
+	return nil
+
+}
+
+func (v v4) version() int {
+	return 4
+}
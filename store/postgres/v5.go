@@ -0,0 +1,82 @@
+package postgres
+
+import "github.com/starkandwayne/goutils/log"
+
+type v5 struct {
+}
+
+func (v v5) migrate(p *Postgres) error {
+
+	log.Debugf("Starting v5 Migration...")
+
+	transaction, err := p.connection.Begin()
+
+	defer func() {
+		if err != nil {
+			err = transaction.Rollback()
+			if err != nil {
+				log.Infof("Failed to roll back transaction: %s", err.Error())
+			} else {
+				log.Infof("Rolled back transaction for v5")
+			}
+		}
+	}()
+
+	// Security groups are now shared by all of the bindings to an instance from
+	// the same space, so the bindings get a table of their own. The number of
+	// rows in it that point at a group is that group's reference count.
+	_, err = transaction.Exec(`CREATE TABLE bindings (
+						 binding_guid   TEXT PRIMARY KEY,
+						 instance_guid  TEXT NOT NULL,
+						 secgroup_name  TEXT NOT NULL,
+						 app_guid       TEXT NOT NULL DEFAULT '',
+						 rules          TEXT NOT NULL DEFAULT '[]',
+						 source_rules   TEXT NOT NULL DEFAULT '[]'
+					 )`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	_, err = transaction.Exec(`CREATE INDEX bindings_secgroup_name_idx ON bindings (secgroup_name)`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	// Every existing group was made for exactly one binding, so each becomes a
+	// group with a single reference
+	_, err = transaction.Exec(`INSERT INTO bindings (binding_guid, instance_guid, secgroup_name, app_guid, rules, source_rules)
+					 SELECT binding_guid, instance_guid, name, app_guid, rules, source_rules FROM secgroups`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	_, err = transaction.Exec(`ALTER TABLE secgroups DROP COLUMN binding_guid, DROP COLUMN app_guid, DROP COLUMN source_rules`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	// Forces that this schema update was done via transaction, this leaves an
+	// artifact that the migration is complete
+	_, err = transaction.Exec(`UPDATE schema_info SET version = $1`, v.version())
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		log.Errorf(err.Error())
+		return err
+	}
+
+	return nil
+
+}
+
+func (v v5) version() int {
+	return 5
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import "github.com/starkandwayne/goutils/log"
+
+type v6 struct {
+}
+
+func (v v6) migrate(p *Postgres) error {
+
+	log.Debugf("Starting v6 Migration...")
+
+	transaction, err := p.connection.Begin()
+
+	defer func() {
+		if err != nil {
+			err = transaction.Rollback()
+			if err != nil {
+				log.Infof("Failed to roll back transaction: %s", err.Error())
+			} else {
+				log.Infof("Rolled back transaction for v6")
+			}
+		}
+	}()
+
+	// Keeps a record of every bind that Portcullis couldn't open egress for, so
+	// that operators can see what went wrong
+	_, err = transaction.Exec(`CREATE TABLE bind_failures (
+						 id             SERIAL PRIMARY KEY,
+						 binding_guid   TEXT NOT NULL,
+						 instance_guid  TEXT NOT NULL DEFAULT '',
+						 mapping_name   TEXT NOT NULL DEFAULT '',
+						 failed_at      TIMESTAMP WITH TIME ZONE NOT NULL,
+						 error          TEXT NOT NULL DEFAULT '',
+						 unbound        BOOLEAN NOT NULL DEFAULT FALSE,
+						 unbind_error   TEXT NOT NULL DEFAULT ''
+					 )`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	// Forces that this schema update was done via transaction, this leaves an
+	// artifact that the migration is complete
+	_, err = transaction.Exec(`UPDATE schema_info SET version = $1`, v.version())
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		log.Errorf(err.Error())
+		return err
+	}
+
+	return nil
+
+}
+
+func (v v6) version() int {
+	return 6
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import "github.com/starkandwayne/goutils/log"
+
+type v7 struct {
+}
+
+func (v v7) migrate(p *Postgres) error {
+
+	log.Debugf("Starting v7 Migration...")
+
+	transaction, err := p.connection.Begin()
+
+	defer func() {
+		if err != nil {
+			err = transaction.Rollback()
+			if err != nil {
+				log.Infof("Failed to roll back transaction: %s", err.Error())
+			} else {
+				log.Infof("Rolled back transaction for v7")
+			}
+		}
+	}()
+
+	// Records the org and space that each binding was made in
+	_, err = transaction.Exec(`ALTER TABLE bindings ADD COLUMN space_guid TEXT NOT NULL DEFAULT '',
+					 ADD COLUMN organization_guid TEXT NOT NULL DEFAULT ''`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	// Bindings from before this were all put in the space of their group
+	_, err = transaction.Exec(`UPDATE bindings SET space_guid = secgroups.space_guid
+					 FROM secgroups WHERE bindings.secgroup_name = secgroups.name`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	// Forces that this schema update was done via transaction, this leaves an
+	// artifact that the migration is complete
+	_, err = transaction.Exec(`UPDATE schema_info SET version = $1`, v.version())
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		log.Errorf(err.Error())
+		return err
+	}
+
+	return nil
+
+}
+
+func (v v7) version() int {
+	return 7
+}
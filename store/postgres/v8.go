@@ -0,0 +1,58 @@
+package postgres
+
+import "github.com/starkandwayne/goutils/log"
+
+type v8 struct {
+}
+
+func (v v8) migrate(p *Postgres) error {
+
+	log.Debugf("Starting v8 Migration...")
+
+	transaction, err := p.connection.Begin()
+
+	defer func() {
+		if err != nil {
+			err = transaction.Rollback()
+			if err != nil {
+				log.Infof("Failed to roll back transaction: %s", err.Error())
+			} else {
+				log.Infof("Rolled back transaction for v8")
+			}
+		}
+	}()
+
+	// Keeps the bind requests of asynchronous binds until the backend broker
+	// says that they're done, so that egress can be opened then
+	_, err = transaction.Exec(`CREATE TABLE pending_binds (
+						 binding_guid   TEXT PRIMARY KEY NOT NULL,
+						 instance_guid  TEXT NOT NULL DEFAULT '',
+						 mapping_name   TEXT NOT NULL DEFAULT '',
+						 request        TEXT NOT NULL DEFAULT ''
+					 )`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	// Forces that this schema update was done via transaction, this leaves an
+	// artifact that the migration is complete
+	_, err = transaction.Exec(`UPDATE schema_info SET version = $1`, v.version())
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		log.Errorf(err.Error())
+		return err
+	}
+
+	return nil
+
+}
+
+func (v v8) version() int {
+	return 8
+}
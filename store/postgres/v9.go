@@ -0,0 +1,69 @@
+package postgres
+
+import "github.com/starkandwayne/goutils/log"
+
+type v9 struct {
+}
+
+func (v v9) migrate(p *Postgres) error {
+
+	log.Debugf("Starting v9 Migration...")
+
+	transaction, err := p.connection.Begin()
+
+	defer func() {
+		if err != nil {
+			err = transaction.Rollback()
+			if err != nil {
+				log.Infof("Failed to roll back transaction: %s", err.Error())
+			} else {
+				log.Infof("Rolled back transaction for v9")
+			}
+		}
+	}()
+
+	// Keeps a record of who changed which orgs can see the plans of each
+	// mapping's service broker
+	_, err = transaction.Exec(`CREATE TABLE access_changes (
+						 id                 SERIAL PRIMARY KEY,
+						 mapping_name       TEXT NOT NULL,
+						 organization_guid  TEXT NOT NULL,
+						 service_name       TEXT NOT NULL DEFAULT '',
+						 plan_name          TEXT NOT NULL DEFAULT '',
+						 plan_guid          TEXT NOT NULL DEFAULT '',
+						 granted            BOOLEAN NOT NULL,
+						 username           TEXT NOT NULL DEFAULT '',
+						 changed_at         TIMESTAMP WITH TIME ZONE NOT NULL
+					 )`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	_, err = transaction.Exec(`CREATE INDEX access_changes_mapping_name ON access_changes (mapping_name)`)
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	// Forces that this schema update was done via transaction, this leaves an
+	// artifact that the migration is complete
+	_, err = transaction.Exec(`UPDATE schema_info SET version = $1`, v.version())
+	if err != nil {
+		log.Debugf("Failed perform command: %s", err.Error())
+		return err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		log.Errorf(err.Error())
+		return err
+	}
+
+	return nil
+
+}
+
+func (v v9) version() int {
+	return 9
+}
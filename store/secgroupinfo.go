@@ -1,10 +1,18 @@
 package store
 
-//SecGroupInfo contains the information about what CF Security groups are
-// associated with which service instances
+import "github.com/cloudfoundry-community/portcullis/broker/bindparser"
+
+//SecGroupInfo contains the information about a CF Security group that
+// Portcullis created. A single group is shared by all of the bindings to a
+// service instance from apps in the same space.
 type SecGroupInfo struct {
-	ServiceInstanceGUID string
-	SecGroupName        string
+	ServiceInstanceGUID string `json:"service_instance_guid"`
+	SecGroupName        string `json:"secgroup_name"`
+	SpaceGUID           string `json:"space_guid"`
+	MappingName         string `json:"mapping_name"`
+	SecGroupGUID        string `json:"secgroup_guid"`
+	//Rules are the rules of all of the bindings to this group, merged together
+	Rules []bindparser.SecGroupRule `json:"rules"`
 }
 
 //WithGUID returns a copy of the receiver SecGroupInfo, except that the
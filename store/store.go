@@ -42,18 +42,27 @@ type Store interface {
 	// If no SecGroupInfo object with that name exists in the store, this should
 	// return ErrNotFound.
 	GetSecGroupInfoByName(name string) (result SecGroupInfo, err error)
-	//GetSecGroupInfoByInstance retrieves the SecGroupInfo instance that is
-	// opening egress to a particular given service instance GUID. If no SecGroup
-	// exists for that service instance GUID, this should return ErrNotFound.
-	GetSecGroupInfoByInstance(GUID string) (result SecGroupInfo, err error)
+	//ListSecGroupInfoByInstance retrieves all of the SecGroupInfo instances that
+	// are opening egress to a particular given service instance GUID. If there
+	// are none, an empty slice should be returned. This replaces getting a
+	// single group by instance, which can't work: an instance has a group for
+	// every space that it is bound from, so the instance GUID isn't unique.
+	ListSecGroupInfoByInstance(GUID string) (results []SecGroupInfo, err error)
+	//ListSecGroupInfo should return all of the SecGroupInfo objects in the store
+	ListSecGroupInfo() (results []SecGroupInfo, err error)
 	//AddSecGroupInfo puts a new SecGroupInfoInstance into the store. If a security
 	// group with that name already exists, this should return ErrDuplicate.
 	AddSecGroupInfo(toAdd SecGroupInfo) error
-	//DeleteSecGroupInfoByInstance removes an existing SecGroupInfo object that is
-	// mapped to the given Service Instance GUID from the store. If no
+	//DeleteSecGroupInfoByInstance removes all existing SecGroupInfo objects that
+	// are mapped to the given Service Instance GUID from the store. If no
 	// SecGroupInfo mapped to that Service Instance exists in the store, this
 	// should return ErrNotFound.
 	DeleteSecGroupInfoByInstance(GUID string) error
+	//EditSecGroupInfo should change the SecGroupInfo with the given name to have
+	// all the values in the given SecGroupInfo. Should return ErrNotFound if
+	// there is no SecGroupInfo with that name in the store, and ErrDuplicate if
+	// the name is being changed to one that is already taken.
+	EditSecGroupInfo(name string, changeTo SecGroupInfo) error
 	//DeleteSecGroupInfoByName removes an existing SecGroupInfo object that has
 	// the given name from the store. If no such SecGroupInfo object with that
 	// name exists in the store, this should return ErrNotFound.
@@ -64,6 +73,71 @@ type Store interface {
 	// Reinitialization should not be required, and Mappings should still remain
 	// intact.
 	ClearSecGroupInfo() error
+	//GetBindingInfo retrieves the BindingInfo for the service binding with the
+	// given GUID. If there is no such BindingInfo in the store, this should
+	// return ErrNotFound.
+	GetBindingInfo(GUID string) (result BindingInfo, err error)
+	//ListBindingInfo should return all of the BindingInfo objects in the store
+	ListBindingInfo() (results []BindingInfo, err error)
+	//ListBindingInfoBySecGroup retrieves all of the BindingInfo objects whose
+	// rules are in the security group with the given name. If there are none,
+	// an empty slice should be returned.
+	ListBindingInfoBySecGroup(name string) (results []BindingInfo, err error)
+	//AddBindingInfo puts a new BindingInfo into the store. If a BindingInfo
+	// with that binding GUID already exists, this should return ErrDuplicate.
+	AddBindingInfo(toAdd BindingInfo) error
+	//EditBindingInfo should change the BindingInfo with the given binding GUID
+	// to have all the values in the given BindingInfo. Should return
+	// ErrNotFound if there is no such BindingInfo in the store, and
+	// ErrDuplicate if the binding GUID is being changed to one that is already
+	// taken.
+	EditBindingInfo(GUID string, changeTo BindingInfo) error
+	//DeleteBindingInfo removes the BindingInfo with the given binding GUID from
+	// the store. If there is no such BindingInfo, this should return
+	// ErrNotFound.
+	DeleteBindingInfo(GUID string) error
+	//NumBindingInfo should return the number of BindingInfo objects in the
+	// store.
+	NumBindingInfo() (int, error)
+	//ClearBindingInfo should delete all BindingInfos from the store.
+	// Reinitialization should not be required, and everything else should
+	// remain intact.
+	ClearBindingInfo() error
+	//AddBindFailure records a BindFailure in the store. The same binding may
+	// fail more than once, and each failure should be kept.
+	AddBindFailure(toAdd BindFailure) error
+	//ListBindFailures should return all of the BindFailures in the store, oldest
+	// first
+	ListBindFailures() (results []BindFailure, err error)
+	//ClearBindFailures should delete all BindFailures from the store.
+	// Reinitialization should not be required, and everything else should
+	// remain intact.
+	ClearBindFailures() error
+	//GetPendingBind retrieves the PendingBind for the service binding with the
+	// given GUID. If there is no such PendingBind in the store, this should
+	// return ErrNotFound.
+	GetPendingBind(GUID string) (result PendingBind, err error)
+	//AddPendingBind puts a new PendingBind into the store. If a PendingBind
+	// with that binding GUID already exists, this should return ErrDuplicate.
+	AddPendingBind(toAdd PendingBind) error
+	//DeletePendingBind removes the PendingBind with the given binding GUID from
+	// the store. If there is no such PendingBind, this should return
+	// ErrNotFound.
+	DeletePendingBind(GUID string) error
+	//ClearPendingBinds should delete all PendingBinds from the store.
+	// Reinitialization should not be required, and everything else should
+	// remain intact.
+	ClearPendingBinds() error
+	//AddAccessChange records an AccessChange in the store
+	AddAccessChange(toAdd AccessChange) error
+	//ListAccessChanges should return all of the AccessChanges for the mapping
+	// with the given name, oldest first. If there are none, an empty slice
+	// should be returned.
+	ListAccessChanges(mappingName string) (results []AccessChange, err error)
+	//ClearAccessChanges should delete all AccessChanges from the store.
+	// Reinitialization should not be required, and everything else should
+	// remain intact.
+	ClearAccessChanges() error
 }
 
 var (
@@ -82,7 +156,7 @@ func SetStoreType(variant string) (err error) {
 	if !found {
 		errorString := fmt.Sprintf("No store exists with variant name `%s`", variant)
 		log.Errorf(errorString)
-		err = fmt.Errorf(errorString)
+		err = fmt.Errorf("%s", errorString)
 	}
 	return err
 }
@@ -116,7 +190,7 @@ func AddMapping(m Mapping) error {
 	//  Make sure name is proper length/content
 	//  Make sure location is parseable as a URL
 
-	err := m.BindConfig.VerifyFlavor()
+	err := m.verify()
 	if err != nil {
 		return NewErrInvalid(err.Error())
 	}
@@ -130,7 +204,7 @@ func AddMapping(m Mapping) error {
 //exists in the store.
 func EditMapping(name string, m Mapping) error {
 	//TODO: See restriction checking for AddMapping
-	err := m.BindConfig.VerifyFlavor()
+	err := m.verify()
 	if err != nil {
 		return NewErrInvalid(err.Error())
 	}
@@ -172,11 +246,16 @@ func GetSecGroupInfoByName(name string) (result SecGroupInfo, err error) {
 	return activeStore.GetSecGroupInfoByName(name)
 }
 
-//GetSecGroupInfoByInstance gets the SecGroupInfo object mapped to the Service
-// Instance with the given GUID from the store. If no such SecGroupInfo object
-// exists in the store, this will return ErrNotFound
-func GetSecGroupInfoByInstance(GUID string) (result SecGroupInfo, err error) {
-	return activeStore.GetSecGroupInfoByInstance(GUID)
+//ListSecGroupInfoByInstance gets all of the SecGroupInfo objects mapped to the
+// Service Instance with the given GUID from the store. There can be more than
+// one, as an instance has a group for every space that it is bound from.
+func ListSecGroupInfoByInstance(GUID string) (results []SecGroupInfo, err error) {
+	return activeStore.ListSecGroupInfoByInstance(GUID)
+}
+
+//ListSecGroupInfo returns all of the SecGroupInfo objects in the store
+func ListSecGroupInfo() (results []SecGroupInfo, err error) {
+	return activeStore.ListSecGroupInfo()
 }
 
 //AddSecGroupInfo puts the given SecGroupInfo object into the database, so long
@@ -194,7 +273,21 @@ func AddSecGroupInfo(toAdd SecGroupInfo) error {
 	return activeStore.AddSecGroupInfo(toAdd)
 }
 
-//DeleteSecGroupInfoByInstance deletes the SecGroupInfo object with the given
+//EditSecGroupInfo changes the SecGroupInfo object with the given SecGroupName
+// to have all of the values in the given SecGroupInfo. If no such object
+// exists, ErrNotFound is returned.
+func EditSecGroupInfo(name string, changeTo SecGroupInfo) error {
+	if changeTo.SecGroupName == "" {
+		return NewErrInvalid("SecGroupName must not be empty")
+	}
+
+	if changeTo.ServiceInstanceGUID == "" {
+		return NewErrInvalid("ServiceInstanceGUID must not be empty")
+	}
+	return activeStore.EditSecGroupInfo(name, changeTo)
+}
+
+//DeleteSecGroupInfoByInstance deletes all SecGroupInfo objects with the given
 // Service Instance GUID from the store. If no such object exists, ErrNotFound
 // is returned
 func DeleteSecGroupInfoByInstance(GUID string) error {
@@ -217,3 +310,149 @@ func NumSecGroupInfo() (int, error) {
 func ClearSecGroupInfo() error {
 	return activeStore.ClearSecGroupInfo()
 }
+
+//GetBindingInfo gets the BindingInfo object for the Service Binding with the
+// given GUID from the store. If no such BindingInfo object exists in the
+// store, this will return ErrNotFound
+func GetBindingInfo(GUID string) (result BindingInfo, err error) {
+	return activeStore.GetBindingInfo(GUID)
+}
+
+//ListBindingInfo returns all of the BindingInfo objects in the store
+func ListBindingInfo() (results []BindingInfo, err error) {
+	return activeStore.ListBindingInfo()
+}
+
+//ListBindingInfoBySecGroup gets all of the BindingInfo objects whose rules are
+// in the security group with the given name
+func ListBindingInfoBySecGroup(name string) (results []BindingInfo, err error) {
+	return activeStore.ListBindingInfoBySecGroup(name)
+}
+
+//SecGroupRefCount returns the number of bindings whose rules are in the
+// security group with the given name. The group is no longer needed once this
+// reaches zero.
+func SecGroupRefCount(name string) (int, error) {
+	bindings, err := activeStore.ListBindingInfoBySecGroup(name)
+	return len(bindings), err
+}
+
+//AddBindingInfo puts the given BindingInfo object into the store, so long as
+// its BindingGUID is unique in the store. If there already exists a
+// BindingInfo with that BindingGUID, this returns ErrDuplicate.
+func AddBindingInfo(toAdd BindingInfo) error {
+	if err := validateBindingInfo(toAdd); err != nil {
+		return err
+	}
+	return activeStore.AddBindingInfo(toAdd)
+}
+
+//EditBindingInfo changes the BindingInfo object with the given BindingGUID to
+// have all of the values in the given BindingInfo. If no such object exists,
+// ErrNotFound is returned.
+func EditBindingInfo(GUID string, changeTo BindingInfo) error {
+	if err := validateBindingInfo(changeTo); err != nil {
+		return err
+	}
+	return activeStore.EditBindingInfo(GUID, changeTo)
+}
+
+func validateBindingInfo(info BindingInfo) error {
+	if info.BindingGUID == "" {
+		return NewErrInvalid("BindingGUID must not be empty")
+	}
+
+	if info.ServiceInstanceGUID == "" {
+		return NewErrInvalid("ServiceInstanceGUID must not be empty")
+	}
+
+	if info.SecGroupName == "" {
+		return NewErrInvalid("SecGroupName must not be empty")
+	}
+	return nil
+}
+
+//DeleteBindingInfo deletes the BindingInfo object with the given BindingGUID
+// from the store. If no such object exists, ErrNotFound is returned
+func DeleteBindingInfo(GUID string) error {
+	return activeStore.DeleteBindingInfo(GUID)
+}
+
+//NumBindingInfo returns the number of BindingInfo objects in the store
+func NumBindingInfo() (int, error) {
+	return activeStore.NumBindingInfo()
+}
+
+//ClearBindingInfo deletes all BindingInfos from the store.
+func ClearBindingInfo() error {
+	return activeStore.ClearBindingInfo()
+}
+
+//AddBindFailure records the given BindFailure in the store
+func AddBindFailure(toAdd BindFailure) error {
+	if toAdd.BindingGUID == "" {
+		return NewErrInvalid("BindingGUID must not be empty")
+	}
+	return activeStore.AddBindFailure(toAdd)
+}
+
+//ListBindFailures returns all of the BindFailures in the store, oldest first
+func ListBindFailures() (results []BindFailure, err error) {
+	return activeStore.ListBindFailures()
+}
+
+//ClearBindFailures deletes all BindFailures from the store.
+func ClearBindFailures() error {
+	return activeStore.ClearBindFailures()
+}
+
+//GetPendingBind gets the PendingBind for the Service Binding with the given
+// GUID from the store. If no such PendingBind exists in the store, this will
+// return ErrNotFound
+func GetPendingBind(GUID string) (result PendingBind, err error) {
+	return activeStore.GetPendingBind(GUID)
+}
+
+//AddPendingBind puts the given PendingBind into the store, so long as its
+// BindingGUID is unique in the store. If there already exists a PendingBind
+// with that BindingGUID, this returns ErrDuplicate.
+func AddPendingBind(toAdd PendingBind) error {
+	if toAdd.BindingGUID == "" {
+		return NewErrInvalid("BindingGUID must not be empty")
+	}
+	return activeStore.AddPendingBind(toAdd)
+}
+
+//DeletePendingBind deletes the PendingBind with the given BindingGUID from the
+// store. If no such object exists, ErrNotFound is returned
+func DeletePendingBind(GUID string) error {
+	return activeStore.DeletePendingBind(GUID)
+}
+
+//ClearPendingBinds deletes all PendingBinds from the store.
+func ClearPendingBinds() error {
+	return activeStore.ClearPendingBinds()
+}
+
+//AddAccessChange records the given AccessChange in the store
+func AddAccessChange(toAdd AccessChange) error {
+	if toAdd.MappingName == "" {
+		return NewErrInvalid("MappingName must not be empty")
+	}
+
+	if toAdd.OrganizationGUID == "" {
+		return NewErrInvalid("OrganizationGUID must not be empty")
+	}
+	return activeStore.AddAccessChange(toAdd)
+}
+
+//ListAccessChanges returns all of the AccessChanges for the mapping with the
+// given name, oldest first
+func ListAccessChanges(mappingName string) (results []AccessChange, err error) {
+	return activeStore.ListAccessChanges(mappingName)
+}
+
+//ClearAccessChanges deletes all AccessChanges from the store.
+func ClearAccessChanges() error {
+	return activeStore.ClearAccessChanges()
+}
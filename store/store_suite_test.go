@@ -90,10 +90,12 @@ func genTestMapping() store.Mapping {
 	return store.Mapping{
 		Name:     genRandomString(),
 		Location: genRandomString(),
-		BindConfig: bindparser.Config{
-			FlavorName: "dummy",
-			Config: map[string]interface{}{
-				"confirm": true,
+		BindConfig: bindparser.ConfigList{
+			{
+				FlavorName: "dummy",
+				Config: map[string]interface{}{
+					"confirm": true,
+				},
 			},
 		},
 	}
@@ -104,5 +106,22 @@ func genTestSecGroupInfo() store.SecGroupInfo {
 	return store.SecGroupInfo{
 		ServiceInstanceGUID: genRandomString(),
 		SecGroupName:        genRandomString(),
+		SpaceGUID:           genRandomString(),
+		MappingName:         genRandomString(),
+		SecGroupGUID:        genRandomString(),
+		Rules: []bindparser.SecGroupRule{
+			{Protocol: "tcp", Destination: "10.0.0.1", Ports: "6379"},
+		},
+	}
+}
+
+//Make a test BindingInfo with random stuff inside
+func genTestBindingInfo() store.BindingInfo {
+	return store.BindingInfo{
+		BindingGUID:         genRandomString(),
+		ServiceInstanceGUID: genRandomString(),
+		SecGroupName:        genRandomString(),
+		SpaceGUID:           genRandomString(),
+		OrganizationGUID:    genRandomString(),
 	}
 }
@@ -3,6 +3,9 @@ package store_test
 import (
 	"fmt"
 	"sort"
+	"time"
+
+	"github.com/cloudfoundry-community/portcullis/broker/bindparser"
 
 	. "github.com/cloudfoundry-community/portcullis/store"
 
@@ -47,6 +50,14 @@ var _ = Describe("Store", func() {
 			Expect(err).NotTo(HaveOccurred())
 			err = ClearSecGroupInfo()
 			Expect(err).NotTo(HaveOccurred())
+			err = ClearBindingInfo()
+			Expect(err).NotTo(HaveOccurred())
+			err = ClearBindFailures()
+			Expect(err).NotTo(HaveOccurred())
+			err = ClearPendingBinds()
+			Expect(err).NotTo(HaveOccurred())
+			err = ClearAccessChanges()
+			Expect(err).NotTo(HaveOccurred())
 		})
 
 		Describe("ClearMappings", func() {
@@ -633,10 +644,10 @@ var _ = Describe("Store", func() {
 					})
 				})
 
-				Context("Because a different group with the same ServiceInstanceGUID has already been added", func() {
+				Context("Because a different group with the same SecGroupName has already been added", func() {
 					var firstGroup SecGroupInfo
 					BeforeEach(func() {
-						firstGroup = genTestSecGroupInfo().WithGUID(testGroup.ServiceInstanceGUID)
+						firstGroup = genTestSecGroupInfo().WithGroupName(testGroup.SecGroupName)
 						err = AddSecGroupInfo(firstGroup)
 					})
 
@@ -651,20 +662,39 @@ var _ = Describe("Store", func() {
 					})
 
 					Specify("it should be the original SecGroupInfo object in the store", func() {
-						group, err := GetSecGroupInfoByInstance(testGroup.ServiceInstanceGUID)
+						group, err := GetSecGroupInfoByName(testGroup.SecGroupName)
 						Expect(err).NotTo(HaveOccurred())
 						Expect(group).To(Equal(firstGroup))
 					})
 				})
 			})
+
+			Context("With a different group for the same ServiceInstanceGUID", func() {
+				var firstGroup SecGroupInfo
+				BeforeEach(func() {
+					firstGroup = genTestSecGroupInfo().WithGUID(testGroup.ServiceInstanceGUID)
+					err = AddSecGroupInfo(firstGroup)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("should not return an error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				Specify("both SecGroupInfo objects should be in the store for that instance", func() {
+					groups, err := ListSecGroupInfoByInstance(testGroup.ServiceInstanceGUID)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(groups).To(ConsistOf(firstGroup, testGroup))
+				})
+			})
 		})
 
 		Describe("Getting SecGroupInfo", func() {
 			Context("By ServiceInstanceGUID", func() {
 				var testGUID string
-				var responseSecGroup SecGroupInfo
+				var responseSecGroups []SecGroupInfo
 				JustBeforeEach(func() {
-					responseSecGroup, err = GetSecGroupInfoByInstance(testGUID)
+					responseSecGroups, err = ListSecGroupInfoByInstance(testGUID)
 				})
 
 				Context("when the target exists in the store", func() {
@@ -681,15 +711,19 @@ var _ = Describe("Store", func() {
 							Expect(err).NotTo(HaveOccurred())
 						})
 
-						Specify("The returned SecGroupInfo should match the inserted one", func() {
-							Expect(responseSecGroup).To(Equal(insertedSecGroup))
+						Specify("The returned list should only contain the inserted SecGroupInfo", func() {
+							Expect(responseSecGroups).To(ConsistOf(insertedSecGroup))
 						})
 					})
 
 					Context("with other things in the store as well", func() {
 						const totalInsertions = 50
+						var sameInstance SecGroupInfo
 						BeforeEach(func() {
-							for i := 0; i < totalInsertions-1; i++ {
+							sameInstance = genTestSecGroupInfo().WithGUID(testGUID)
+							err = AddSecGroupInfo(sameInstance)
+							Expect(err).NotTo(HaveOccurred())
+							for i := 0; i < totalInsertions-2; i++ {
 								err = AddSecGroupInfo(genTestSecGroupInfo())
 								Expect(err).NotTo(HaveOccurred())
 							}
@@ -699,8 +733,8 @@ var _ = Describe("Store", func() {
 							Expect(err).NotTo(HaveOccurred())
 						})
 
-						It("should return the target SecGroupInfo", func() {
-							Expect(responseSecGroup).To(Equal(insertedSecGroup))
+						It("should return all of the SecGroupInfo for that instance", func() {
+							Expect(responseSecGroups).To(ConsistOf(insertedSecGroup, sameInstance))
 						})
 					})
 				})
@@ -711,8 +745,12 @@ var _ = Describe("Store", func() {
 					})
 
 					Context("Because the store is empty", func() {
-						It("should return ErrNotFound", func() {
-							Expect(err).To(Equal(ErrNotFound))
+						It("should not return an error", func() {
+							Expect(err).NotTo(HaveOccurred())
+						})
+
+						It("should return an empty list", func() {
+							Expect(responseSecGroups).To(BeEmpty())
 						})
 					})
 
@@ -724,8 +762,12 @@ var _ = Describe("Store", func() {
 							}
 						})
 
-						It("should return ErrNotFound", func() {
-							Expect(err).To(Equal(ErrNotFound))
+						It("should not return an error", func() {
+							Expect(err).NotTo(HaveOccurred())
+						})
+
+						It("should return an empty list", func() {
+							Expect(responseSecGroups).To(BeEmpty())
 						})
 					})
 				})
@@ -803,6 +845,112 @@ var _ = Describe("Store", func() {
 			})
 		})
 
+		Describe("Listing SecGroupInfo", func() {
+			var results []SecGroupInfo
+			JustBeforeEach(func() {
+				results, err = ListSecGroupInfo()
+			})
+
+			Context("With nothing in the store", func() {
+				It("should not return an error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("should return an empty list", func() {
+					Expect(results).To(BeEmpty())
+				})
+			})
+
+			Context("With many SecGroupInfo objects in the store", func() {
+				var inserted []SecGroupInfo
+				BeforeEach(func() {
+					inserted = []SecGroupInfo{}
+					for i := 0; i < 5; i++ {
+						toAdd := genTestSecGroupInfo()
+						Expect(AddSecGroupInfo(toAdd)).To(Succeed())
+						inserted = append(inserted, toAdd)
+					}
+				})
+
+				It("should not return an error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("should return all of the inserted SecGroupInfo", func() {
+					Expect(results).To(ConsistOf(inserted))
+				})
+			})
+		})
+
+		Describe("Editing SecGroupInfo", func() {
+			var targetName string
+			var edited SecGroupInfo
+			JustBeforeEach(func() {
+				err = EditSecGroupInfo(targetName, edited)
+			})
+
+			Context("on a SecGroupInfo that already exists", func() {
+				var orig SecGroupInfo
+				BeforeEach(func() {
+					orig = genTestSecGroupInfo()
+					Expect(AddSecGroupInfo(orig)).To(Succeed())
+					targetName = orig.SecGroupName
+					edited = orig
+					edited.Rules = []bindparser.SecGroupRule{
+						{Protocol: "tcp", Destination: "10.0.0.5", Ports: "5432"},
+					}
+				})
+
+				It("should not return an error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				Specify("The store should contain the edited version", func() {
+					var result SecGroupInfo
+					result, err = GetSecGroupInfoByName(targetName)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(edited))
+				})
+
+				It("should only have one SecGroupInfo in the store", func() {
+					var size int
+					size, err = NumSecGroupInfo()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(size).To(Equal(1))
+				})
+
+				Context("to a SecGroupName that is already taken", func() {
+					BeforeEach(func() {
+						other := genTestSecGroupInfo()
+						Expect(AddSecGroupInfo(other)).To(Succeed())
+						edited.SecGroupName = other.SecGroupName
+					})
+
+					It("should return ErrDuplicate", func() {
+						Expect(err).To(Equal(ErrDuplicate))
+					})
+
+					Specify("The original should be unchanged", func() {
+						var result SecGroupInfo
+						result, err = GetSecGroupInfoByName(targetName)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result).To(Equal(orig))
+					})
+				})
+			})
+
+			Context("on a SecGroupInfo that does not exist", func() {
+				BeforeEach(func() {
+					edited = genTestSecGroupInfo()
+					targetName = edited.SecGroupName
+				})
+
+				It("should return ErrNotFound", func() {
+					Expect(err).To(Equal(ErrNotFound))
+				})
+			})
+		})
+
 		Describe("Deleting SecGroupInfo", func() {
 			Context("By ServiceInstanceGUID", func() {
 				var testGUID string
@@ -824,8 +972,9 @@ var _ = Describe("Store", func() {
 						})
 
 						Specify("The SecGroupInfo object should no longer be in the store", func() {
-							_, err = GetSecGroupInfoByInstance(testGUID)
-							Expect(err).To(Equal(ErrNotFound))
+							groups, err := ListSecGroupInfoByInstance(testGUID)
+							Expect(err).NotTo(HaveOccurred())
+							Expect(groups).To(BeEmpty())
 						})
 					})
 
@@ -843,8 +992,9 @@ var _ = Describe("Store", func() {
 						})
 
 						Specify("The SecGroupInfo object should no longer be in the store", func() {
-							_, err = GetSecGroupInfoByInstance(testGUID)
-							Expect(err).To(Equal(ErrNotFound))
+							groups, err := ListSecGroupInfoByInstance(testGUID)
+							Expect(err).NotTo(HaveOccurred())
+							Expect(groups).To(BeEmpty())
 						})
 					})
 				})
@@ -896,7 +1046,7 @@ var _ = Describe("Store", func() {
 						})
 
 						Specify("The SecGroupInfo object should no longer be in the store", func() {
-							_, err = GetSecGroupInfoByInstance(testName)
+							_, err = GetSecGroupInfoByName(testName)
 							Expect(err).To(Equal(ErrNotFound))
 						})
 					})
@@ -995,5 +1145,494 @@ var _ = Describe("Store", func() {
 				})
 			})
 		})
+
+		Describe("SecGroupInfo for an instance bound from many spaces", func() {
+			var instanceGUID string
+			var groups []SecGroupInfo
+			BeforeEach(func() {
+				instanceGUID = genRandomString()
+				groups = []SecGroupInfo{}
+				for i := 0; i < 3; i++ {
+					group := genTestSecGroupInfo().WithGUID(instanceGUID)
+					Expect(AddSecGroupInfo(group)).To(Succeed())
+					groups = append(groups, group)
+				}
+				Expect(AddSecGroupInfo(genTestSecGroupInfo())).To(Succeed())
+			})
+
+			It("should keep every field of every group", func() {
+				for _, group := range groups {
+					result, err := GetSecGroupInfoByName(group.SecGroupName)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(group))
+				}
+			})
+
+			Context("When the instance's groups are deleted", func() {
+				BeforeEach(func() {
+					Expect(DeleteSecGroupInfoByInstance(instanceGUID)).To(Succeed())
+				})
+
+				It("should remove all of them", func() {
+					results, err := ListSecGroupInfoByInstance(instanceGUID)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(results).To(BeEmpty())
+				})
+
+				It("should leave the other instance's group alone", func() {
+					size, err := NumSecGroupInfo()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(size).To(Equal(1))
+				})
+			})
+		})
+
+		Describe("Adding BindingInfo", func() {
+			var testBinding BindingInfo
+			JustBeforeEach(func() {
+				err = AddBindingInfo(testBinding)
+			})
+
+			BeforeEach(func() {
+				testBinding = genTestBindingInfo()
+			})
+
+			Context("With a unique value", func() {
+				It("should not return an error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				Specify("The binding should be in the store", func() {
+					result, err := GetBindingInfo(testBinding.BindingGUID)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(testBinding))
+				})
+			})
+
+			Context("Without a SecGroupName", func() {
+				BeforeEach(func() {
+					testBinding = testBinding.WithGroupName("")
+				})
+
+				It("should return an error", func() {
+					Expect(err).To(HaveOccurred())
+				})
+
+				Specify("The binding should not be in the store", func() {
+					_, err := GetBindingInfo(testBinding.BindingGUID)
+					Expect(err).To(Equal(ErrNotFound))
+				})
+			})
+
+			Context("Without a BindingGUID", func() {
+				BeforeEach(func() {
+					testBinding = testBinding.WithBindingGUID("")
+				})
+
+				It("should return an error", func() {
+					Expect(err).To(HaveOccurred())
+				})
+			})
+
+			Context("When a binding with the same BindingGUID has already been added", func() {
+				var firstBinding BindingInfo
+				BeforeEach(func() {
+					firstBinding = genTestBindingInfo().WithBindingGUID(testBinding.BindingGUID)
+					Expect(AddBindingInfo(firstBinding)).To(Succeed())
+				})
+
+				It("should return ErrDuplicate", func() {
+					Expect(err).To(Equal(ErrDuplicate))
+				})
+
+				Specify("it should be the original BindingInfo object in the store", func() {
+					result, err := GetBindingInfo(testBinding.BindingGUID)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(firstBinding))
+				})
+			})
+		})
+
+		Describe("Counting the bindings to a security group", func() {
+			var groupName string
+			BeforeEach(func() {
+				groupName = genRandomString()
+				for i := 0; i < 3; i++ {
+					Expect(AddBindingInfo(genTestBindingInfo().WithGroupName(groupName))).To(Succeed())
+				}
+				Expect(AddBindingInfo(genTestBindingInfo())).To(Succeed())
+			})
+
+			It("should only count the bindings to that group", func() {
+				count, err := SecGroupRefCount(groupName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(count).To(Equal(3))
+			})
+
+			It("should list only the bindings to that group", func() {
+				bindings, err := ListBindingInfoBySecGroup(groupName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(bindings).To(HaveLen(3))
+				for _, binding := range bindings {
+					Expect(binding.SecGroupName).To(Equal(groupName))
+				}
+			})
+
+			It("should list every binding with ListBindingInfo", func() {
+				bindings, err := ListBindingInfo()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(bindings).To(HaveLen(4))
+			})
+
+			Context("After one of the bindings is deleted", func() {
+				BeforeEach(func() {
+					bindings, err := ListBindingInfoBySecGroup(groupName)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(DeleteBindingInfo(bindings[0].BindingGUID)).To(Succeed())
+				})
+
+				It("should count one fewer binding", func() {
+					count, err := SecGroupRefCount(groupName)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(count).To(Equal(2))
+				})
+			})
+
+			Context("For a group with no bindings", func() {
+				It("should return zero", func() {
+					count, err := SecGroupRefCount(genRandomString())
+					Expect(err).NotTo(HaveOccurred())
+					Expect(count).To(BeZero())
+				})
+			})
+		})
+
+		Describe("Editing BindingInfo", func() {
+			var targetGUID string
+			var edited BindingInfo
+			JustBeforeEach(func() {
+				err = EditBindingInfo(targetGUID, edited)
+			})
+
+			Context("on a BindingInfo that already exists", func() {
+				var orig BindingInfo
+				BeforeEach(func() {
+					orig = genTestBindingInfo()
+					Expect(AddBindingInfo(orig)).To(Succeed())
+					targetGUID = orig.BindingGUID
+					edited = orig
+					edited.Rules = []bindparser.SecGroupRule{
+						{Protocol: "tcp", Destination: "10.0.0.5", Ports: "5432"},
+					}
+					edited.SourceRules = []bindparser.SecGroupRule{
+						{Protocol: "tcp", Destination: "db.example.com", Ports: "5432"},
+					}
+				})
+
+				It("should not return an error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				Specify("The store should contain the edited version", func() {
+					result, err := GetBindingInfo(targetGUID)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(edited))
+				})
+
+				Context("to a BindingGUID that is already taken", func() {
+					BeforeEach(func() {
+						other := genTestBindingInfo()
+						Expect(AddBindingInfo(other)).To(Succeed())
+						edited.BindingGUID = other.BindingGUID
+					})
+
+					It("should return ErrDuplicate", func() {
+						Expect(err).To(Equal(ErrDuplicate))
+					})
+
+					Specify("The original should be unchanged", func() {
+						result, err := GetBindingInfo(targetGUID)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result).To(Equal(orig))
+					})
+				})
+			})
+
+			Context("on a BindingInfo that does not exist", func() {
+				BeforeEach(func() {
+					edited = genTestBindingInfo()
+					targetGUID = edited.BindingGUID
+				})
+
+				It("should return ErrNotFound", func() {
+					Expect(err).To(Equal(ErrNotFound))
+				})
+			})
+		})
+
+		Describe("Deleting BindingInfo", func() {
+			Context("When the binding is in the store", func() {
+				var target BindingInfo
+				BeforeEach(func() {
+					target = genTestBindingInfo()
+					Expect(AddBindingInfo(target)).To(Succeed())
+					err = DeleteBindingInfo(target.BindingGUID)
+				})
+
+				It("should not return an error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				Specify("The binding should no longer be in the store", func() {
+					_, err := GetBindingInfo(target.BindingGUID)
+					Expect(err).To(Equal(ErrNotFound))
+				})
+
+				Specify("The store should be empty", func() {
+					size, err := NumBindingInfo()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(size).To(BeZero())
+				})
+			})
+
+			Context("When the binding is not in the store", func() {
+				It("should return ErrNotFound", func() {
+					Expect(DeleteBindingInfo(genRandomString())).To(Equal(ErrNotFound))
+				})
+			})
+		})
+
+		Describe("Clearing BindingInfo", func() {
+			BeforeEach(func() {
+				for i := 0; i < 5; i++ {
+					Expect(AddBindingInfo(genTestBindingInfo())).To(Succeed())
+				}
+				err = ClearBindingInfo()
+			})
+
+			It("should not return an error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should leave no bindings in the store", func() {
+				size, err := NumBindingInfo()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(size).To(BeZero())
+			})
+		})
+
+		Describe("BindFailures", func() {
+			var failures []BindFailure
+			JustBeforeEach(func() {
+				failures, err = ListBindFailures()
+			})
+
+			Context("With nothing in the store", func() {
+				It("should return an empty list", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(failures).To(BeEmpty())
+				})
+			})
+
+			Context("After adding failures", func() {
+				var first, second BindFailure
+				BeforeEach(func() {
+					first = BindFailure{
+						BindingGUID: genRandomString(),
+						Time:        time.Unix(1000, 0).UTC(),
+						Error:       "no app_guid",
+						Unbound:     true,
+					}
+					second = BindFailure{
+						BindingGUID: first.BindingGUID,
+						Time:        time.Unix(2000, 0).UTC(),
+						Error:       "CF is down",
+						UnbindError: "broker is down too",
+					}
+					Expect(AddBindFailure(first)).To(Succeed())
+					Expect(AddBindFailure(second)).To(Succeed())
+				})
+
+				It("should keep every failure, oldest first", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(failures).To(HaveLen(2))
+					Expect(failures[0].Error).To(Equal(first.Error))
+					Expect(failures[0].Unbound).To(BeTrue())
+					Expect(failures[1].UnbindError).To(Equal(second.UnbindError))
+					Expect(failures[1].Time.Equal(second.Time)).To(BeTrue())
+				})
+
+				Context("and then clearing them", func() {
+					BeforeEach(func() {
+						Expect(ClearBindFailures()).To(Succeed())
+					})
+
+					It("should return an empty list", func() {
+						Expect(failures).To(BeEmpty())
+					})
+				})
+			})
+
+			Context("Adding a failure without a BindingGUID", func() {
+				It("should return an error", func() {
+					Expect(AddBindFailure(BindFailure{Error: "oops"})).NotTo(Succeed())
+				})
+			})
+		})
+
+		Describe("PendingBinds", func() {
+			var target PendingBind
+			BeforeEach(func() {
+				target = PendingBind{
+					BindingGUID:         genRandomString(),
+					ServiceInstanceGUID: genRandomString(),
+					MappingName:         genRandomString(),
+					Request:             `{"app_guid":"` + genRandomString() + `"}`,
+				}
+			})
+
+			Context("After adding a pending unbind", func() {
+				BeforeEach(func() {
+					target.Request = ""
+					target.Unbind = true
+					Expect(AddPendingBind(target)).To(Succeed())
+				})
+
+				It("should get back that it is an unbind", func() {
+					result, err := GetPendingBind(target.BindingGUID)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(target))
+				})
+			})
+
+			Context("With nothing in the store", func() {
+				It("should not find the pending bind", func() {
+					_, err := GetPendingBind(target.BindingGUID)
+					Expect(err).To(Equal(ErrNotFound))
+				})
+
+				It("should return ErrNotFound when deleting", func() {
+					Expect(DeletePendingBind(target.BindingGUID)).To(Equal(ErrNotFound))
+				})
+			})
+
+			Context("After adding a pending bind", func() {
+				BeforeEach(func() {
+					Expect(AddPendingBind(target)).To(Succeed())
+				})
+
+				It("should get back what was put in", func() {
+					result, err := GetPendingBind(target.BindingGUID)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(Equal(target))
+				})
+
+				It("should not allow another with the same BindingGUID", func() {
+					Expect(AddPendingBind(target)).To(Equal(ErrDuplicate))
+				})
+
+				It("should not allow an unbind with the same BindingGUID", func() {
+					Expect(AddPendingBind(PendingBind{BindingGUID: target.BindingGUID, Unbind: true})).To(Equal(ErrDuplicate))
+				})
+
+				Context("and then deleting it", func() {
+					BeforeEach(func() {
+						Expect(DeletePendingBind(target.BindingGUID)).To(Succeed())
+					})
+
+					It("should no longer be in the store", func() {
+						_, err := GetPendingBind(target.BindingGUID)
+						Expect(err).To(Equal(ErrNotFound))
+					})
+				})
+
+				Context("and then clearing them", func() {
+					BeforeEach(func() {
+						Expect(ClearPendingBinds()).To(Succeed())
+					})
+
+					It("should no longer be in the store", func() {
+						_, err := GetPendingBind(target.BindingGUID)
+						Expect(err).To(Equal(ErrNotFound))
+					})
+				})
+			})
+
+			Context("Adding a pending bind without a BindingGUID", func() {
+				It("should return an error", func() {
+					Expect(AddPendingBind(PendingBind{Request: "{}"})).NotTo(Succeed())
+				})
+			})
+		})
+
+		Describe("AccessChanges", func() {
+			var mappingName string
+			var changes []AccessChange
+			BeforeEach(func() {
+				mappingName = genRandomString()
+			})
+
+			JustBeforeEach(func() {
+				changes, err = ListAccessChanges(mappingName)
+			})
+
+			Context("With nothing in the store", func() {
+				It("should return an empty list", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(changes).To(BeEmpty())
+				})
+			})
+
+			Context("After adding changes", func() {
+				var grant, revoke AccessChange
+				BeforeEach(func() {
+					grant = AccessChange{
+						MappingName:      mappingName,
+						OrganizationGUID: genRandomString(),
+						ServiceName:      "redis",
+						PlanName:         "small",
+						PlanGUID:         genRandomString(),
+						Granted:          true,
+						User:             "admin",
+						Time:             time.Unix(1000, 0).UTC(),
+					}
+					revoke = grant
+					revoke.Granted = false
+					revoke.Time = time.Unix(2000, 0).UTC()
+					Expect(AddAccessChange(grant)).To(Succeed())
+					Expect(AddAccessChange(AccessChange{
+						MappingName:      genRandomString(),
+						OrganizationGUID: genRandomString(),
+					})).To(Succeed())
+					Expect(AddAccessChange(revoke)).To(Succeed())
+				})
+
+				It("should return the changes for the mapping, oldest first", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(changes).To(HaveLen(2))
+					Expect(changes[0].Granted).To(BeTrue())
+					Expect(changes[0].User).To(Equal(grant.User))
+					Expect(changes[0].PlanGUID).To(Equal(grant.PlanGUID))
+					Expect(changes[1].Granted).To(BeFalse())
+					Expect(changes[1].Time.Equal(revoke.Time)).To(BeTrue())
+				})
+
+				Context("and then clearing them", func() {
+					BeforeEach(func() {
+						Expect(ClearAccessChanges()).To(Succeed())
+					})
+
+					It("should return an empty list", func() {
+						Expect(changes).To(BeEmpty())
+					})
+				})
+			})
+
+			Context("Adding a change without an OrganizationGUID", func() {
+				It("should return an error", func() {
+					Expect(AddAccessChange(AccessChange{MappingName: mappingName})).NotTo(Succeed())
+				})
+			})
+		})
 	})
 })
@@ -0,0 +1,205 @@
+//Package testutil holds stand-ins for the services that Portcullis talks to,
+// for the specs of the other packages to share
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+)
+
+//StubCF is a stand-in for the CF API, which knows just enough to log in, to
+// manage service brokers and the visibility of their plans, to look up apps and
+// service bindings, and to manage security groups and the spaces they are
+// bound to
+type StubCF struct {
+	//Mutex is held while the stub answers a request. Hold it to change what the
+	// stub has while it is running.
+	sync.Mutex
+	Server *httptest.Server
+	//Brokers are the service broker entities that CF has, by GUID
+	Brokers map[string]map[string]interface{}
+	//Lists are the entities of the other resources that CF has, by GUID, keyed
+	// by the path that lists them
+	Lists map[string]map[string]map[string]interface{}
+	//Fail makes every call that changes something fail
+	Fail bool
+}
+
+//The paths of the resources that the stub keeps in its Lists
+const (
+	ServicesPath        = "/v2/services"
+	PlansPath           = "/v2/service_plans"
+	VisibilitiesPath    = "/v2/service_plan_visibilities"
+	InstancesPath       = "/v2/service_instances"
+	ServiceBindingsPath = "/v2/service_bindings"
+	SpacesPath          = "/v2/spaces"
+	AppsPath            = "/v2/apps"
+	SecGroupsPath       = "/v2/security_groups"
+)
+
+//NewStubCF starts a new StubCF, which has nothing in it yet
+func NewStubCF() *StubCF {
+	cf := &StubCF{
+		Brokers: map[string]map[string]interface{}{},
+		Lists: map[string]map[string]map[string]interface{}{
+			ServicesPath:        {},
+			PlansPath:           {},
+			VisibilitiesPath:    {},
+			InstancesPath:       {},
+			ServiceBindingsPath: {},
+			SpacesPath:          {},
+			AppsPath:            {},
+			SecGroupsPath:       {},
+		},
+	}
+	cf.Server = httptest.NewServer(cf)
+	return cf
+}
+
+//newGUID makes up a GUID for a new entity
+func newGUID() string {
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
+		rand.Uint32(), rand.Intn(1<<16), rand.Intn(1<<16), rand.Intn(1<<16), rand.Int63n(1<<48))
+}
+
+//ServeHTTP answers a request to the CF API
+func (cf *StubCF) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cf.Lock()
+	defer cf.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON := func(code int, value interface{}) {
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(value)
+	}
+	resource := func(guid string, entity map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"metadata": map[string]interface{}{"guid": guid},
+			"entity":   entity,
+		}
+	}
+
+	list := cf.Lists[path.Dir(r.URL.Path)]
+	guid := path.Base(r.URL.Path)
+	brokerGUID := strings.TrimPrefix(r.URL.Path, "/v2/service_brokers/")
+	//Security groups are bound to spaces at /v2/security_groups/<guid>/spaces
+	secGroupParts := strings.Split(strings.TrimPrefix(r.URL.Path, SecGroupsPath+"/"), "/")
+	secGroupSpaces := strings.HasPrefix(r.URL.Path, SecGroupsPath+"/") && len(secGroupParts) > 1 && secGroupParts[1] == "spaces"
+	secGroupGUID := secGroupParts[0]
+	switch {
+	case r.URL.Path == "/v2/info":
+		writeJSON(http.StatusOK, map[string]string{
+			"authorization_endpoint": cf.Server.URL,
+			"token_endpoint":         cf.Server.URL,
+		})
+	case r.URL.Path == "/oauth/token":
+		writeJSON(http.StatusOK, map[string]interface{}{
+			"access_token": "stub-token",
+			"token_type":   "bearer",
+			"expires_in":   3600,
+		})
+	case r.URL.Path == "/v2/service_brokers" && r.Method == "GET":
+		name := strings.TrimPrefix(r.URL.Query().Get("q"), "name:")
+		resources := []interface{}{}
+		for guid, entity := range cf.Brokers {
+			if entity["name"] == name {
+				resources = append(resources, resource(guid, entity))
+			}
+		}
+		writeJSON(http.StatusOK, map[string]interface{}{"resources": resources})
+	case secGroupSpaces && cf.Lists[SecGroupsPath][secGroupGUID] == nil:
+		writeJSON(http.StatusNotFound, map[string]string{"description": "The security group could not be found"})
+	case secGroupSpaces && r.Method == "GET":
+		resources := []interface{}{}
+		for _, space := range SpaceGUIDs(cf.Lists[SecGroupsPath][secGroupGUID]) {
+			resources = append(resources, resource(space.(string), cf.Lists[SpacesPath][space.(string)]))
+		}
+		writeJSON(http.StatusOK, map[string]interface{}{"resources": resources})
+	case cf.Lists[r.URL.Path] != nil && r.Method == "GET":
+		//Only filters of the form `q=field:value` are understood
+		filter := strings.SplitN(r.URL.Query().Get("q"), ":", 2)
+		resources := []interface{}{}
+		for guid, entity := range cf.Lists[r.URL.Path] {
+			if len(filter) == 2 && entity[filter[0]] != filter[1] {
+				continue
+			}
+			resources = append(resources, resource(guid, entity))
+		}
+		writeJSON(http.StatusOK, map[string]interface{}{"resources": resources})
+	case list != nil && list[guid] == nil:
+		writeJSON(http.StatusNotFound, map[string]string{"description": "The resource could not be found"})
+	case list != nil && r.Method == "GET":
+		writeJSON(http.StatusOK, resource(guid, list[guid]))
+	case cf.Fail:
+		writeJSON(http.StatusBadGateway, map[string]string{"description": "The service broker could not be reached"})
+	case r.URL.Path == "/v2/service_brokers" && r.Method == "POST":
+		entity := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&entity)
+		guid = newGUID()
+		cf.Brokers[guid] = entity
+		writeJSON(http.StatusCreated, resource(guid, entity))
+	case cf.Lists[r.URL.Path] != nil && r.Method == "POST":
+		entity := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&entity)
+		guid = newGUID()
+		cf.Lists[r.URL.Path][guid] = entity
+		writeJSON(http.StatusCreated, resource(guid, entity))
+	case secGroupSpaces && r.Method == "PUT":
+		entity := cf.Lists[SecGroupsPath][secGroupGUID]
+		entity["space_guids"] = append(SpaceGUIDs(entity), guid)
+		writeJSON(http.StatusCreated, resource(secGroupGUID, entity))
+	case secGroupSpaces && r.Method == "DELETE":
+		entity := cf.Lists[SecGroupsPath][secGroupGUID]
+		remaining := []interface{}{}
+		for _, space := range SpaceGUIDs(entity) {
+			if space != guid {
+				remaining = append(remaining, space)
+			}
+		}
+		entity["space_guids"] = remaining
+		w.WriteHeader(http.StatusNoContent)
+	case list != nil && r.Method == "PUT":
+		//Only security groups are updated, which CF answers with a 201
+		changes := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&changes)
+		for k, v := range changes {
+			list[guid][k] = v
+		}
+		writeJSON(http.StatusCreated, resource(guid, list[guid]))
+	case list != nil && r.Method == "DELETE":
+		delete(list, guid)
+		w.WriteHeader(http.StatusNoContent)
+	case cf.Brokers[brokerGUID] == nil:
+		writeJSON(http.StatusNotFound, map[string]string{"description": "The service broker could not be found"})
+	case r.Method == "PUT":
+		changes := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&changes)
+		for k, v := range changes {
+			cf.Brokers[brokerGUID][k] = v
+		}
+		writeJSON(http.StatusOK, resource(brokerGUID, cf.Brokers[brokerGUID]))
+	case r.Method == "DELETE":
+		delete(cf.Brokers, brokerGUID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSON(http.StatusNotFound, map[string]string{"description": "Unknown request"})
+	}
+}
+
+//SpaceGUIDs returns the GUIDs of the spaces that the given security group
+// entity is bound to
+func SpaceGUIDs(secGroup map[string]interface{}) []interface{} {
+	spaces, _ := secGroup["space_guids"].([]interface{})
+	return spaces
+}
+
+//Close shuts the stub down
+func (cf *StubCF) Close() {
+	cf.Server.Close()
+}